@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/s.usynin/testing/go-server/internal/config"
+)
+
+const configUsage = `Использование: server config print [флаги]
+
+Выводит действующие настройки в виде файла config.toml с источником каждого
+значения; флаги те же, что у server (см. server -h).`
+
+// runConfig выполняет подкоманду `server config print ...`. Настройки
+// выводятся и когда в них есть ошибки - так проще найти неверное значение.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("неизвестная команда\n\n%s", configUsage)
+	}
+
+	cfg, rest, err := config.Load(args[1:])
+	if cfg == nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("лишние аргументы: %v\n\n%s", rest, configUsage)
+	}
+	if printErr := cfg.Print(os.Stdout); printErr != nil {
+		return printErr
+	}
+	return err
+}
@@ -1,28 +1,88 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/mattn/go-sqlite3"
 
+	"github.com/s.usynin/testing/go-server/internal/config"
 	"github.com/s.usynin/testing/go-server/internal/database"
 	"github.com/s.usynin/testing/go-server/internal/handlers"
+	"github.com/s.usynin/testing/go-server/internal/markdown"
+	middlewarePkg "github.com/s.usynin/testing/go-server/internal/middleware"
+	"github.com/s.usynin/testing/go-server/internal/openapi"
 	"github.com/s.usynin/testing/go-server/internal/repository"
 	"github.com/s.usynin/testing/go-server/internal/service"
+	"github.com/s.usynin/testing/go-server/internal/spam"
+	"github.com/s.usynin/testing/go-server/internal/storage"
 	templatesPkg "github.com/s.usynin/testing/go-server/internal/templates"  // только чтобы избежать конфликт имен
 )
 
 func main() {
+	// Подкоманды: server config print ..., server migrate ..., server user ...
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfig(args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+			log.Fatal(err)
+		}
+		return
+	}
+	var command string
+	if len(args) > 0 && (args[0] == "migrate" || args[0] == "user") {
+		command, args = args[0], args[1:]
+	}
+
+	// Настройки: значения по умолчанию, config.toml, переменные окружения и флаги
+	cfg, args, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch command {
+	case "migrate":
+		if err := runMigrate(cfg.Database.Driver, cfg.Database.DSN, args); err != nil {
+			log.Fatal("Ошибка миграции: ", err)
+		}
+		return
+	case "user":
+		if err := runUser(cfg.Database.Driver, cfg.Database.DSN, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(args) > 0 {
+		log.Fatalf("Неизвестная команда %q, см. server -h", args[0])
+	}
+
+	// Журнал пишется в stderr или в файл log.file
+	if cfg.Log.File != "" {
+		logFile, err := os.OpenFile(cfg.Log.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatal("Ошибка открытия журнала:", err)
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	}
+
 	// Инициализация базы данных
-	db, err := database.InitDB("./blog.db")
+	db, err := database.InitDB(cfg.Database.Driver, cfg.Database.DSN)
 	if err != nil {
 		log.Fatal("Ошибка инициализации БД:", err)
 	}
-	defer db.Close()
 
 	// Заполняем базу начальными данными
 	if err := database.SeedDatabase(db); err != nil {
@@ -30,7 +90,7 @@ func main() {
 	}
 
 	// Инициализация шаблонов
-	if err := templatesPkg.InitTemplates(); err != nil {
+	if err := templatesPkg.InitTemplates(cfg.Templates.Dir); err != nil {
 		log.Fatal("Ошибка инициализации шаблонов:", err)
 	}
 
@@ -38,37 +98,283 @@ func main() {
 	postRepo := repository.NewPostRepository(db)
 	commentRepo := repository.NewCommentRepository(db)
 	categoryRepo := repository.NewCategoryRepository(db)
+	tagRepo := repository.NewTagRepository(db)
 	likeRepo := repository.NewLikeRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	revisionRepo := repository.NewRevisionRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	spamRepo := repository.NewSpamRepository(db)
+	reactionRepo := repository.NewReactionRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+
+	// Хранилище файлов вложений: каталог на диске или бакет S3
+	blob, err := openStorage(cfg.Storage)
+	if err != nil {
+		log.Fatal("Ошибка инициализации хранилища файлов:", err)
+	}
+
+	// HTML из Markdown кэшируется для последних постов и комментариев
+	markdownCache := markdown.NewCache(markdown.NewRenderer(), 1000)
+
+	// Спам фильтр комментариев; без secrets.spam ключ подписи токенов формы
+	// генерируется при каждом запуске
+	formTimer, err := spam.NewFormTimer(cfg.Secrets.Spam)
+	if err != nil {
+		log.Fatal("Ошибка инициализации спам фильтра:", err)
+	}
+	spamFilter := spam.NewFilter(
+		spam.NewLinkChecker(),
+		spam.Honeypot{},
+		formTimer,
+		spam.NewBlocklist(settingsRepo),
+		spam.NewClassifier(spamRepo),
+	)
 
 	// Создаём сервисы
-	postService := service.NewPostService(postRepo, commentRepo, categoryRepo, likeRepo)
+	sitemap := service.NewSitemap(postRepo, categoryRepo, tagRepo)
+	moderationService := service.NewModerationService(commentRepo, postRepo, categoryRepo, settingsRepo, notificationRepo, spamFilter)
+	attachmentService := service.NewAttachmentService(attachmentRepo, postRepo, blob,
+		int64(cfg.Attachments.MaxSizeMB)<<20)
+	postService := service.NewPostService(postRepo, commentRepo, categoryRepo, tagRepo, likeRepo, revisionRepo,
+		markdownCache, moderationService, attachmentService, sitemap, cfg.Comments.MaxDepth)
+	authService := service.NewAuthService(userRepo, sessionRepo)
+	userService := service.NewUserService(userRepo)
+	categoryService := service.NewCategoryService(categoryRepo, sitemap)
+	tagService := service.NewTagService(tagRepo, sitemap)
+	notificationService := service.NewNotificationService(notificationRepo)
+	reactionService := service.NewReactionService(reactionRepo, postRepo, commentRepo, cfg.Reactions.Emoji)
+
+	// Удаляем сессии, истёкшие пока сервер был остановлен
+	if n, err := authService.CleanupSessions(); err != nil {
+		log.Println("Ошибка очистки сессий:", err)
+	} else if n > 0 {
+		log.Printf("Удалено истёкших сессий: %d", n)
+	}
+
+	// Публикуем запланированные посты, когда подходит их время.
+	// Фоновые задачи работают, пока не отменён workersCtx
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	scheduler := service.NewScheduler(postRepo, sitemap)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		scheduler.Run(workersCtx)
+	}()
 
 	// Создаём handlers
-	postHandler := handlers.NewPostHandler(postService, templatesPkg.Tpl)
+	// server.cookie_secure включает флаг Secure у cookie сессии и посетителя (для HTTPS);
+	// secrets.visitor - ключ подписи cookie анонимных посетителей (для их лайков и реакций);
+	// если не задан, ключ генерируется при первом запуске и хранится в БД
+	secureCookies := cfg.Server.CookieSecure
+	visitorSecret, err := loadSecret(settingsRepo, "visitor_secret", cfg.Secrets.Visitor)
+	if err != nil {
+		log.Fatal("Ошибка загрузки ключа cookie посетителей:", err)
+	}
+	visitors, err := middlewarePkg.NewVisitors(visitorSecret, secureCookies)
+	if err != nil {
+		log.Fatal("Ошибка инициализации cookie посетителей:", err)
+	}
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, templatesPkg.Tpl)
+	authHandler := handlers.NewAuthHandler(authService, templatesPkg.Tpl, secureCookies)
+	adminHandler := handlers.NewAdminHandler(userService, moderationService, categoryService, tagService, templatesPkg.Tpl)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, templatesPkg.Tpl)
+	// server.base_url - адрес блога для ссылок в лентах и карте сайта. Адрес из
+	// запроса не годится: Host подделывается, а ID записей лент не должны меняться
+	baseURL := cfg.Server.BaseURL
+	if baseURL == "" {
+		log.Println("server.base_url не задан: ленты и карта сайта отключены")
+	}
+	feedHandler := handlers.NewFeedHandler(postService, tagService, templatesPkg.Tpl, baseURL,
+		cfg.Feed.Size, cfg.Feed.Content != "excerpt")
+	postHandler := handlers.NewPostHandler(postService, reactionService, tagService, attachmentService, templatesPkg.Tpl,
+		feedHandler.Enabled())
+	// server.robots_file - файл с правилами robots.txt вместо стандартных; строка Sitemap добавляется сама
+	robots := handlers.DefaultRobots
+	if path := cfg.Server.RobotsFile; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal("Ошибка чтения server.robots_file:", err)
+		}
+		robots = string(data)
+	}
+	sitemapHandler := handlers.NewSitemapHandler(sitemap, templatesPkg.Tpl, baseURL, robots)
+	healthHandler := handlers.NewHealthHandler(db)
+	spec := handlers.OpenAPISpec()
+	apiHandler := handlers.NewAPIHandler(postService, authService, reactionService, categoryService, tagService,
+		attachmentService, spec)
 
 	// Настройка роутера
-	r := setupRoutes(postHandler)
+	r := setupRoutes(postHandler, authHandler, adminHandler, notificationHandler, feedHandler, sitemapHandler,
+		attachmentHandler, healthHandler, apiHandler, authService, visitors, cfg)
+
+	// Каждый маршрут должен быть описан в спецификации OpenAPI
+	if err := openapi.CheckRoutes(spec, r); err != nil {
+		log.Fatal(err)
+	}
 
-	fmt.Println("Сервер запущен на http://localhost:3000")
-	log.Fatal(http.ListenAndServe(":3000", r))
+	// Работа до сигнала остановки; затем по порядку останавливаются HTTP сервер,
+	// фоновые задачи и БД
+	serveErr := serve(newHTTPServer(cfg.Server, r), healthHandler, cfg.Server)
+	if serveErr != nil {
+		log.Println("Ошибка HTTP сервера:", serveErr)
+	}
+	stopWorkers()
+	workers.Wait()
+	if err := db.Close(); err != nil {
+		log.Println("Ошибка закрытия БД:", err)
+	}
+	if serveErr != nil {
+		os.Exit(1)
+	}
+	log.Println("Сервер остановлен")
 }
 
-func setupRoutes(postHandler *handlers.PostHandler) http.Handler {
+// loadSecret возвращает ключ из настроек сервера, а если он не задан - ключ,
+// сохранённый в таблице settings под именем key. При первом запуске ключ
+// генерируется, и все экземпляры сервера с общей БД используют один ключ.
+func loadSecret(settings repository.SettingsRepository, key, configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return settings.SetDefault(key, hex.EncodeToString(raw))
+}
+
+// openStorage открывает хранилище файлов вложений: local - каталог uploads_dir,
+// s3 - бакет в S3-совместимом хранилище (AWS, MinIO)
+func openStorage(cfg config.Storage) (storage.Blob, error) {
+	switch cfg.Backend {
+	case "local":
+		return storage.NewLocal(cfg.UploadsDir)
+	case "s3":
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище %q, ожидается local или s3", cfg.Backend)
+	}
+}
+
+func setupRoutes(
+	postHandler *handlers.PostHandler,
+	authHandler *handlers.AuthHandler,
+	adminHandler *handlers.AdminHandler,
+	notificationHandler *handlers.NotificationHandler,
+	feedHandler *handlers.FeedHandler,
+	sitemapHandler *handlers.SitemapHandler,
+	attachmentHandler *handlers.AttachmentHandler,
+	healthHandler *handlers.HealthHandler,
+	apiHandler *handlers.APIHandler,
+	authService *service.AuthService,
+	visitors *middlewarePkg.Visitors,
+	cfg *config.Config,
+) *chi.Mux {
 	r := chi.NewRouter()
 
-	// Middleware
-	r.Use(middleware.Logger)
+	// Middleware; запросы пишутся в тот же журнал, что и остальные сообщения
+	if cfg.Log.Requests {
+		r.Use(middleware.RequestLogger(&middleware.DefaultLogFormatter{
+			Logger:  log.Default(),
+			NoColor: cfg.Log.File != "",
+		}))
+	}
 	r.Use(middleware.Recoverer)
+	// Тело запроса ограничено server.max_body_mb, формы с файлами - числом
+	// и размером вложений сверх того
+	maxBody := int64(cfg.Server.MaxBodyMB) << 20
+	maxUpload := service.MaxAttachmentsPerPost*int64(cfg.Attachments.MaxSizeMB)<<20 + maxBody
+	r.Use(middlewarePkg.MaxBodySize(maxBody, maxUpload))
+	r.Use(middlewarePkg.SessionMiddleware(authService))
+	r.Use(visitors.Load)
+
+	// Проверки для оркестратора и балансировщика
+	r.Get("/healthz", healthHandler.Live)
+	r.Get("/readyz", healthHandler.Ready)
+
+	// Аккаунты
+	r.Get("/login", authHandler.LoginPage)
+	r.Post("/login", authHandler.Login)
+	r.Get("/signup", authHandler.SignupPage)
+	r.Post("/signup", authHandler.Signup)
+	r.Post("/logout", authHandler.Logout)
 
 	// Роуты
 	r.Get("/", postHandler.Home)
-	r.Post("/posts", postHandler.CreatePost)
-	r.Delete("/posts/{id}", postHandler.DeletePost)
+	r.Get("/search", postHandler.Search)
+	r.Get("/category/{slug}", postHandler.CategoryPage)
+	r.Get("/tag/{slug}", postHandler.TagPage)
+	r.Get("/tags/suggest", postHandler.TagSuggest)
+	r.Get("/feed.{format:rss|atom|json}", feedHandler.Blog)
+	r.Get("/category/{slug}/feed.{format:rss|atom|json}", feedHandler.Category)
+	r.Get("/tag/{slug}/feed.{format:rss|atom|json}", feedHandler.Tag)
+	r.Get("/sitemap.xml", sitemapHandler.Sitemap)
+	r.Get("/sitemap-{n:[0-9]+}.xml", sitemapHandler.Chunk)
+	r.Get("/robots.txt", sitemapHandler.Robots)
+	r.With(middlewarePkg.RequireUser).Post("/posts", postHandler.CreatePost)
+	r.With(middlewarePkg.RequireUser).Post("/posts/preview", postHandler.Preview)
+	r.Get("/posts/{id}", postHandler.PostPage)
+	r.Get("/posts/{id}/item", postHandler.PostItem)
+	r.Get("/posts/{id}/comments", postHandler.Comments)
+	r.With(middlewarePkg.RequireUser).Get("/drafts", postHandler.Drafts)
+	r.Group(func(r chi.Router) {
+		r.Use(middlewarePkg.RequireUser)
+		r.Get("/posts/{id}/edit", postHandler.EditForm)
+		r.Put("/posts/{id}", postHandler.UpdatePost)
+		r.Delete("/posts/{id}", postHandler.DeletePost)
+		r.Get("/posts/{id}/revisions", postHandler.Revisions)
+		r.Get("/posts/{id}/revisions/diff", postHandler.RevisionDiff)
+		r.Post("/posts/{id}/revisions/{revision}/restore", postHandler.RestoreRevision)
+	})
+	r.Get("/attachments/{id}/{name}", attachmentHandler.File)
+	r.Get("/attachments/{id}/thumb/{name}", attachmentHandler.Thumbnail)
+	r.With(middlewarePkg.RequireUser).Delete("/attachments/{id}", attachmentHandler.Delete)
 	r.Post("/comments", postHandler.AddComment)
-	r.Post("/likes", postHandler.AddLike)
+	r.Get("/comments/{id}/replies", postHandler.Replies)
+	r.With(middlewarePkg.RequireUser).Delete("/comments/{id}", postHandler.DeleteComment)
+	r.With(visitors.Require).Post("/likes", postHandler.ToggleLike)
+	r.With(visitors.Require).Post("/reactions", postHandler.ToggleReaction)
+
+	// Уведомления
+	r.With(middlewarePkg.RequireUser).Get("/notifications", notificationHandler.List)
+	r.Get("/notifications/count", notificationHandler.Count)
+
+	// Администрирование (права проверяются в service слое)
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(middlewarePkg.RequireUser)
+		r.Get("/users", adminHandler.Users)
+		r.Put("/users/{id}/role", adminHandler.SetUserRole)
+		r.Get("/categories", adminHandler.Categories)
+		r.Post("/categories", adminHandler.CreateCategory)
+		r.Put("/categories/{id}", adminHandler.UpdateCategory)
+		r.Put("/categories/{id}/parent", adminHandler.MoveCategory)
+		r.Delete("/categories/{id}", adminHandler.DeleteCategory)
+		r.Get("/tags", adminHandler.Tags)
+		r.Put("/tags/{id}", adminHandler.RenameTag)
+		r.Post("/tags/{id}/merge", adminHandler.MergeTags)
+		r.Delete("/tags/{id}", adminHandler.DeleteTag)
+		r.Get("/comments", adminHandler.Comments)
+		r.Post("/comments/moderate", adminHandler.ModerateComments)
+		r.Put("/comments/settings", adminHandler.UpdateModerationSettings)
+		r.Put("/comments/blocklist", adminHandler.UpdateBlocklist)
+	})
+
+	// JSON API и его спецификация
+	r.Get("/api/openapi.json", apiHandler.Spec)
+	r.Mount("/api/v1", apiHandler.Routes())
 
 	// Статические файлы
-	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	r.Method(http.MethodGet, "/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir(cfg.Server.StaticDir))))
 
 	return r
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+)
+
+const migrateUsage = `Использование: server migrate <команда>
+
+Команды:
+  up        применить все новые миграции
+  down      откатить последнюю миграцию
+  status    показать состояние миграций
+  to N      привести схему к версии N (0 - откатить всё)
+  force N   снять грязное состояние, считая версию N применённой`
+
+// runMigrate выполняет подкоманду `server migrate ...`
+func runMigrate(driver, dsn string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана команда\n\n%s", migrateUsage)
+	}
+
+	db, err := database.OpenDB(driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up()
+	case "down":
+		return migrator.Down()
+	case "status":
+		return printMigrationStatus(migrator)
+	case "to", "force":
+		if len(args) < 2 {
+			return fmt.Errorf("команде %s нужен номер версии\n\n%s", args[0], migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("неверный номер версии: %s", args[1])
+		}
+		if args[0] == "to" {
+			return migrator.To(version)
+		}
+		return migrator.Force(version)
+	default:
+		return fmt.Errorf("неизвестная команда %q\n\n%s", args[0], migrateUsage)
+	}
+}
+
+func printMigrationStatus(migrator *database.Migrator) error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Текущая версия: %d (последняя: %d)", version, migrator.Latest())
+	if dirty {
+		fmt.Print(" [DIRTY]")
+	}
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, st := range statuses {
+		state, appliedAt := "pending", ""
+		if st.Applied {
+			state = "applied"
+			appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if st.Dirty {
+			state = "dirty"
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/s.usynin/testing/go-server/internal/config"
+	"github.com/s.usynin/testing/go-server/internal/handlers"
+	middlewarePkg "github.com/s.usynin/testing/go-server/internal/middleware"
+	"github.com/s.usynin/testing/go-server/internal/openapi"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+// Каждый маршрут сервера описан в спецификации OpenAPI, и наоборот. Роутер
+// собирается тем же setupRoutes, что и при запуске; запросы он не обслуживает,
+// поэтому handler'ам не нужны сервисы.
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	spec := handlers.OpenAPISpec()
+	r := setupRoutes(
+		&handlers.PostHandler{},
+		&handlers.AuthHandler{},
+		&handlers.AdminHandler{},
+		&handlers.NotificationHandler{},
+		&handlers.FeedHandler{},
+		&handlers.SitemapHandler{},
+		&handlers.AttachmentHandler{},
+		&handlers.HealthHandler{},
+		handlers.NewAPIHandler(nil, nil, nil, nil, nil, nil, spec),
+		&service.AuthService{},
+		&middlewarePkg.Visitors{},
+		config.Default(),
+	)
+
+	if err := openapi.CheckRoutes(spec, r); err != nil {
+		t.Fatal(err)
+	}
+}
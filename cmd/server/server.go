@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/config"
+	"github.com/s.usynin/testing/go-server/internal/handlers"
+)
+
+// newHTTPServer создаёт HTTP сервер с таймаутами и пределом заголовков из настроек
+func newHTTPServer(cfg config.Server, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderKB << 10,
+	}
+}
+
+// serve принимает запросы до SIGINT или SIGTERM и останавливает сервер:
+// /readyz начинает отвечать 503, ещё drain_delay запросы принимаются как
+// обычно, затем новые соединения закрываются, а начатым запросам даётся
+// shutdown_timeout на завершение. Повторный сигнал завершает процесс сразу.
+func serve(server *http.Server, health *handlers.HealthHandler, cfg config.Server) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	health.SetReady(true)
+	fmt.Println("Сервер запущен на", serverURL(listener.Addr().String()))
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	// Дальше сигналы обрабатываются как обычно: второй Ctrl+C прервёт остановку
+	stop()
+
+	log.Println("Получен сигнал остановки, сервер больше не готов принимать запросы")
+	health.SetReady(false)
+	if cfg.DrainDelay > 0 {
+		time.Sleep(cfg.DrainDelay)
+	}
+
+	log.Printf("Ожидание начатых запросов (до %s)", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("запросы не завершились за %s: %w", cfg.ShutdownTimeout, err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serverURL - адрес для открытия в браузере по адресу, который слушает сервер
+func serverURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+const userUsage = `Использование: server user <команда>
+
+Команды:
+  create NAME [ROLE]  создать пользователя (роль по умолчанию admin);
+                      пароль читается из первой строки стандартного ввода
+  role NAME ROLE      сменить роль пользователя
+  list                показать пользователей
+
+Роли: reader, author, editor, admin. Зарегистрировавшиеся на сайте
+получают роль reader; первого администратора создаёт эта команда.`
+
+// runUser выполняет подкоманду `server user ...`. Схема БД при необходимости
+// обновляется, чтобы администратора можно было создать до первого запуска.
+func runUser(driver, dsn string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана команда\n\n%s", userUsage)
+	}
+
+	db, err := database.InitDB(driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	userRepo := repository.NewUserRepository(db)
+
+	switch args[0] {
+	case "create":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("команде create нужно имя пользователя и, возможно, роль\n\n%s", userUsage)
+		}
+		role := models.RoleAdmin
+		if len(args) == 3 {
+			role = models.Role(args[2])
+		}
+		password, err := readPassword(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		authService := service.NewAuthService(userRepo, repository.NewSessionRepository(db))
+		user, err := authService.CreateUser(args[1], password, role)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Создан пользователь %s (%s)\n", user.Username, user.Role)
+		return nil
+	case "role":
+		if len(args) != 3 {
+			return fmt.Errorf("команде role нужно имя пользователя и роль\n\n%s", userUsage)
+		}
+		role := models.Role(args[2])
+		if !role.Valid() {
+			return service.ErrInvalidRole
+		}
+		user, err := userRepo.GetByUsername(args[1])
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("пользователь %s не найден", args[1])
+		}
+		if err != nil {
+			return err
+		}
+		if err := userRepo.SetRole(user.ID, role); err != nil {
+			return err
+		}
+		fmt.Printf("Пользователь %s: %s -> %s\n", user.Username, user.Role, role)
+		return nil
+	case "list":
+		return printUsers(userRepo)
+	default:
+		return fmt.Errorf("неизвестная команда %q\n\n%s", args[0], userUsage)
+	}
+}
+
+// readPassword читает пароль из первой строки r; в терминале сначала
+// выводит приглашение
+func readPassword(r *os.File) (string, error) {
+	if info, err := r.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Пароль: ")
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("пароль не указан: передайте его первой строкой стандартного ввода")
+	}
+	return password, nil
+}
+
+func printUsers(userRepo repository.UserRepository) error {
+	users, err := userRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tROLE\tCREATED AT")
+	for _, user := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", user.ID, user.Username, user.Role, user.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,498 @@
+// Package config собирает настройки сервера из четырёх источников, каждый
+// следующий важнее предыдущего: значения по умолчанию, файл TOML, переменные
+// окружения и флаги командной строки.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultFile - файл настроек, который читается, если он есть, а путь не задан явно
+const DefaultFile = "config.toml"
+
+// Config - настройки сервера. Поля соответствуют разделам и ключам файла:
+// Server.Addr - ключ addr раздела [server].
+type Config struct {
+	Server      Server
+	Database    Database
+	Templates   Templates
+	Log         Log
+	Storage     Storage
+	Attachments Attachments
+	Feed        Feed
+	Comments    Comments
+	Reactions   Reactions
+	Secrets     Secrets
+
+	// File - прочитанный файл настроек; пустой - файла нет
+	File string
+	// sources - откуда взято значение каждого ключа, заданного не по умолчанию
+	sources map[string]string
+}
+
+// Server - HTTP сервер
+type Server struct {
+	Addr string
+	// BaseURL - адрес блога для абсолютных ссылок; пустой - ленты и карта сайта отключены
+	BaseURL   string
+	StaticDir string
+	// RobotsFile - правила robots.txt вместо стандартных
+	RobotsFile   string
+	CookieSecure bool
+
+	// Таймауты соединения: чтение заголовков, всего запроса, запись ответа
+	// и ожидание следующего запроса keep-alive; 0 - без ограничения
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderKB       int
+	// MaxBodyMB - наибольшее тело запроса; формы с файлами ограничены
+	// размером вложений
+	MaxBodyMB int
+
+	// DrainDelay - сколько после сигнала остановки сервер ещё принимает
+	// запросы, отвечая на /readyz 503, чтобы балансировщик успел его убрать
+	DrainDelay time.Duration
+	// ShutdownTimeout - сколько ждать завершения начатых запросов
+	ShutdownTimeout time.Duration
+}
+
+// Database - подключение к БД
+type Database struct {
+	Driver string
+	DSN    string
+}
+
+// Templates - HTML шаблоны
+type Templates struct {
+	Dir string
+}
+
+// Log - журнал сервера
+type Log struct {
+	// File - файл журнала; пустой - stderr
+	File string
+	// Requests - записывать ли в журнал HTTP запросы
+	Requests bool
+}
+
+// Storage - хранилище файлов вложений
+type Storage struct {
+	// Backend - local (каталог UploadsDir) или s3
+	Backend    string
+	UploadsDir string
+	S3         S3
+}
+
+// S3 - бакет S3-совместимого хранилища
+type S3 struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// Attachments - вложения постов
+type Attachments struct {
+	MaxSizeMB int
+}
+
+// Feed - ленты RSS, Atom и JSON Feed
+type Feed struct {
+	Size int
+	// Content - full (полный текст постов) или excerpt (отрывки)
+	Content string
+}
+
+// Comments - комментарии
+type Comments struct {
+	MaxDepth int
+}
+
+// Reactions - реакции на посты и комментарии
+type Reactions struct {
+	Emoji []string
+}
+
+// Secrets - ключи подписи. Пустой ключ посетителей создаётся один раз и
+// хранится в БД, пустой ключ спам фильтра создаётся при каждом запуске
+type Secrets struct {
+	Visitor string
+	Spam    string
+}
+
+// Default возвращает настройки по умолчанию
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:              ":3000",
+			StaticDir:         "static",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       2 * time.Minute,
+			WriteTimeout:      2 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderKB:       64,
+			MaxBodyMB:         2,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: Database{
+			Driver: "sqlite3",
+			DSN:    "./blog.db",
+		},
+		Templates: Templates{Dir: "templates"},
+		Log:       Log{Requests: true},
+		Storage: Storage{
+			Backend:    "local",
+			UploadsDir: "./uploads",
+			S3:         S3{Region: "us-east-1"},
+		},
+		Attachments: Attachments{MaxSizeMB: 10},
+		Feed:        Feed{Size: 20, Content: "full"},
+		Comments:    Comments{MaxDepth: 5},
+		Reactions:   Reactions{Emoji: []string{"👍", "❤️", "😂", "😮", "😢"}},
+		sources:     make(map[string]string),
+	}
+}
+
+// setting - одна настройка: ключ в файле, переменная окружения и флаг
+type setting struct {
+	key string
+	env string
+	// flag - имя флага; пустое у ключей, которым не место в командной строке
+	flag  string
+	usage string
+	value value
+	// secret - при выводе значение скрывается
+	secret bool
+}
+
+func (c *Config) settings() []*setting {
+	return []*setting{
+		{key: "server.addr", env: "HTTP_ADDR", flag: "addr", value: (*stringValue)(&c.Server.Addr),
+			usage: "адрес HTTP сервера"},
+		{key: "server.base_url", env: "BASE_URL", flag: "base-url", value: (*stringValue)(&c.Server.BaseURL),
+			usage: "адрес блога для лент и карты сайта; пустой - они отключены"},
+		{key: "server.static_dir", env: "STATIC_DIR", flag: "static-dir", value: (*stringValue)(&c.Server.StaticDir),
+			usage: "каталог статических файлов /static/"},
+		{key: "server.robots_file", env: "ROBOTS_FILE", flag: "robots-file", value: (*stringValue)(&c.Server.RobotsFile),
+			usage: "файл с правилами robots.txt вместо стандартных"},
+		{key: "server.cookie_secure", env: "COOKIE_SECURE", flag: "cookie-secure", value: (*boolValue)(&c.Server.CookieSecure),
+			usage: "флаг Secure у cookie (для HTTPS)"},
+		{key: "server.read_header_timeout", env: "HTTP_READ_HEADER_TIMEOUT", flag: "read-header-timeout", value: (*durationValue)(&c.Server.ReadHeaderTimeout),
+			usage: "время на чтение заголовков запроса"},
+		{key: "server.read_timeout", env: "HTTP_READ_TIMEOUT", flag: "read-timeout", value: (*durationValue)(&c.Server.ReadTimeout),
+			usage: "время на чтение всего запроса"},
+		{key: "server.write_timeout", env: "HTTP_WRITE_TIMEOUT", flag: "write-timeout", value: (*durationValue)(&c.Server.WriteTimeout),
+			usage: "время на запись ответа"},
+		{key: "server.idle_timeout", env: "HTTP_IDLE_TIMEOUT", flag: "idle-timeout", value: (*durationValue)(&c.Server.IdleTimeout),
+			usage: "время ожидания следующего запроса в соединении keep-alive"},
+		{key: "server.max_header_kb", env: "HTTP_MAX_HEADER_KB", flag: "max-header-kb", value: (*intValue)(&c.Server.MaxHeaderKB),
+			usage: "наибольший размер заголовков запроса, КБ"},
+		{key: "server.max_body_mb", env: "HTTP_MAX_BODY_MB", flag: "max-body-mb", value: (*intValue)(&c.Server.MaxBodyMB),
+			usage: "наибольшее тело запроса без файлов, МБ"},
+		{key: "server.drain_delay", env: "HTTP_DRAIN_DELAY", flag: "drain-delay", value: (*durationValue)(&c.Server.DrainDelay),
+			usage: "сколько принимать запросы после сигнала остановки, отвечая на /readyz 503"},
+		{key: "server.shutdown_timeout", env: "HTTP_SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", value: (*durationValue)(&c.Server.ShutdownTimeout),
+			usage: "сколько ждать завершения начатых запросов при остановке"},
+		{key: "database.driver", env: "DB_DRIVER", flag: "db-driver", value: (*stringValue)(&c.Database.Driver),
+			usage: "драйвер БД: sqlite3 или postgres"},
+		{key: "database.dsn", env: "DB_DSN", flag: "db-dsn", value: (*stringValue)(&c.Database.DSN),
+			usage: "путь к файлу SQLite или строка подключения PostgreSQL"},
+		{key: "templates.dir", env: "TEMPLATES_DIR", flag: "templates-dir", value: (*stringValue)(&c.Templates.Dir),
+			usage: "каталог HTML шаблонов"},
+		{key: "log.file", env: "LOG_FILE", flag: "log-file", value: (*stringValue)(&c.Log.File),
+			usage: "файл журнала; пустой - stderr"},
+		{key: "log.requests", env: "LOG_REQUESTS", flag: "log-requests", value: (*boolValue)(&c.Log.Requests),
+			usage: "записывать HTTP запросы в журнал"},
+		{key: "storage.backend", env: "STORAGE", flag: "storage", value: (*stringValue)(&c.Storage.Backend),
+			usage: "хранилище вложений: local или s3"},
+		{key: "storage.uploads_dir", env: "UPLOADS_DIR", flag: "uploads-dir", value: (*stringValue)(&c.Storage.UploadsDir),
+			usage: "каталог вложений для хранилища local"},
+		{key: "storage.s3.endpoint", env: "S3_ENDPOINT", flag: "s3-endpoint", value: (*stringValue)(&c.Storage.S3.Endpoint),
+			usage: "адрес S3-совместимого хранилища"},
+		{key: "storage.s3.region", env: "S3_REGION", flag: "s3-region", value: (*stringValue)(&c.Storage.S3.Region),
+			usage: "регион S3"},
+		{key: "storage.s3.bucket", env: "S3_BUCKET", flag: "s3-bucket", value: (*stringValue)(&c.Storage.S3.Bucket),
+			usage: "бакет S3"},
+		{key: "storage.s3.access_key", env: "S3_ACCESS_KEY", value: (*stringValue)(&c.Storage.S3.AccessKey),
+			usage: "ключ доступа S3"},
+		{key: "storage.s3.secret_key", env: "S3_SECRET_KEY", value: (*stringValue)(&c.Storage.S3.SecretKey),
+			usage: "секретный ключ S3", secret: true},
+		{key: "attachments.max_size_mb", env: "ATTACHMENT_MAX_MB", flag: "attachment-max-mb", value: (*intValue)(&c.Attachments.MaxSizeMB),
+			usage: "наибольший размер файла вложения, МБ"},
+		{key: "feed.size", env: "FEED_SIZE", flag: "feed-size", value: (*intValue)(&c.Feed.Size),
+			usage: "постов в лентах"},
+		{key: "feed.content", env: "FEED_CONTENT", flag: "feed-content", value: (*stringValue)(&c.Feed.Content),
+			usage: "содержимое лент: full или excerpt"},
+		{key: "comments.max_depth", env: "COMMENT_MAX_DEPTH", flag: "comment-max-depth", value: (*intValue)(&c.Comments.MaxDepth),
+			usage: "глубина веток ответов"},
+		{key: "reactions.emoji", env: "REACTIONS", flag: "reactions", value: (*listValue)(&c.Reactions.Emoji),
+			usage: "набор реакций через пробел или запятую"},
+		{key: "secrets.visitor", env: "VISITOR_SECRET", value: (*stringValue)(&c.Secrets.Visitor),
+			usage: "ключ подписи cookie посетителей", secret: true},
+		{key: "secrets.spam", env: "SPAM_SECRET", value: (*stringValue)(&c.Secrets.Spam),
+			usage: "ключ подписи токенов формы комментария", secret: true},
+	}
+}
+
+// Load собирает настройки: значения по умолчанию, файл (флаг -config,
+// переменная CONFIG_FILE или config.toml в текущем каталоге, если он есть),
+// переменные окружения и флаги из args. Возвращает аргументы после флагов.
+// Ошибки в значениях перечисляются все сразу вместе с источником неверного
+// значения, настройки при этом тоже возвращаются (их можно вывести);
+// -h возвращает flag.ErrHelp после вывода справки.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fset := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fset.String("config", "", "файл настроек TOML (CONFIG_FILE)")
+	flags := make(map[string]*pendingFlag)
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		_, isBool := s.value.(*boolValue)
+		// Значение по умолчанию попадает в справку -h (кроме false)
+		def := s.value.String()
+		if isBool && def == "false" {
+			def = ""
+		}
+		flags[s.flag] = &pendingFlag{value: def, isBool: isBool}
+		fset.Var(flags[s.flag], s.flag, s.usage+" ("+s.env+")")
+	}
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Использование: server [флаги]\n"+
+			"               server migrate [флаги] <команда>\n"+
+			"               server user [флаги] <команда>\n"+
+			"               server config print [флаги]\n\nФлаги:\n")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+
+	// Файл
+	path, explicit := *configFile, *configFile != ""
+	if !explicit {
+		path = os.Getenv("CONFIG_FILE")
+		explicit = path != ""
+	}
+	if !explicit {
+		path = DefaultFile
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		cfg.File = path
+		errs = append(errs, cfg.applyFile(path, data, settings)...)
+	case errors.Is(err, fs.ErrNotExist) && !explicit:
+		// config.toml не обязателен
+	default:
+		return nil, nil, fmt.Errorf("файл настроек: %w", err)
+	}
+
+	// Переменные окружения; пустая переменная считается незаданной
+	for _, s := range settings {
+		raw, ok := os.LookupEnv(s.env)
+		if !ok || raw == "" {
+			continue
+		}
+		source := "переменная " + s.env
+		if err := s.value.Set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %v", s.key, source, err))
+			continue
+		}
+		cfg.sources[s.key] = source
+	}
+
+	// Флаги, заданные явно
+	for _, s := range settings {
+		f, ok := flags[s.flag]
+		if !ok || !f.set {
+			continue
+		}
+		source := "флаг -" + s.flag
+		if err := s.value.Set(f.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %v", s.key, source, err))
+			continue
+		}
+		cfg.sources[s.key] = source
+	}
+
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return cfg, fset.Args(), fmt.Errorf("неверные настройки:\n%w", errors.Join(errs...))
+	}
+	return cfg, fset.Args(), nil
+}
+
+// applyFile задаёт значения из файла настроек
+func (c *Config) applyFile(path string, data []byte, settings []*setting) []error {
+	entries, err := parseTOML(data)
+	if err != nil {
+		return []error{fmt.Errorf("%s:%v", path, err)}
+	}
+
+	byKey := make(map[string]*setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	var errs []error
+	for _, e := range entries {
+		source := fmt.Sprintf("%s:%d", path, e.line)
+		s, ok := byKey[e.key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: неизвестный параметр %s", source, e.key))
+			continue
+		}
+		if err := s.value.setTOML(e.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %v", source, e.key, err))
+			continue
+		}
+		c.sources[e.key] = source
+	}
+	return errs
+}
+
+// validate проверяет значения целиком; ошибка называет ключ и откуда взято значение
+func (c *Config) validate() []error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		source := c.Source(key)
+		if source == "" {
+			source = "по умолчанию"
+		}
+		errs = append(errs, fmt.Errorf("%s (%s): %s", key, source, fmt.Sprintf(format, args...)))
+	}
+
+	if _, port, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		fail("server.addr", "ожидается хост:порт или :порт, получено %q", c.Server.Addr)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		fail("server.addr", "неверный порт %q", port)
+	}
+	if c.Server.BaseURL != "" {
+		u, err := url.Parse(c.Server.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("server.base_url", "ожидается адрес вида https://blog.example.com, получено %q", c.Server.BaseURL)
+		} else if u.RawQuery != "" || u.Fragment != "" {
+			fail("server.base_url", "адрес не должен содержать параметры запроса и якорь")
+		}
+	}
+	for _, t := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.drain_delay", c.Server.DrainDelay},
+	} {
+		if t.d < 0 {
+			fail(t.key, "длительность не может быть отрицательной: %s", t.d)
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout", "ожидается положительная длительность, получено %s", c.Server.ShutdownTimeout)
+	}
+	if c.Server.MaxHeaderKB < 1 {
+		fail("server.max_header_kb", "размер должен быть не меньше 1, получено %d", c.Server.MaxHeaderKB)
+	}
+	if c.Server.MaxBodyMB < 1 {
+		fail("server.max_body_mb", "размер должен быть не меньше 1, получено %d", c.Server.MaxBodyMB)
+	}
+	if c.Server.StaticDir == "" {
+		fail("server.static_dir", "не задан каталог")
+	}
+
+	switch c.Database.Driver {
+	case "", "sqlite3", "sqlite", "postgres", "postgresql", "pg":
+	default:
+		fail("database.driver", "ожидается sqlite3 или postgres, получено %q", c.Database.Driver)
+	}
+	if c.Database.DSN == "" {
+		fail("database.dsn", "не задана строка подключения")
+	}
+
+	if c.Templates.Dir == "" {
+		fail("templates.dir", "не задан каталог")
+	}
+
+	switch c.Storage.Backend {
+	case "local":
+		if c.Storage.UploadsDir == "" {
+			fail("storage.uploads_dir", "не задан каталог вложений")
+		}
+	case "s3":
+		s3 := c.Storage.S3
+		if u, err := url.Parse(s3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("storage.s3.endpoint", "ожидается адрес вида http://localhost:9000, получено %q", s3.Endpoint)
+		}
+		if s3.Bucket == "" {
+			fail("storage.s3.bucket", "не задан бакет")
+		}
+		if s3.AccessKey == "" {
+			fail("storage.s3.access_key", "не задан ключ доступа")
+		}
+		if s3.SecretKey == "" {
+			fail("storage.s3.secret_key", "не задан секретный ключ")
+		}
+	default:
+		fail("storage.backend", "ожидается local или s3, получено %q", c.Storage.Backend)
+	}
+
+	if c.Attachments.MaxSizeMB < 1 {
+		fail("attachments.max_size_mb", "размер должен быть не меньше 1, получено %d", c.Attachments.MaxSizeMB)
+	}
+	if c.Feed.Size < 1 || c.Feed.Size > 100 {
+		fail("feed.size", "ожидается от 1 до 100, получено %d", c.Feed.Size)
+	}
+	if c.Feed.Content != "full" && c.Feed.Content != "excerpt" {
+		fail("feed.content", "ожидается full или excerpt, получено %q", c.Feed.Content)
+	}
+	if c.Comments.MaxDepth < 1 {
+		fail("comments.max_depth", "глубина должна быть не меньше 1, получено %d", c.Comments.MaxDepth)
+	}
+	if len(c.Reactions.Emoji) == 0 {
+		fail("reactions.emoji", "набор реакций пуст")
+	}
+
+	return errs
+}
+
+// Source возвращает, откуда взято значение ключа: файл со строкой,
+// переменная окружения или флаг; пустая строка - значение по умолчанию
+func (c *Config) Source(key string) string {
+	return c.sources[key]
+}
+
+// pendingFlag запоминает значение флага, чтобы применить его после файла
+// и переменных окружения
+type pendingFlag struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (f *pendingFlag) String() string { return f.value }
+
+func (f *pendingFlag) Set(value string) error {
+	f.value, f.set = value, true
+	return nil
+}
+
+func (f *pendingFlag) IsBoolFlag() bool { return f.isBool }
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv убирает переменные настроек окружения, в котором запущены тесты:
+// пустая переменная считается незаданной
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range Default().settings() {
+		t.Setenv(s.env, "")
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Файл важнее значений по умолчанию, переменная - файла, флаг - переменной
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, `[server]
+addr = ":4000"
+shutdown_timeout = "10s"
+
+[database]
+dsn = "file.db"
+
+[feed]
+size = 5
+
+[reactions]
+emoji = ["👍", "🔥"]
+`)
+	t.Setenv("HTTP_ADDR", ":5000")
+	t.Setenv("FEED_SIZE", "7")
+	t.Setenv("DB_DRIVER", "")
+
+	cfg, rest, err := Load([]string{"-config", path, "-addr", ":6000", "migrate", "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rest, []string{"migrate", "up"}) {
+		t.Errorf("аргументы после флагов: %q", rest)
+	}
+	if cfg.File != path {
+		t.Errorf("File = %q, want %q", cfg.File, path)
+	}
+
+	tests := []struct {
+		key    string
+		got    any
+		want   any
+		source string
+	}{
+		{"server.addr", cfg.Server.Addr, ":6000", "флаг -addr"},
+		{"feed.size", cfg.Feed.Size, 7, "переменная FEED_SIZE"},
+		{"database.dsn", cfg.Database.DSN, "file.db", path + ":6"},
+		{"server.shutdown_timeout", cfg.Server.ShutdownTimeout, 10 * time.Second, path + ":3"},
+		{"reactions.emoji", cfg.Reactions.Emoji, []string{"👍", "🔥"}, path + ":12"},
+		{"database.driver", cfg.Database.Driver, "sqlite3", ""},
+		{"comments.max_depth", cfg.Comments.MaxDepth, 5, ""},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+		}
+		if source := cfg.Source(tt.key); source != tt.source {
+			t.Errorf("Source(%s) = %q, want %q", tt.key, source, tt.source)
+		}
+	}
+}
+
+// Файл берётся из флага -config, затем из CONFIG_FILE; без них config.toml
+// не обязателен, а явно указанный файл - обязателен
+func TestLoadFile(t *testing.T) {
+	clearEnv(t)
+	fromEnv := writeFile(t, "[feed]\nsize = 3\n")
+	fromFlag := writeFile(t, "[feed]\nsize = 4\n")
+
+	t.Setenv("CONFIG_FILE", fromEnv)
+	cfg, _, err := Load(nil)
+	if err != nil || cfg.Feed.Size != 3 {
+		t.Fatalf("CONFIG_FILE: size %v, %v", cfg, err)
+	}
+	cfg, _, err = Load([]string{"-config", fromFlag})
+	if err != nil || cfg.Feed.Size != 4 {
+		t.Fatalf("-config важнее CONFIG_FILE: %v, %v", cfg, err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "нет.toml")
+	if _, _, err := Load([]string{"-config", missing}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("явно указанный файл без файла: %v", err)
+	}
+
+	// В каталоге пакета config.toml нет: значения по умолчанию
+	t.Setenv("CONFIG_FILE", "")
+	cfg, _, err = Load(nil)
+	if err != nil || cfg.File != "" {
+		t.Errorf("без файла: File %q, %v", cfg.File, err)
+	}
+
+	if _, _, err := Load([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h: %v", err)
+	}
+}
+
+// Все ошибки перечисляются сразу, каждая - с ключом и источником значения
+func TestLoadErrors(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, `[server]
+addr = "localhost"
+max_body_mb = "много"
+timeout = "1s"
+
+[feed]
+size = 500
+`)
+	t.Setenv("COMMENT_MAX_DEPTH", "глубоко")
+	t.Setenv("HTTP_READ_TIMEOUT", "-1s")
+
+	cfg, _, err := Load([]string{"-config", path, "-db-driver", "mysql", "-feed-content", "summary"})
+	if err == nil {
+		t.Fatal("неверные настройки приняты")
+	}
+	if cfg == nil {
+		t.Fatal("с ошибками настройки тоже возвращаются")
+	}
+	want := strings.Join([]string{
+		"неверные настройки:",
+		path + ":3: server.max_body_mb: ожидается целое число, получено: строка",
+		path + ":4: неизвестный параметр server.timeout",
+		`comments.max_depth (переменная COMMENT_MAX_DEPTH): ожидается целое число, получено "глубоко"`,
+		`server.addr (` + path + `:2): ожидается хост:порт или :порт, получено "localhost"`,
+		"server.read_timeout (переменная HTTP_READ_TIMEOUT): длительность не может быть отрицательной: -1s",
+		`database.driver (флаг -db-driver): ожидается sqlite3 или postgres, получено "mysql"`,
+		"feed.size (" + path + ":7): ожидается от 1 до 100, получено 500",
+		`feed.content (флаг -feed-content): ожидается full или excerpt, получено "summary"`,
+	}, "\n")
+	if err.Error() != want {
+		t.Errorf("ошибка:\n%s\nwant:\n%s", err, want)
+	}
+
+	// Синтаксическая ошибка файла - с путём и строкой
+	broken := writeFile(t, "[server]\naddr = :3000\n")
+	clearEnv(t)
+	_, _, err = Load([]string{"-config", broken})
+	want = "неверные настройки:\n" + broken + ":2: неподдерживаемое значение :3000: " +
+		"ожидается строка в кавычках, целое число, true/false или массив"
+	if err == nil || err.Error() != want {
+		t.Errorf("ошибка разбора:\n%v\nwant:\n%s", err, want)
+	}
+}
+
+// Проверки, которые зависят от сочетания значений
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   []string
+	}{
+		{"по умолчанию", func(*Config) {}, nil},
+		{"base_url без схемы", func(c *Config) { c.Server.BaseURL = "blog.example.com" },
+			[]string{`server.base_url (по умолчанию): ожидается адрес вида https://blog.example.com, получено "blog.example.com"`}},
+		{"base_url с запросом", func(c *Config) { c.Server.BaseURL = "https://blog.example.com/?a=1" },
+			[]string{"server.base_url (по умолчанию): адрес не должен содержать параметры запроса и якорь"}},
+		{"неверный порт", func(c *Config) { c.Server.Addr = ":99999" },
+			[]string{`server.addr (по умолчанию): неверный порт "99999"`}},
+		{"s3 без настроек", func(c *Config) { c.Storage.Backend = "s3" }, []string{
+			`storage.s3.endpoint (по умолчанию): ожидается адрес вида http://localhost:9000, получено ""`,
+			"storage.s3.bucket (по умолчанию): не задан бакет",
+			"storage.s3.access_key (по умолчанию): не задан ключ доступа",
+			"storage.s3.secret_key (по умолчанию): не задан секретный ключ",
+		}},
+		{"s3 целиком", func(c *Config) {
+			c.Storage.Backend = "s3"
+			c.Storage.S3 = S3{Endpoint: "http://localhost:9000", Bucket: "blog", AccessKey: "a", SecretKey: "s"}
+		}, nil},
+		{"пустые значения", func(c *Config) {
+			c.Database.DSN = ""
+			c.Reactions.Emoji = nil
+			c.Server.ShutdownTimeout = 0
+		}, []string{
+			"server.shutdown_timeout (по умолчанию): ожидается положительная длительность, получено 0s",
+			"database.dsn (по умолчанию): не задана строка подключения",
+			"reactions.emoji (по умолчанию): набор реакций пуст",
+		}},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		tt.change(cfg)
+		var got []string
+		for _, err := range cfg.validate() {
+			got = append(got, err.Error())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Разбор файла настроек. Поддерживается часть TOML, которой хватает для
+// настроек сервера: таблицы [раздел] и [раздел.подраздел], ключи вида
+// ключ = значение (в том числе через точку), строки в двойных и одинарных
+// кавычках, целые числа, true/false, массивы (можно на нескольких строках)
+// и комментарии #.
+
+// entry - значение из файла с полным ключом (раздел.ключ) и номером строки
+type entry struct {
+	key   string
+	value any
+	line  int
+}
+
+// parseError - ошибка разбора в строке line; выводится как "line: сообщение"
+type parseError struct {
+	line int
+	msg  string
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("%d: %s", e.line, e.msg)
+}
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func parseTOML(data []byte) ([]entry, error) {
+	p := &tomlParser{src: string(data), line: 1}
+	var entries []entry
+	seen := make(map[string]int)
+	table := ""
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return entries, nil
+		}
+
+		if p.peek() == '[' {
+			if strings.HasPrefix(p.src[p.pos:], "[[") {
+				return nil, p.errorf("массивы таблиц не поддерживаются")
+			}
+			p.pos++
+			name, err := p.key(']')
+			if err != nil {
+				return nil, err
+			}
+			p.pos++ // ]
+			if err := p.endOfLine(); err != nil {
+				return nil, err
+			}
+			table = name
+			continue
+		}
+
+		line := p.line
+		key, err := p.key('=')
+		if err != nil {
+			return nil, err
+		}
+		p.pos++ // =
+		p.skipSpaces()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+
+		if table != "" {
+			key = table + "." + key
+		}
+		if prev, ok := seen[key]; ok {
+			return nil, &parseError{line, fmt.Sprintf("%s уже задан в строке %d", key, prev)}
+		}
+		seen[key] = line
+		entries = append(entries, entry{key: key, value: value, line: line})
+	}
+}
+
+// key читает ключ или имя таблицы из слов через точку до символа end
+func (p *tomlParser) key(end byte) (string, error) {
+	var parts []string
+	for {
+		p.skipSpaces()
+		start := p.pos
+		for !p.eof() && isBareKeyChar(p.peek()) {
+			p.pos++
+		}
+		if start == p.pos {
+			if !p.eof() && (p.peek() == '"' || p.peek() == '\'') {
+				return "", p.errorf("ключи в кавычках не поддерживаются")
+			}
+			return "", p.errorf("ожидается имя ключа")
+		}
+		parts = append(parts, p.src[start:p.pos])
+		p.skipSpaces()
+		switch {
+		case p.eof():
+			return "", p.errorf("ожидается %q", end)
+		case p.peek() == '.':
+			p.pos++
+		case p.peek() == end:
+			return strings.Join(parts, "."), nil
+		default:
+			return "", p.errorf("ожидается %q после %s", end, strings.Join(parts, "."))
+		}
+	}
+}
+
+func (p *tomlParser) value() (any, error) {
+	if p.eof() || p.peek() == '\n' || p.peek() == '#' {
+		return nil, p.errorf("не задано значение")
+	}
+	switch c := p.peek(); {
+	case c == '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return nil, p.errorf("многострочные строки не поддерживаются")
+		}
+		return p.basicString()
+	case c == '\'':
+		if strings.HasPrefix(p.src[p.pos:], "'''") {
+			return nil, p.errorf("многострочные строки не поддерживаются")
+		}
+		p.pos++
+		end := strings.IndexAny(p.src[p.pos:], "'\n")
+		if end < 0 || p.src[p.pos+end] != '\'' {
+			return nil, p.errorf("не закрыта строка")
+		}
+		s := p.src[p.pos : p.pos+end]
+		p.pos += end + 1
+		return s, nil
+	case c == '[':
+		return p.array()
+	default:
+		start := p.pos
+		for !p.eof() && !strings.ContainsRune(" \t\r\n#,]", rune(p.peek())) {
+			p.pos++
+		}
+		word := p.src[start:p.pos]
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+		if err != nil || strings.HasPrefix(word, "_") || strings.HasSuffix(word, "_") {
+			p.pos = start
+			return nil, p.errorf("неподдерживаемое значение %s: ожидается строка в кавычках, целое число, true/false или массив", word)
+		}
+		return n, nil
+	}
+}
+
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++ // "
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("не закрыта строка")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("не закрыта строка")
+			}
+			esc := p.peek()
+			p.pos++
+			switch esc {
+			case 'b':
+				b.WriteByte('\b')
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'f':
+				b.WriteByte('\f')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(esc)
+			case 'u', 'U':
+				size := 4
+				if esc == 'U' {
+					size = 8
+				}
+				if p.pos+size > len(p.src) {
+					return "", p.errorf("неверная escape-последовательность")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(code)) {
+					return "", p.errorf("неверная escape-последовательность \\%c%s", esc, p.src[p.pos:p.pos+size])
+				}
+				b.WriteRune(rune(code))
+				p.pos += size
+			default:
+				return "", p.errorf("неверная escape-последовательность \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++ // [
+	items := []any{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("не закрыт массив")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return items, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.skipBlank()
+		if !p.eof() && p.peek() == ',' {
+			p.pos++
+			continue
+		}
+		if p.eof() || p.peek() != ']' {
+			return nil, p.errorf("ожидается ',' или ']' в массиве")
+		}
+	}
+}
+
+// endOfLine пропускает пробелы и комментарий до конца строки
+func (p *tomlParser) endOfLine() error {
+	p.skipSpaces()
+	if !p.eof() && p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	if !p.eof() && p.peek() != '\n' {
+		return p.errorf("лишние символы в конце строки: %s", p.rest())
+	}
+	return nil
+}
+
+// skipBlank пропускает пробелы, переводы строк и комментарии
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t' || p.peek() == '\r') {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *tomlParser) peek() byte { return p.src[p.pos] }
+
+// rest - остаток текущей строки для сообщений об ошибках
+func (p *tomlParser) rest() string {
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.src) - p.pos
+	}
+	return strings.TrimSpace(p.src[p.pos : p.pos+end])
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return &parseError{p.line, fmt.Sprintf(format, args...)}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// quote записывает строку в двойных кавычках TOML
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
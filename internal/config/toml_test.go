@@ -0,0 +1,124 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	src := `# Настройки
+top = "вне таблиц"
+
+[server]
+addr = ":8080"   # комментарий после значения
+base_url = 'https://blog.example.com/path\n'
+cookie_secure = true
+max_body_mb = 1_024
+
+[storage.s3]
+endpoint = "http://localhost:9000"
+
+[reactions]
+emoji = [
+  "👍",   # первая
+  "\u2764\uFE0F",
+  'x',
+]
+empty = []
+log.requests = false
+`
+	entries, err := parseTOML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []entry{
+		{key: "top", value: "вне таблиц", line: 2},
+		{key: "server.addr", value: ":8080", line: 5},
+		{key: "server.base_url", value: `https://blog.example.com/path\n`, line: 6},
+		{key: "server.cookie_secure", value: true, line: 7},
+		{key: "server.max_body_mb", value: int64(1024), line: 8},
+		{key: "storage.s3.endpoint", value: "http://localhost:9000", line: 11},
+		{key: "reactions.emoji", value: []any{"👍", "❤️", "x"}, line: 14},
+		{key: "reactions.empty", value: []any{}, line: 19},
+		{key: "reactions.log.requests", value: false, line: 20},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("parseTOML:\n got %#v\nwant %#v", entries, want)
+	}
+}
+
+func TestParseTOMLStrings(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{`v = "a\tb\nc"`, "a\tb\nc"},
+		{`v = "кавычка \" и слеш \\"`, `кавычка " и слеш \`},
+		{`v = "\b\f\r"`, "\b\f\r"},
+		{`v = "\u00e9\U0001F600"`, "é😀"},
+		{`v = 'C:\dir\file'`, `C:\dir\file`},
+		{`v = "# не комментарий"`, "# не комментарий"},
+		{`v = ""`, ""},
+	}
+	for _, tt := range tests {
+		entries, err := parseTOML([]byte(tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(entries) != 1 || entries[0].value != tt.want {
+			t.Errorf("%s: %#v, want %q", tt.src, entries, tt.want)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"повтор ключа", "a = 1\nb = 2\na = 3", "3: a уже задан в строке 1"},
+		{"повтор ключа в таблице", "[s]\na = 1\n[t]\nb = 1\n[s]\na = 2", "6: s.a уже задан в строке 2"},
+		{"повтор через точку", "server.addr = \":1\"\n[server]\naddr = \":2\"", "3: server.addr уже задан в строке 1"},
+		{"нет значения", "a =\n", "1: не задано значение"},
+		{"нет значения перед комментарием", "a = # пусто", "1: не задано значение"},
+		{"нет =", "\n\nkey value", "3: ожидается '=' после key"},
+		{"ключ в кавычках", `"a" = 1`, "1: ключи в кавычках не поддерживаются"},
+		{"пустая таблица", "[]", "1: ожидается имя ключа"},
+		{"незакрытая таблица", "[server", "1: ожидается ']'"},
+		{"массив таблиц", "[[posts]]", "1: массивы таблиц не поддерживаются"},
+		{"незакрытая строка", "a = \"abc\nb = 1", "1: не закрыта строка"},
+		{"незакрытая одинарная строка", "a = 'abc", "1: не закрыта строка"},
+		{"многострочная строка", `a = """x"""`, "1: многострочные строки не поддерживаются"},
+		{"неверный escape", `a = "\q"`, `1: неверная escape-последовательность \q`},
+		{"неверный \\u", `a = "\uZZZZ"`, `1: неверная escape-последовательность \uZZZZ`},
+		{"короткий \\u", `a = "\u12"`, "1: неверная escape-последовательность"},
+		{"суррогат", `a = "\uD800"`, `1: неверная escape-последовательность \uD800`},
+		{"дробное число", "a = 1.5", "1: неподдерживаемое значение 1.5: ожидается строка в кавычках, целое число, true/false или массив"},
+		{"подчёркивание в начале", "a = _1", "1: неподдерживаемое значение _1: ожидается строка в кавычках, целое число, true/false или массив"},
+		{"строка без кавычек", "a = yes", "1: неподдерживаемое значение yes: ожидается строка в кавычках, целое число, true/false или массив"},
+		{"лишнее после значения", `a = "x" y`, "1: лишние символы в конце строки: y"},
+		{"лишнее после таблицы", "[s] x", "1: лишние символы в конце строки: x"},
+		{"незакрытый массив", "a = [\n1,\n", "3: не закрыт массив"},
+		{"массив до конца файла", "a = [1, 2", "1: ожидается ',' или ']' в массиве"},
+		{"массив без запятой", "a = [1 2]", "1: ожидается ',' или ']' в массиве"},
+		{"ошибка в массиве", "a = [\n  1,\n  x,\n]", "3: неподдерживаемое значение x: ожидается строка в кавычках, целое число, true/false или массив"},
+	}
+	for _, tt := range tests {
+		_, err := parseTOML([]byte(tt.src))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: ошибка %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "обычная", `с "кавычками" и \ слешем`, "строки\nи\tтабы", "управляющий \x01 и \x7f"} {
+		entries, err := parseTOML([]byte("v = " + quote(s)))
+		if err != nil {
+			t.Errorf("quote(%q) = %s: %v", s, quote(s), err)
+			continue
+		}
+		if entries[0].value != s {
+			t.Errorf("quote(%q) = %s читается как %q", s, quote(s), entries[0].value)
+		}
+	}
+}
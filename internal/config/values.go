@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// value - поле Config, которое задаётся строкой (переменная окружения, флаг)
+// или значением из файла TOML
+type value interface {
+	// Set разбирает значение из переменной окружения или флага, String - обратно
+	Set(raw string) error
+	String() string
+	// setTOML принимает значение из файла: string, int64, bool или []any
+	setTOML(v any) error
+	// toml возвращает значение в записи TOML
+	toml() string
+}
+
+type stringValue string
+
+func (v *stringValue) Set(raw string) error {
+	*v = stringValue(raw)
+	return nil
+}
+
+func (v *stringValue) setTOML(x any) error {
+	s, ok := x.(string)
+	if !ok {
+		return typeError("строка", x)
+	}
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+
+func (v *stringValue) toml() string { return quote(string(*v)) }
+
+type intValue int
+
+func (v *intValue) Set(raw string) error {
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("ожидается целое число, получено %q", raw)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) setTOML(x any) error {
+	n, ok := x.(int64)
+	if !ok {
+		return typeError("целое число", x)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *intValue) toml() string { return v.String() }
+
+type boolValue bool
+
+func (v *boolValue) Set(raw string) error {
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return fmt.Errorf("ожидается true или false, получено %q", raw)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) setTOML(x any) error {
+	b, ok := x.(bool)
+	if !ok {
+		return typeError("true или false", x)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+func (v *boolValue) toml() string { return v.String() }
+
+// durationValue - длительность в записи Go: 30s, 2m, 1h30m
+type durationValue time.Duration
+
+func (v *durationValue) Set(raw string) error {
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return fmt.Errorf("ожидается длительность вида 30s или 2m, получено %q", raw)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) setTOML(x any) error {
+	s, ok := x.(string)
+	if !ok {
+		return typeError("длительность в кавычках (\"30s\")", x)
+	}
+	return v.Set(s)
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+func (v *durationValue) toml() string { return quote(v.String()) }
+
+// listValue - список строк; в переменной и флаге элементы перечисляются
+// через пробел или запятую, в файле - массивом
+type listValue []string
+
+func (v *listValue) Set(raw string) error {
+	*v = strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	return nil
+}
+
+func (v *listValue) setTOML(x any) error {
+	items, ok := x.([]any)
+	if !ok {
+		return typeError("массив строк", x)
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return typeError("массив строк", x)
+		}
+		list = append(list, s)
+	}
+	*v = list
+	return nil
+}
+
+func (v *listValue) String() string { return strings.Join(*v, " ") }
+
+func (v *listValue) toml() string {
+	items := make([]string, len(*v))
+	for i, s := range *v {
+		items[i] = quote(s)
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+func typeError(want string, got any) error {
+	kind := "строка"
+	switch got.(type) {
+	case int64:
+		kind = "число"
+	case bool:
+		kind = "true/false"
+	case []any:
+		kind = "массив"
+	}
+	return fmt.Errorf("ожидается %s, получено: %s", want, kind)
+}
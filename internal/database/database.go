@@ -2,87 +2,113 @@ package database
 
 import (
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
+	"strings"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // InitDB инициализирует подключение к базе данных и выполняет миграции
-func InitDB(dbPath string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+func InitDB(driver, dsn string) (*DB, error) {
+	db, err := OpenDB(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	// Настройка SQLite WAL режима
-	_, err = db.Exec("PRAGMA journal_mode=WAL")
+	// Выполняем миграции
+	if err := runMigrations(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// OpenDB открывает базу данных без применения миграций.
+// driver - sqlite3 или postgres, dsn - путь к файлу SQLite или строка подключения PostgreSQL.
+func OpenDB(driver, dsn string) (*DB, error) {
+	dialect, err := ParseDialect(driver)
 	if err != nil {
 		return nil, err
 	}
 
-	// Выполняем миграции
-	if err := runMigrations(db); err != nil {
+	if dialect == DialectSQLite {
+		return openSQLite(dsn)
+	}
+
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
 		return nil, err
 	}
 
-	return db, nil
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return &DB{DB: sqlDB, Dialect: DialectPostgres}, nil
 }
 
-// runMigrations выполняет все миграции базы данных
-func runMigrations(db *sql.DB) error {
-	migrations := []string{
-		createPostsTable,
-		createCategoriesTable,
-		createCommentsTable,
-		createLikesTable,
+// Close закрывает БД. У SQLite перед этим журнал WAL переносится в основной
+// файл и обрезается, чтобы остановленная база была целиком в одном файле.
+func (db *DB) Close() error {
+	var checkpointErr error
+	if db.Dialect == DialectSQLite {
+		if _, err := db.DB.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			checkpointErr = fmt.Errorf("перенос журнала WAL: %w", err)
+		}
 	}
+	return errors.Join(checkpointErr, db.DB.Close())
+}
 
-	for i, migration := range migrations {
-		_, err := db.Exec(migration)
-		if err != nil {
-			log.Printf("Migration %d failed: %v", i+1, err)
-			return err
+func openSQLite(dbPath string) (*DB, error) {
+	// Внешние ключи в SQLite включаются на каждом соединении,
+	// иначе ON DELETE CASCADE работает не так, как в PostgreSQL
+	if !strings.Contains(dbPath, "_foreign_keys") {
+		sep := "?"
+		if strings.Contains(dbPath, "?") {
+			sep = "&"
 		}
-		log.Printf("Migration %d completed successfully", i+1)
+		dbPath += sep + "_foreign_keys=on"
+	}
+
+	sqlDB, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Настройка SQLite WAL режима
+	_, err = sqlDB.Exec("PRAGMA journal_mode=WAL")
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
 	}
 
-	return nil
+	// Поиск построен на FTS5, который go-sqlite3 включает только тегом сборки
+	var fts5 bool
+	if err := sqlDB.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	if !fts5 {
+		sqlDB.Close()
+		return nil, ErrNoFTS5
+	}
+
+	return &DB{DB: sqlDB, Dialect: DialectSQLite}, nil
 }
 
-// SQL миграции
-const createPostsTable = `
-CREATE TABLE IF NOT EXISTS posts (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	title TEXT NOT NULL,
-	content TEXT NOT NULL,
-	category_id INTEGER NOT NULL,
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (category_id) REFERENCES categories(id)
-)`
-
-const createCategoriesTable = `
-CREATE TABLE IF NOT EXISTS categories (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	name TEXT NOT NULL UNIQUE,
-	slug TEXT NOT NULL UNIQUE,
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-)`
-
-const createCommentsTable = `
-CREATE TABLE IF NOT EXISTS comments (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	post_id INTEGER NOT NULL,
-	author TEXT NOT NULL,
-	content TEXT NOT NULL,
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
-)`
-
-const createLikesTable = `
-CREATE TABLE IF NOT EXISTS likes (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	post_id INTEGER NOT NULL,
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
-)`
+// ErrNoFTS5 - бинарник собран без поддержки FTS5 в SQLite
+var ErrNoFTS5 = errors.New("SQLite собран без FTS5: соберите сервер с флагом -tags sqlite_fts5 (см. Makefile)")
+
+// runMigrations применяет все новые миграции из internal/database/migrations
+func runMigrations(db *DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	return migrator.Up()
+}
@@ -0,0 +1,82 @@
+package database
+
+import "testing"
+
+func TestParseDialect(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   Dialect
+	}{
+		{"", DialectSQLite},
+		{"sqlite", DialectSQLite},
+		{"sqlite3", DialectSQLite},
+		{"postgres", DialectPostgres},
+		{"postgresql", DialectPostgres},
+		{"pg", DialectPostgres},
+	}
+	for _, tt := range tests {
+		got, err := ParseDialect(tt.driver)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDialect(%q) = %q, %v; want %q", tt.driver, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseDialect("mysql"); err == nil {
+		t.Error("ParseDialect(mysql): ожидалась ошибка")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{"sqlite без изменений", DialectSQLite,
+			"SELECT * FROM posts WHERE id = ? AND user_id = ?",
+			"SELECT * FROM posts WHERE id = ? AND user_id = ?"},
+		{"postgres нумерует", DialectPostgres,
+			"SELECT * FROM posts WHERE id = ? AND user_id = ?",
+			"SELECT * FROM posts WHERE id = $1 AND user_id = $2"},
+		{"без плейсхолдеров", DialectPostgres,
+			"SELECT 1",
+			"SELECT 1"},
+		{"знак вопроса в строке", DialectPostgres,
+			"UPDATE posts SET title = 'Что?' WHERE id = ?",
+			"UPDATE posts SET title = 'Что?' WHERE id = $1"},
+		{"экранированная кавычка", DialectPostgres,
+			"SELECT 'it''s ?', ? FROM posts WHERE id IN (?, ?)",
+			"SELECT 'it''s ?', $1 FROM posts WHERE id IN ($2, $3)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.Rebind(tt.query); got != tt.want {
+				t.Errorf("Rebind(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// У SQLite и PostgreSQL должен быть одинаковый набор миграций:
+// версия схемы означает одно и то же в обеих базах
+func TestMigrationsMatchAcrossDialects(t *testing.T) {
+	sqlite, err := loadMigrations(migrationsFS, "migrations/sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := loadMigrations(migrationsFS, "migrations/postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("миграций SQLite %d, PostgreSQL %d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("миграция %d: SQLite %04d_%s, PostgreSQL %04d_%s", i,
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
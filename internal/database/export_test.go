@@ -0,0 +1,9 @@
+package database
+
+// NewMigratorWith - Migrator с миграциями migrations вместо встроенных, для тестов
+func NewMigratorWith(db *DB, migrations []Migration) (*Migrator, error) {
+	if _, err := db.Exec(createSchemaMigrationsTable); err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
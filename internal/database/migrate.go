@@ -0,0 +1,346 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationsFS embed.FS
+
+// migrationFileRe описывает имя файла миграции: 0001_name.up.sql / 0001_name.down.sql
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	dirty BOOLEAN NOT NULL DEFAULT FALSE,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Migration - одна версионированная миграция схемы
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus - состояние миграции в конкретной базе
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// ErrDirty возвращается, если предыдущая миграция прервалась на середине
+type ErrDirty struct {
+	Version int
+}
+
+func (e ErrDirty) Error() string {
+	return fmt.Sprintf("база данных в грязном состоянии на версии %d: исправьте схему вручную и выполните `migrate force %d`", e.Version, e.Version)
+}
+
+// Migrator применяет и откатывает миграции, отслеживая версии в schema_migrations
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+}
+
+// NewMigrator загружает встроенные миграции диалекта и готовит таблицу schema_migrations
+func NewMigrator(db *DB) (*Migrator, error) {
+	dir := "migrations/sqlite"
+	if db.Dialect == DialectPostgres {
+		dir = "migrations/postgres"
+	}
+
+	migrations, err := loadMigrations(migrationsFS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(createSchemaMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations читает пары up/down файлов и сортирует их по версии
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("неверное имя файла миграции: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("версия %d используется миграциями %s и %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s должны быть up и down файлы", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Latest возвращает номер последней известной миграции
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version возвращает текущую версию схемы и признак грязного состояния
+func (m *Migrator) Version() (int, bool, error) {
+	var version int
+	var dirty bool
+	err := m.db.QueryRow(`SELECT version, dirty FROM schema_migrations ORDER BY version DESC LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
+
+// Status возвращает список всех миграций с отметкой о применении
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	rows, err := m.db.Query(`SELECT version, dirty, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]MigrationStatus)
+	for rows.Next() {
+		var st MigrationStatus
+		if err := rows.Scan(&st.Version, &st.Dirty, &st.AppliedAt); err != nil {
+			return nil, err
+		}
+		st.Applied = true
+		applied[st.Version] = st
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		st := applied[migration.Version]
+		st.Migration = migration
+		statuses = append(statuses, st)
+	}
+
+	return statuses, nil
+}
+
+// Up применяет все ещё не применённые миграции
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down откатывает одну последнюю применённую миграцию
+func (m *Migrator) Down() error {
+	current, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return ErrDirty{Version: current}
+	}
+	if current == 0 {
+		log.Println("Нет применённых миграций для отката")
+		return nil
+	}
+
+	target := 0
+	for _, migration := range m.migrations {
+		if migration.Version < current {
+			target = migration.Version
+		}
+	}
+
+	return m.To(target)
+}
+
+// To приводит схему к указанной версии, применяя или откатывая миграции по одной
+func (m *Migrator) To(target int) error {
+	current, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return ErrDirty{Version: current}
+	}
+	if target != 0 && m.find(target) == nil {
+		return fmt.Errorf("миграция версии %d не найдена", target)
+	}
+
+	if target >= current {
+		for _, migration := range m.migrations {
+			if migration.Version > current && migration.Version <= target {
+				if err := m.apply(migration, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= current && migration.Version > target {
+			if err := m.apply(migration, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Force помечает версию как чистую после ручного исправления схемы
+func (m *Migrator) Force(version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("миграция версии %d не найдена", version)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version > ?`, version); err != nil {
+		return err
+	}
+	if version > 0 {
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			_, err := tx.Exec(`
+				INSERT INTO schema_migrations (version, dirty) VALUES (?, FALSE)
+				ON CONFLICT (version) DO UPDATE SET dirty = FALSE
+			`, migration.Version)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// apply выполняет один шаг миграции в отдельной транзакции.
+// Перед шагом версия помечается грязной: если процесс упадёт посреди шага,
+// следующий запуск откажется продолжать, пока схему не проверят вручную.
+func (m *Migrator) apply(migration Migration, up bool) error {
+	direction, body := "up", migration.Up
+	if !up {
+		direction, body = "down", migration.Down
+	}
+
+	_, err := m.db.Exec(`
+		INSERT INTO schema_migrations (version, dirty) VALUES (?, TRUE)
+		ON CONFLICT (version) DO UPDATE SET dirty = TRUE
+	`, migration.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := m.runStep(migration.Version, body, up); err != nil {
+		err = fmt.Errorf("миграция %04d_%s (%s): %w", migration.Version, migration.Name, direction, err)
+
+		// Транзакция откатилась целиком, значит схема не тронута и отметку
+		// можно снять. Если не получилось, версия остаётся грязной.
+		var cleanupErr error
+		if up {
+			_, cleanupErr = m.db.Exec(`DELETE FROM schema_migrations WHERE version = ?`, migration.Version)
+		} else {
+			_, cleanupErr = m.db.Exec(`UPDATE schema_migrations SET dirty = FALSE WHERE version = ?`, migration.Version)
+		}
+		if cleanupErr != nil {
+			return errors.Join(err, fmt.Errorf("версия %d осталась в грязном состоянии: %w", migration.Version, cleanupErr))
+		}
+		return err
+	}
+
+	log.Printf("Миграция %04d_%s (%s) применена", migration.Version, migration.Name, direction)
+	return nil
+}
+
+func (m *Migrator) runStep(version int, body string, up bool) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(body); err != nil {
+		return err
+	}
+
+	if up {
+		_, err = tx.Exec(`UPDATE schema_migrations SET dirty = FALSE, applied_at = CURRENT_TIMESTAMP WHERE version = ?`, version)
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,225 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+)
+
+// Все миграции применяются на пустую базу, откатываются до нуля и применяются снова
+func TestMigrateUpDownUp(t *testing.T) {
+	dbtest.EachEmpty(t, func(t *testing.T, db *database.DB) {
+		migrator, err := database.NewMigrator(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := migrator.Up(); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		checkVersion(t, migrator, migrator.Latest())
+
+		// Повторный Up ничего не делает
+		if err := migrator.Up(); err != nil {
+			t.Fatalf("повторный Up: %v", err)
+		}
+		checkVersion(t, migrator, migrator.Latest())
+
+		if err := migrator.To(0); err != nil {
+			t.Fatalf("To(0): %v", err)
+		}
+		checkVersion(t, migrator, 0)
+
+		if err := migrator.Up(); err != nil {
+			t.Fatalf("Up после отката: %v", err)
+		}
+		checkVersion(t, migrator, migrator.Latest())
+	})
+}
+
+// Каждая миграция откатывается по одной и применяется снова
+func TestMigrateDownStepByStep(t *testing.T) {
+	dbtest.EachEmpty(t, func(t *testing.T, db *database.DB) {
+		migrator, err := database.NewMigrator(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := migrator.Up(); err != nil {
+			t.Fatal(err)
+		}
+
+		statuses, err := migrator.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := len(statuses) - 1; i >= 0; i-- {
+			version := statuses[i].Version
+			if err := migrator.Down(); err != nil {
+				t.Fatalf("Down %04d_%s: %v", version, statuses[i].Name, err)
+			}
+			if err := migrator.To(version); err != nil {
+				t.Fatalf("повторное применение %04d_%s: %v", version, statuses[i].Name, err)
+			}
+			if err := migrator.Down(); err != nil {
+				t.Fatalf("повторный Down %04d_%s: %v", version, statuses[i].Name, err)
+			}
+		}
+		checkVersion(t, migrator, 0)
+	})
+}
+
+func TestMigrateForce(t *testing.T) {
+	dbtest.EachEmpty(t, func(t *testing.T, db *database.DB) {
+		migrator, err := database.NewMigrator(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := migrator.To(3); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := migrator.Force(2); err != nil {
+			t.Fatal(err)
+		}
+		checkVersion(t, migrator, 2)
+	})
+}
+
+// 0010_like_owners оставляет от накликанных старых лайков по одному на пост
+func TestMigrateDedupsLegacyLikes(t *testing.T) {
+	dbtest.EachEmpty(t, func(t *testing.T, db *database.DB) {
+		migrator, err := database.NewMigrator(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := migrator.To(9); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Exec(`INSERT INTO categories (name, slug) VALUES ('Общие', 'general')`); err != nil {
+			t.Fatal(err)
+		}
+		likes := map[int]int{1: 1000, 2: 1, 3: 0}
+		for post := 1; post <= 3; post++ {
+			if _, err := db.Exec(`INSERT INTO posts (id, title, content, category_id) VALUES (?, 'Пост', 'Текст', 1)`, post); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < likes[post]; i++ {
+				if _, err := db.Exec(`INSERT INTO likes (post_id) VALUES (?)`, post); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		if err := migrator.To(10); err != nil {
+			t.Fatal(err)
+		}
+		want := map[int]int{1: 1, 2: 1, 3: 0}
+		for post, n := range want {
+			var count int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM likes WHERE post_id = ?`, post).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != n {
+				t.Errorf("пост %d: %d лайков после миграции, ожидался %d", post, count, n)
+			}
+		}
+	})
+}
+
+// 0017_reader_default меняет роль по умолчанию на reader, не трогая
+// роли существующих пользователей; откат возвращает author
+func TestMigrateReaderDefault(t *testing.T) {
+	dbtest.EachEmpty(t, func(t *testing.T, db *database.DB) {
+		migrator, err := database.NewMigrator(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := migrator.To(16); err != nil {
+			t.Fatal(err)
+		}
+
+		addUser := func(name string) {
+			t.Helper()
+			if _, err := db.Exec(`INSERT INTO users (username, password_hash) VALUES (?, 'hash')`, name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		role := func(name string) string {
+			t.Helper()
+			var role string
+			if err := db.QueryRow(`SELECT role FROM users WHERE username = ?`, name).Scan(&role); err != nil {
+				t.Fatal(err)
+			}
+			return role
+		}
+
+		addUser("old")
+		if _, err := db.Exec(`UPDATE users SET role = 'editor' WHERE username = 'old'`); err != nil {
+			t.Fatal(err)
+		}
+		if err := migrator.To(17); err != nil {
+			t.Fatal(err)
+		}
+		addUser("new")
+		if got := role("old"); got != "editor" {
+			t.Errorf("роль существующего пользователя после миграции: %s, ожидалась editor", got)
+		}
+		if got := role("new"); got != "reader" {
+			t.Errorf("роль по умолчанию после миграции: %s, ожидалась reader", got)
+		}
+
+		if err := migrator.To(16); err != nil {
+			t.Fatal(err)
+		}
+		addUser("rolled-back")
+		if got := role("new"); got != "reader" {
+			t.Errorf("роль пользователя после отката: %s, ожидалась reader", got)
+		}
+		if got := role("rolled-back"); got != "author" {
+			t.Errorf("роль по умолчанию после отката: %s, ожидалась author", got)
+		}
+	})
+}
+
+// Неудачный шаг откатывается целиком и не оставляет версию грязной
+func TestMigrateFailedStep(t *testing.T) {
+	dbtest.EachEmpty(t, func(t *testing.T, db *database.DB) {
+		migrator, err := database.NewMigratorWith(db, []database.Migration{
+			{Version: 1, Name: "notes", Up: `CREATE TABLE notes (id INTEGER PRIMARY KEY)`, Down: `DROP TABLE missing_table`},
+			{Version: 2, Name: "broken", Up: `ALTER TABLE notes ADD COLUMN body TEXT; SELECT * FROM missing_table`, Down: `SELECT 1`},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = migrator.Up()
+		if err == nil || !strings.HasPrefix(err.Error(), "миграция 0002_broken (up): ") {
+			t.Fatalf("Up = %v, ожидалась ошибка миграции 0002_broken", err)
+		}
+		checkVersion(t, migrator, 1)
+		// Первая половина шага откатилась вместе со второй
+		if _, err := db.Exec(`INSERT INTO notes (id, body) VALUES (1, 'текст')`); err == nil {
+			t.Error("колонка из неудачной миграции осталась")
+		}
+
+		err = migrator.Down()
+		if err == nil || !strings.HasPrefix(err.Error(), "миграция 0001_notes (down): ") {
+			t.Fatalf("Down = %v, ожидалась ошибка миграции 0001_notes", err)
+		}
+		checkVersion(t, migrator, 1)
+	})
+}
+
+func checkVersion(t *testing.T, migrator *database.Migrator, want int) {
+	t.Helper()
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != want || dirty {
+		t.Fatalf("версия %d (dirty %v), ожидалась %d", version, dirty, want)
+	}
+}
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS posts_search_comments ON comments;
+DROP TRIGGER IF EXISTS posts_search_post ON posts;
+DROP FUNCTION IF EXISTS posts_search_sync_comments();
+DROP FUNCTION IF EXISTS posts_search_sync_post();
+DROP TABLE IF EXISTS posts_search;
@@ -0,0 +1,53 @@
+-- Аналог posts_fts из SQLite: одна строка на пост, tsvector собирается
+-- из заголовка (вес A), текста (B) и комментариев (C).
+-- Конфигурация 'simple' - без стемминга, одинаково для русского и английского.
+CREATE TABLE posts_search (
+	post_id INTEGER PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+	title TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL DEFAULT '',
+	comments TEXT NOT NULL DEFAULT '',
+	document tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', title), 'A') ||
+		setweight(to_tsvector('simple', content), 'B') ||
+		setweight(to_tsvector('simple', comments), 'C')
+	) STORED
+);
+
+CREATE INDEX idx_posts_search_document ON posts_search USING GIN (document);
+
+CREATE FUNCTION posts_search_sync_post() RETURNS trigger AS $$
+BEGIN
+	INSERT INTO posts_search (post_id, title, content) VALUES (NEW.id, NEW.title, NEW.content)
+	ON CONFLICT (post_id) DO UPDATE SET title = EXCLUDED.title, content = EXCLUDED.content;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER posts_search_post AFTER INSERT OR UPDATE OF title, content ON posts
+FOR EACH ROW EXECUTE FUNCTION posts_search_sync_post();
+
+CREATE FUNCTION posts_search_sync_comments() RETURNS trigger AS $$
+DECLARE
+	target INTEGER;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		target := OLD.post_id;
+	ELSE
+		target := NEW.post_id;
+	END IF;
+
+	UPDATE posts_search
+	SET comments = COALESCE((SELECT string_agg(content, ' ' ORDER BY id) FROM comments WHERE post_id = target), '')
+	WHERE post_id = target;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER posts_search_comments AFTER INSERT OR UPDATE OF content OR DELETE ON comments
+FOR EACH ROW EXECUTE FUNCTION posts_search_sync_comments();
+
+-- Индексируем уже существующие посты
+INSERT INTO posts_search (post_id, title, content, comments)
+SELECT p.id, p.title, p.content,
+       COALESCE((SELECT string_agg(c.content, ' ' ORDER BY c.id) FROM comments c WHERE c.post_id = p.id), '')
+FROM posts p;
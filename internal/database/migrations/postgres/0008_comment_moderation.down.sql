@@ -0,0 +1,28 @@
+CREATE OR REPLACE FUNCTION posts_search_sync_comments() RETURNS trigger AS $$
+DECLARE
+	target INTEGER;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		target := OLD.post_id;
+	ELSE
+		target := NEW.post_id;
+	END IF;
+
+	UPDATE posts_search
+	SET comments = COALESCE((SELECT string_agg(content, ' ' ORDER BY id) FROM comments WHERE post_id = target), '')
+	WHERE post_id = target;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_search_comments ON comments;
+CREATE TRIGGER posts_search_comments AFTER INSERT OR UPDATE OF content OR DELETE ON comments
+FOR EACH ROW EXECUTE FUNCTION posts_search_sync_comments();
+
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS settings;
+
+ALTER TABLE categories DROP COLUMN comment_moderation;
+
+DROP INDEX IF EXISTS idx_comments_status_created_at;
+ALTER TABLE comments DROP COLUMN status;
@@ -0,0 +1,49 @@
+-- Модерация комментариев: status - pending, approved, rejected или spam.
+-- Показываются, считаются и ищутся только одобренные (approved);
+-- уже опубликованные комментарии считаются одобренными.
+ALTER TABLE comments ADD COLUMN status TEXT NOT NULL DEFAULT 'approved';
+CREATE INDEX idx_comments_status_created_at ON comments(status, created_at);
+
+-- Режим премодерации комментариев в категории; NULL - глобальный режим из settings
+ALTER TABLE categories ADD COLUMN comment_moderation TEXT;
+
+-- Настройки блога, которые меняются из админки
+CREATE TABLE settings (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE TABLE notifications (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+	comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+	message TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	read_at TIMESTAMP
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, id);
+
+-- В полнотекстовый индекс попадают только одобренные комментарии
+CREATE OR REPLACE FUNCTION posts_search_sync_comments() RETURNS trigger AS $$
+DECLARE
+	target INTEGER;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		target := OLD.post_id;
+	ELSE
+		target := NEW.post_id;
+	END IF;
+
+	UPDATE posts_search
+	SET comments = COALESCE((SELECT string_agg(content, ' ' ORDER BY id) FROM comments
+	                         WHERE post_id = target AND status = 'approved'), '')
+	WHERE post_id = target;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER posts_search_comments ON comments;
+CREATE TRIGGER posts_search_comments AFTER INSERT OR UPDATE OF content, status OR DELETE ON comments
+FOR EACH ROW EXECUTE FUNCTION posts_search_sync_comments();
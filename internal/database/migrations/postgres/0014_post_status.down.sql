@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_status_published_at;
+
+ALTER TABLE posts DROP COLUMN published_at;
+ALTER TABLE posts DROP COLUMN status;
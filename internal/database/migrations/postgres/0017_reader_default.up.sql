@@ -0,0 +1,3 @@
+-- Пользователи, добавленные в обход приложения, по умолчанию получают роль
+-- reader, а не author; роли существующих пользователей не меняются.
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'reader';
@@ -0,0 +1,5 @@
+ALTER TABLE comments DROP COLUMN user_id;
+ALTER TABLE posts DROP COLUMN user_id;
+
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'author';
+
+-- Первый зарегистрированный пользователь становится администратором
+UPDATE users SET role = 'admin' WHERE id = (SELECT MIN(id) FROM users);
@@ -0,0 +1,20 @@
+ALTER TABLE posts ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+
+-- Каждая версия поста, включая текущую. posts хранит копию последней ревизии.
+CREATE TABLE post_revisions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL,
+	revision INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	category_id INTEGER NOT NULL,
+	editor_id INTEGER,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (post_id, revision),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+	FOREIGN KEY (editor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Существующие посты получают первую ревизию
+INSERT INTO post_revisions (post_id, revision, title, content, category_id, editor_id, created_at)
+SELECT id, 1, title, content, category_id, user_id, updated_at FROM posts;
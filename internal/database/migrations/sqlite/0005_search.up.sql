@@ -0,0 +1,45 @@
+-- Полнотекстовый индекс: одна строка на пост (rowid = posts.id),
+-- в колонке comments - тексты всех комментариев поста.
+-- Требует SQLite с FTS5 (сборка с -tags sqlite_fts5).
+CREATE VIRTUAL TABLE posts_fts USING fts5(
+	title,
+	content,
+	comments,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+	INSERT INTO posts_fts (rowid, title, content, comments) VALUES (NEW.id, NEW.title, NEW.content, '');
+END;
+
+CREATE TRIGGER posts_fts_update AFTER UPDATE OF title, content ON posts BEGIN
+	UPDATE posts_fts SET title = NEW.title, content = NEW.content WHERE rowid = NEW.id;
+END;
+
+CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+	DELETE FROM posts_fts WHERE rowid = OLD.id;
+END;
+
+CREATE TRIGGER comments_fts_insert AFTER INSERT ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments WHERE post_id = NEW.post_id), '')
+	WHERE rowid = NEW.post_id;
+END;
+
+CREATE TRIGGER comments_fts_update AFTER UPDATE OF content ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments WHERE post_id = NEW.post_id), '')
+	WHERE rowid = NEW.post_id;
+END;
+
+CREATE TRIGGER comments_fts_delete AFTER DELETE ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments WHERE post_id = OLD.post_id), '')
+	WHERE rowid = OLD.post_id;
+END;
+
+-- Индексируем уже существующие посты
+INSERT INTO posts_fts (rowid, title, content, comments)
+SELECT p.id, p.title, p.content,
+       COALESCE((SELECT group_concat(c.content, ' ') FROM comments c WHERE c.post_id = p.id), '')
+FROM posts p;
@@ -0,0 +1,5 @@
+-- Индексы под keyset пагинацию: лента постов и комментарии поста
+-- выбираются по убыванию (created_at, id)
+CREATE INDEX idx_posts_created_at_id ON posts(created_at, id);
+CREATE INDEX idx_posts_category_created_at_id ON posts(category_id, created_at, id);
+CREATE INDEX idx_comments_post_created_at_id ON comments(post_id, created_at, id);
@@ -0,0 +1,7 @@
+-- Ответы на комментарии: parent_id ссылается на родительский комментарий того же поста.
+-- Комментарий с ответами при удалении становится "надгробием": текст и автор
+-- стираются, deleted_at заполняется, а ветка ответов остаётся на месте.
+ALTER TABLE comments ADD COLUMN parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE;
+ALTER TABLE comments ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX idx_comments_parent_id ON comments(parent_id);
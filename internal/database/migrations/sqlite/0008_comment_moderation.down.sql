@@ -0,0 +1,29 @@
+DROP TRIGGER IF EXISTS comments_fts_insert;
+DROP TRIGGER IF EXISTS comments_fts_update;
+DROP TRIGGER IF EXISTS comments_fts_delete;
+
+CREATE TRIGGER comments_fts_insert AFTER INSERT ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments WHERE post_id = NEW.post_id), '')
+	WHERE rowid = NEW.post_id;
+END;
+
+CREATE TRIGGER comments_fts_update AFTER UPDATE OF content ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments WHERE post_id = NEW.post_id), '')
+	WHERE rowid = NEW.post_id;
+END;
+
+CREATE TRIGGER comments_fts_delete AFTER DELETE ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments WHERE post_id = OLD.post_id), '')
+	WHERE rowid = OLD.post_id;
+END;
+
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS settings;
+
+ALTER TABLE categories DROP COLUMN comment_moderation;
+
+DROP INDEX IF EXISTS idx_comments_status_created_at;
+ALTER TABLE comments DROP COLUMN status;
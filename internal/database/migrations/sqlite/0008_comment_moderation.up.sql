@@ -0,0 +1,52 @@
+-- Модерация комментариев: status - pending, approved, rejected или spam.
+-- Показываются, считаются и ищутся только одобренные (approved);
+-- уже опубликованные комментарии считаются одобренными.
+ALTER TABLE comments ADD COLUMN status TEXT NOT NULL DEFAULT 'approved';
+CREATE INDEX idx_comments_status_created_at ON comments(status, created_at);
+
+-- Режим премодерации комментариев в категории; NULL - глобальный режим из settings
+ALTER TABLE categories ADD COLUMN comment_moderation TEXT;
+
+-- Настройки блога, которые меняются из админки
+CREATE TABLE settings (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE TABLE notifications (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+	comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+	message TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	read_at DATETIME
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, id);
+
+-- В полнотекстовый индекс попадают только одобренные комментарии
+DROP TRIGGER comments_fts_insert;
+DROP TRIGGER comments_fts_update;
+DROP TRIGGER comments_fts_delete;
+
+CREATE TRIGGER comments_fts_insert AFTER INSERT ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments
+	                         WHERE post_id = NEW.post_id AND status = 'approved'), '')
+	WHERE rowid = NEW.post_id;
+END;
+
+CREATE TRIGGER comments_fts_update AFTER UPDATE OF content, status ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments
+	                         WHERE post_id = NEW.post_id AND status = 'approved'), '')
+	WHERE rowid = NEW.post_id;
+END;
+
+CREATE TRIGGER comments_fts_delete AFTER DELETE ON comments BEGIN
+	UPDATE posts_fts
+	SET comments = COALESCE((SELECT group_concat(content, ' ') FROM comments
+	                         WHERE post_id = OLD.post_id AND status = 'approved'), '')
+	WHERE rowid = OLD.post_id;
+END;
@@ -0,0 +1,7 @@
+DROP TABLE spam_corpus;
+DROP TABLE spam_tokens;
+
+ALTER TABLE comments DROP COLUMN spam_label;
+ALTER TABLE comments DROP COLUMN ip;
+ALTER TABLE comments DROP COLUMN spam_reasons;
+ALTER TABLE comments DROP COLUMN spam_score;
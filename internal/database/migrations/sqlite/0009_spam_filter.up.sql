@@ -0,0 +1,20 @@
+-- Спам фильтр: у комментария хранятся итоговая оценка проверок и их причины,
+-- адрес отправителя (для блокировки по IP) и метка, которой модератор обучил
+-- байесовский классификатор (spam или ham), чтобы при смене решения её отменить.
+ALTER TABLE comments ADD COLUMN spam_score REAL NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN spam_reasons TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN ip TEXT;
+ALTER TABLE comments ADD COLUMN spam_label TEXT;
+
+-- Статистика байесовского классификатора: сколько комментариев каждой метки
+-- содержали слово, и сколько всего комментариев каждой метки было в обучении
+CREATE TABLE spam_tokens (
+	token TEXT PRIMARY KEY,
+	spam_count INTEGER NOT NULL DEFAULT 0,
+	ham_count INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE spam_corpus (
+	label TEXT PRIMARY KEY,
+	documents INTEGER NOT NULL DEFAULT 0
+);
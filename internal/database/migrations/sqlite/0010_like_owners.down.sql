@@ -0,0 +1,7 @@
+-- Лайки, удалённые при дедупликации в up, не восстанавливаются: у поста
+-- остаётся по одному старому лайку и лайки с владельцами
+DROP INDEX IF EXISTS idx_likes_post_visitor;
+DROP INDEX IF EXISTS idx_likes_post_user;
+
+ALTER TABLE likes DROP COLUMN visitor_id;
+ALTER TABLE likes DROP COLUMN user_id;
@@ -0,0 +1,16 @@
+-- Лайк принадлежит пользователю (user_id) или анонимному посетителю с подписанной
+-- cookie (visitor_id); от одного владельца у поста может быть только один лайк.
+
+-- Старые лайки ничьи, и один посетитель мог накликать их сколько угодно.
+-- От них остаётся не больше одного лайка на пост (самый ранний), остальные
+-- удаляются без возможности восстановления
+DELETE FROM likes WHERE id NOT IN (SELECT MIN(id) FROM likes GROUP BY post_id);
+
+ALTER TABLE likes ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE likes ADD COLUMN visitor_id TEXT;
+
+-- Оставшийся старый лайк ничей: оба столбца NULL. Уникальные индексы не
+-- сравнивают NULL, поэтому он учитывается в счётчике, но снять его нельзя.
+
+CREATE UNIQUE INDEX idx_likes_post_user ON likes(post_id, user_id);
+CREATE UNIQUE INDEX idx_likes_post_visitor ON likes(post_id, visitor_id);
@@ -0,0 +1,29 @@
+-- Реакции на посты (post_id) и комментарии (comment_id). Как и лайки, реакция
+-- принадлежит пользователю или анонимному посетителю; одну и ту же реакцию
+-- владелец ставит на пост или комментарий только один раз.
+CREATE TABLE reactions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+	comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+	emoji TEXT NOT NULL,
+	user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+	visitor_id TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_reactions_post_user ON reactions(post_id, emoji, user_id);
+CREATE UNIQUE INDEX idx_reactions_post_visitor ON reactions(post_id, emoji, visitor_id);
+CREATE UNIQUE INDEX idx_reactions_comment_user ON reactions(comment_id, emoji, user_id);
+CREATE UNIQUE INDEX idx_reactions_comment_visitor ON reactions(comment_id, emoji, visitor_id);
+
+-- Готовые счётчики реакций: обновляются в одной транзакции с reactions,
+-- чтобы лента не считала реакции JOIN'ами на каждый пост
+CREATE TABLE reaction_counts (
+	post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+	comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+	emoji TEXT NOT NULL,
+	count INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX idx_reaction_counts_post ON reaction_counts(post_id, emoji);
+CREATE UNIQUE INDEX idx_reaction_counts_comment ON reaction_counts(comment_id, emoji);
@@ -0,0 +1,5 @@
+-- Вложенные категории: parent_id ссылается на родительскую категорию, NULL - верхний уровень.
+-- Пост категории показывается и на страницах всех её предков.
+ALTER TABLE categories ADD COLUMN parent_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
@@ -0,0 +1,9 @@
+-- Черновики и отложенная публикация: status - draft, scheduled, published или archived.
+-- published_at - время публикации, у запланированных - назначенное; NULL у черновиков.
+-- В ленте, поиске и счётчиках участвуют только опубликованные посты;
+-- уже существующие посты считаются опубликованными в момент создания.
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN published_at DATETIME;
+UPDATE posts SET published_at = created_at;
+
+CREATE INDEX idx_posts_status_published_at ON posts(status, published_at);
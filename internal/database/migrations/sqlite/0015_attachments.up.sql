@@ -0,0 +1,18 @@
+-- Вложения постов. Сами файлы лежат в хранилище (каталог или S3) под
+-- storage_key, у изображений есть превью thumb_key. Строки удаляются вместе
+-- с постом, файлы из хранилища удаляет PostService.DeletePost.
+CREATE TABLE attachments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+	user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	storage_key TEXT NOT NULL UNIQUE,
+	thumb_key TEXT NOT NULL DEFAULT '',
+	width INTEGER NOT NULL DEFAULT 0,
+	height INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_attachments_post_id ON attachments(post_id);
@@ -0,0 +1,9 @@
+-- Когда автору поста сообщили об одобренном комментарии: повторное одобрение
+-- (например, после отклонения) не присылает уведомление ещё раз.
+-- Уже одобренные комментарии и те, о которых были уведомления, считаются
+-- разосланными.
+ALTER TABLE comments ADD COLUMN notified_at DATETIME;
+
+UPDATE comments SET notified_at = CURRENT_TIMESTAMP
+WHERE status = 'approved'
+   OR id IN (SELECT comment_id FROM notifications WHERE comment_id IS NOT NULL);
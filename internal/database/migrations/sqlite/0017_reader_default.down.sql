@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN role_new TEXT NOT NULL DEFAULT 'author';
+UPDATE users SET role_new = role;
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users RENAME COLUMN role_new TO role;
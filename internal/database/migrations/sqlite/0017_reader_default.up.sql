@@ -0,0 +1,8 @@
+-- Пользователи, добавленные в обход приложения, по умолчанию получают роль
+-- reader, а не author; роли существующих пользователей не меняются.
+-- SQLite не умеет менять DEFAULT у столбца, поэтому role пересоздаётся.
+-- Столбец последний в таблице, так что порядок столбцов сохраняется.
+ALTER TABLE users ADD COLUMN role_new TEXT NOT NULL DEFAULT 'reader';
+UPDATE users SET role_new = role;
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users RENAME COLUMN role_new TO role;
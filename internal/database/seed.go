@@ -1,12 +1,11 @@
 package database
 
 import (
-	"database/sql"
 	"log"
 )
 
 // SeedDatabase заполняет базу данных начальными данными
-func SeedDatabase(db *sql.DB) error {
+func SeedDatabase(db *DB) error {
 	// Проверяем, есть ли уже категории
 	var count int
 	err := db.QueryRow("SELECT COUNT(*) FROM categories").Scan(&count)
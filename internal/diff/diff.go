@@ -0,0 +1,122 @@
+// Package diff строит построчное сравнение двух текстов для просмотра ревизий
+package diff
+
+import (
+	"errors"
+	"strings"
+)
+
+// Op - тип строки в результате сравнения
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Ограничения на размер сравниваемых текстов: таблица НОП занимает память
+// пропорционально произведению числа различающихся строк
+const (
+	// MaxBytes - наибольший суммарный размер двух текстов
+	MaxBytes = 1 << 20
+	// MaxLines - наибольшее число различающихся строк в каждом тексте
+	// (без общих начала и конца)
+	MaxLines = 2000
+)
+
+// ErrTooLarge - тексты слишком велики для построчного сравнения
+var ErrTooLarge = errors.New("тексты слишком велики для сравнения")
+
+// Line - строка результата сравнения
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines сравнивает тексты построчно по наибольшей общей подпоследовательности.
+// Таблица квадратична по памяти, поэтому размер текстов ограничен MaxBytes
+// и MaxLines; на больших текстах возвращается ErrTooLarge.
+func Lines(a, b string) ([]Line, error) {
+	if len(a)+len(b) > MaxBytes {
+		return nil, ErrTooLarge
+	}
+	x := splitLines(a)
+	y := splitLines(b)
+
+	// Общие начало и конец не участвуют в таблице
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix &&
+		x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	if len(x)-prefix-suffix > MaxLines || len(y)-prefix-suffix > MaxLines {
+		return nil, ErrTooLarge
+	}
+
+	result := make([]Line, 0, len(x)+len(y))
+	for _, text := range x[:prefix] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+	result = append(result, lcs(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, text := range x[len(x)-suffix:] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+
+	return result, nil
+}
+
+func lcs(x, y []string) []Line {
+	n, m := len(x), len(y)
+
+	// table[i*w+j] - длина НОП для x[i:] и y[j:]; строки не длиннее MaxLines,
+	// так что int32 хватает, а таблица занимает вдвое меньше памяти
+	w := m + 1
+	table := make([]int32, (n+1)*w)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				table[i*w+j] = table[(i+1)*w+j+1] + 1
+			} else {
+				table[i*w+j] = max(table[(i+1)*w+j], table[i*w+j+1])
+			}
+		}
+	}
+
+	result := make([]Line, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case x[i] == y[j]:
+			result = append(result, Line{Op: Equal, Text: x[i]})
+			i++
+			j++
+		case table[(i+1)*w+j] >= table[i*w+j+1]:
+			result = append(result, Line{Op: Delete, Text: x[i]})
+			i++
+		default:
+			result = append(result, Line{Op: Insert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, Line{Op: Delete, Text: x[i]})
+	}
+	for ; j < m; j++ {
+		result = append(result, Line{Op: Insert, Text: y[j]})
+	}
+
+	return result
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,68 @@
+package diff
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	eq := func(text string) Line { return Line{Op: Equal, Text: text} }
+	ins := func(text string) Line { return Line{Op: Insert, Text: text} }
+	del := func(text string) Line { return Line{Op: Delete, Text: text} }
+
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{"оба пустые", "", "", []Line{}},
+		{"одинаковые", "a\nb\n", "a\nb", []Line{eq("a"), eq("b")}},
+		{"вставка в середину", "a\nc", "a\nb\nc", []Line{eq("a"), ins("b"), eq("c")}},
+		{"вставка в пустой", "", "a\nb", []Line{ins("a"), ins("b")}},
+		{"удаление", "a\nb\nc", "a\nc", []Line{eq("a"), del("b"), eq("c")}},
+		{"удаление всего", "a\nb", "", []Line{del("a"), del("b")}},
+		{"замена", "a\nb\nc", "a\nx\nc", []Line{eq("a"), del("b"), ins("x"), eq("c")}},
+		{"замена с общими строками внутри", "a\nb\nc\nd", "x\nb\nd\ny",
+			[]Line{del("a"), ins("x"), eq("b"), del("c"), eq("d"), ins("y")}},
+		{"переводы строк CRLF", "a\r\nb\r\n", "a\nb\n", []Line{eq("a"), eq("b")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lines(tt.a, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines(%q, %q)\n got %v\nwant %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesTooLarge(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteString(prefix)
+			b.WriteString(strings.Repeat("x", i%7))
+			b.WriteByte('\n')
+		}
+		return b.String()
+	}
+
+	// Общие начало и конец не считаются: большой текст с маленькой правкой сравнивается
+	common := lines("общая ", MaxLines*2)
+	if _, err := Lines(common+"a\n"+common, common+"b\n"+common); err != nil {
+		t.Errorf("маленькая правка большого текста: %v", err)
+	}
+
+	if _, err := Lines(lines("a", MaxLines+1), lines("b", 1)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("слишком много строк: %v, want ErrTooLarge", err)
+	}
+	big := strings.Repeat("x", MaxBytes)
+	if _, err := Lines(big, "y"); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("слишком большой текст: %v, want ErrTooLarge", err)
+	}
+}
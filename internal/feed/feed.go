@@ -0,0 +1,73 @@
+// Package feed строит ленты для читалок в форматах RSS 2.0, Atom 1.0 и JSON Feed 1.1
+// из одного описания ленты
+package feed
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Feed - лента: все адреса абсолютные
+type Feed struct {
+	Title       string
+	Description string
+	// Language - язык ленты, например "ru"
+	Language string
+	// Link - страница сайта, которую повторяет лента
+	Link string
+	// Self - адрес самой ленты; в Atom он же служит её идентификатором
+	Self string
+	// Updated - время последнего изменения записей ленты
+	Updated time.Time
+	Items   []Item
+}
+
+// Item - запись ленты
+type Item struct {
+	// ID - постоянный идентификатор записи: читалки по нему отличают новые
+	// записи от уже прочитанных, поэтому он не должен меняться вместе с Link
+	ID     string
+	Link   string
+	Title  string
+	Author string
+	// Summary - текстовый отрывок записи
+	Summary string
+	// ContentHTML - полный текст в HTML; пустой, если лента отдаёт только отрывки
+	ContentHTML string
+	Categories  []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// Типы содержимого лент
+const (
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+	AtomContentType = "application/atom+xml; charset=utf-8"
+	JSONContentType = "application/feed+json; charset=utf-8"
+)
+
+var (
+	// blockTag - теги, которые разделяют слова; остальные теги просто убираются
+	blockTag = regexp.MustCompile(`(?i)</?(p|div|br|hr|h[1-6]|ul|ol|li|pre|blockquote|table|tr|td|th)\b[^>]*>`)
+	htmlTag  = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Excerpt превращает очищенный HTML в текст и обрезает его до limit символов
+// по границе слова, добавляя многоточие
+func Excerpt(source string, limit int) string {
+	text := htmlTag.ReplaceAllString(blockTag.ReplaceAllString(source, " "), "")
+	text = html.UnescapeString(text)
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	cut := []rune(text)[:limit]
+	if i := strings.LastIndexByte(string(cut), ' '); i > 0 {
+		return string(cut)[:i] + "…"
+	}
+	return string(cut) + "…"
+}
@@ -0,0 +1,28 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name, html string
+		limit      int
+		want       string
+	}{
+		{"теги убираются", "<p>Привет, <strong>мир</strong>!</p>", 100, "Привет, мир!"},
+		{"блоки разделяют слова", "<h1>Заголовок</h1><p>текст</p><ul><li>раз</li><li>два</li></ul>", 100, "Заголовок текст раз два"},
+		{"сущности", "<p>a &lt; b &amp;&amp; c</p>", 100, "a < b && c"},
+		{"пробелы схлопываются", "<p>  много\n\n   пробелов  </p>", 100, "много пробелов"},
+		{"по границе слова", "<p>один два три четыре</p>", 12, "один два…"},
+		{"ровно limit", "<p>один два</p>", 8, "один два"},
+		{"одно длинное слово", "<p>" + strings.Repeat("я", 20) + "</p>", 5, "яяяяя…"},
+		{"пусто", "", 10, ""},
+	}
+	for _, tt := range tests {
+		if got := Excerpt(tt.html, tt.limit); got != tt.want {
+			t.Errorf("%s: Excerpt = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s.usynin/testing/go-server/internal/middleware"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+type AdminHandler struct {
+	userService       *service.UserService
+	moderationService *service.ModerationService
+	categoryService   *service.CategoryService
+	tagService        *service.TagService
+	templates         *template.Template
+}
+
+func NewAdminHandler(
+	userService *service.UserService,
+	moderationService *service.ModerationService,
+	categoryService *service.CategoryService,
+	tagService *service.TagService,
+	templates *template.Template,
+) *AdminHandler {
+	return &AdminHandler{
+		userService:       userService,
+		moderationService: moderationService,
+		categoryService:   categoryService,
+		tagService:        tagService,
+		templates:         templates,
+	}
+}
+
+func (h *AdminHandler) Users(w http.ResponseWriter, r *http.Request) {
+	user := middleware.CurrentUser(r)
+	users, err := h.userService.ListUsers(user)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	data := struct {
+		User  *models.User
+		Users []models.User
+		Roles []models.Role
+	}{
+		User:  user,
+		Users: users,
+		Roles: models.Roles,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = h.templates.ExecuteTemplate(w, "admin_users.html", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	updated, err := h.userService.SetRole(middleware.CurrentUser(r), id, models.Role(r.FormValue("role")))
+	if errors.Is(err, service.ErrInvalidRole) {
+		renderError(w, r, h.templates, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte("✓ " + template.HTMLEscapeString(string(updated.Role))))
+}
+
+// Categories - управление категориями: создание, переименование и удаление
+func (h *AdminHandler) Categories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.categoryService.ListCategories(middleware.CurrentUser(r))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct{ Categories []models.Category }{categories}
+	if err := h.templates.ExecuteTemplate(w, "admin_categories.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// CreateCategory создаёт категорию (поля name, slug и parent_id) и отдаёт обновлённую таблицу
+func (h *AdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	parentID, ok := h.formParentID(w, r)
+	if !ok {
+		return
+	}
+
+	user := middleware.CurrentUser(r)
+	_, err := h.categoryService.CreateCategory(user, r.FormValue("name"), r.FormValue("slug"), parentID)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderCategoryTable(w, r)
+}
+
+// UpdateCategory переименовывает категорию и меняет её адрес (поля name и slug)
+func (h *AdminHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID категории")
+		return
+	}
+
+	_, err = h.categoryService.UpdateCategory(middleware.CurrentUser(r), id, r.FormValue("name"), r.FormValue("slug"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderCategoryTable(w, r)
+}
+
+// MoveCategory переносит категорию внутрь parent_id; пустое значение - на верхний уровень
+func (h *AdminHandler) MoveCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID категории")
+		return
+	}
+	parentID, ok := h.formParentID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.categoryService.MoveCategory(middleware.CurrentUser(r), id, parentID); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderCategoryTable(w, r)
+}
+
+// formParentID читает необязательное поле parent_id; при ошибке пишет ответ и возвращает false
+func (h *AdminHandler) formParentID(w http.ResponseWriter, r *http.Request) (*int, bool) {
+	value := r.FormValue("parent_id")
+	if value == "" {
+		return nil, true
+	}
+	parentID, err := strconv.Atoi(value)
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID родительской категории")
+		return nil, false
+	}
+	return &parentID, true
+}
+
+// DeleteCategory удаляет категорию, перенося её посты в категорию reassign_to
+func (h *AdminHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID категории")
+		return
+	}
+	reassignTo, err := strconv.Atoi(r.FormValue("reassign_to"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Выберите категорию для постов")
+		return
+	}
+
+	if _, err := h.categoryService.DeleteCategory(middleware.CurrentUser(r), id, reassignTo); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderCategoryTable(w, r)
+}
+
+func (h *AdminHandler) renderCategoryTable(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.categoryService.ListCategories(middleware.CurrentUser(r))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct{ Categories []models.Category }{categories}
+	if err := h.templates.ExecuteTemplate(w, "category_table.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Tags - управление тегами: переименование, объединение и удаление
+func (h *AdminHandler) Tags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.tagService.ListTags(middleware.CurrentUser(r))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct{ Tags []models.Tag }{tags}
+	if err := h.templates.ExecuteTemplate(w, "admin_tags.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RenameTag меняет название и адрес тега (поля name и slug)
+func (h *AdminHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID тега")
+		return
+	}
+
+	_, err = h.tagService.RenameTag(middleware.CurrentUser(r), id, r.FormValue("name"), r.FormValue("slug"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderTagTable(w, r)
+}
+
+// MergeTags переносит посты тега в тег into и удаляет тег
+func (h *AdminHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID тега")
+		return
+	}
+	into, err := strconv.Atoi(r.FormValue("into"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Выберите тег для объединения")
+		return
+	}
+
+	if _, err := h.tagService.MergeTags(middleware.CurrentUser(r), id, into); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderTagTable(w, r)
+}
+
+// DeleteTag удаляет тег и снимает его с постов
+func (h *AdminHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID тега")
+		return
+	}
+
+	if err := h.tagService.DeleteTag(middleware.CurrentUser(r), id); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderTagTable(w, r)
+}
+
+func (h *AdminHandler) renderTagTable(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.tagService.ListTags(middleware.CurrentUser(r))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct{ Tags []models.Tag }{tags}
+	if err := h.templates.ExecuteTemplate(w, "tag_table.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// moderationPerPage - комментариев на странице очереди модерации
+const moderationPerPage = 50
+
+// Comments - страница модерации: очередь комментариев и, для администратора, режимы премодерации
+func (h *AdminHandler) Comments(w http.ResponseWriter, r *http.Request) {
+	user := middleware.CurrentUser(r)
+	queue, err := h.moderationQueue(r, r.URL.Query().Get("status"), r.URL.Query().Get("page"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	// Настройки видит только тот, кому можно их менять
+	var settings *service.ModerationSettings
+	if service.Can(user, service.PermManageModeration) {
+		if settings, err = h.moderationService.Settings(user); err != nil {
+			handleServiceError(w, r, h.templates, err)
+			return
+		}
+	}
+
+	data := struct {
+		User     *models.User
+		Queue    *service.ModerationQueue
+		Settings *service.ModerationSettings
+		Modes    []models.ModerationMode
+	}{
+		User:     user,
+		Queue:    queue,
+		Settings: settings,
+		Modes:    models.ModerationModes,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "admin_comments.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ModerateComments применяет действие к отмеченным комментариям и отдаёт
+// обновлённую очередь. Поля формы: ids (несколько), action, status и page очереди.
+func (h *AdminHandler) ModerateComments(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверная форма")
+		return
+	}
+
+	ids := make([]int, 0, len(r.PostForm["ids"]))
+	for _, raw := range r.PostForm["ids"] {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID комментария")
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	action := models.CommentStatus(r.PostFormValue("action"))
+	if _, err := h.moderationService.Moderate(middleware.CurrentUser(r), ids, action); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	queue, err := h.moderationQueue(r, r.PostFormValue("status"), r.PostFormValue("page"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "moderation_queue.html", queue); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// UpdateModerationSettings сохраняет режимы премодерации. Поля формы: global и
+// category_{id} для каждой категории; пустое значение - режим как у блога.
+func (h *AdminHandler) UpdateModerationSettings(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверная форма")
+		return
+	}
+
+	categories := make(map[int]*models.ModerationMode)
+	for field := range r.PostForm {
+		idStr, ok := strings.CutPrefix(field, "category_")
+		if !ok {
+			continue
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			renderError(w, r, h.templates, http.StatusBadRequest, "Неверный ID категории")
+			return
+		}
+		categories[id] = nil
+		if value := r.PostFormValue(field); value != "" {
+			mode := models.ModerationMode(value)
+			categories[id] = &mode
+		}
+	}
+
+	global := models.ModerationMode(r.PostFormValue("global"))
+	err := h.moderationService.UpdateSettings(middleware.CurrentUser(r), global, categories)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte("✓ Сохранено"))
+}
+
+// UpdateBlocklist сохраняет списки блокировки спам фильтра: поля blocked_words
+// и blocked_ips, по слову или адресу (подсети) на строку
+func (h *AdminHandler) UpdateBlocklist(w http.ResponseWriter, r *http.Request) {
+	words, ips := r.FormValue("blocked_words"), r.FormValue("blocked_ips")
+	if err := h.moderationService.UpdateBlocklist(middleware.CurrentUser(r), words, ips); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte("✓ Сохранено"))
+}
+
+func (h *AdminHandler) moderationQueue(r *http.Request, status, page string) (*service.ModerationQueue, error) {
+	if status == "" {
+		status = string(models.CommentPending)
+	}
+	pageNum, _ := strconv.Atoi(page)
+	return h.moderationService.Queue(middleware.CurrentUser(r), models.CommentStatus(status), pageNum, moderationPerPage)
+}
@@ -0,0 +1,980 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s.usynin/testing/go-server/internal/middleware"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/openapi"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/service"
+	"github.com/s.usynin/testing/go-server/internal/spam"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// APIHandler - JSON API /api/v1 поверх тех же сервисов, что и HTML handlers
+type APIHandler struct {
+	postService       *service.PostService
+	authService       *service.AuthService
+	reactionService   *service.ReactionService
+	categoryService   *service.CategoryService
+	tagService        *service.TagService
+	attachmentService *service.AttachmentService
+	spec              *openapi.Document
+}
+
+func NewAPIHandler(
+	postService *service.PostService,
+	authService *service.AuthService,
+	reactionService *service.ReactionService,
+	categoryService *service.CategoryService,
+	tagService *service.TagService,
+	attachmentService *service.AttachmentService,
+	spec *openapi.Document,
+) *APIHandler {
+	return &APIHandler{
+		postService:       postService,
+		authService:       authService,
+		reactionService:   reactionService,
+		categoryService:   categoryService,
+		tagService:        tagService,
+		attachmentService: attachmentService,
+		spec:              spec,
+	}
+}
+
+// Routes возвращает роутер API для монтирования в /api/v1.
+// Запросы проверяются по спецификации до вызова handler'ов.
+func (h *APIHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(openapi.Validator(h.spec, maxAPIBodySize, writeAPIRequestError))
+
+	r.Post("/auth/login", h.Login)
+	r.Post("/auth/logout", h.Logout)
+	r.Get("/me", h.Me)
+
+	r.Get("/search", h.Search)
+
+	r.Get("/posts", h.ListPosts)
+	r.Post("/posts", h.CreatePost)
+	r.Get("/posts/{id}", h.GetPost)
+	r.Put("/posts/{id}", h.UpdatePost)
+	r.Delete("/posts/{id}", h.DeletePost)
+
+	r.Get("/posts/{id}/attachments", h.ListAttachments)
+	r.Post("/posts/{id}/attachments", h.CreateAttachments)
+	r.Delete("/attachments/{id}", h.DeleteAttachment)
+
+	r.Get("/posts/{id}/comments", h.ListComments)
+	r.Post("/posts/{id}/comments", h.CreateComment)
+	r.Get("/comments/{id}", h.GetComment)
+	r.Delete("/comments/{id}", h.DeleteComment)
+
+	// Лайки гостей привязываются к cookie посетителя, выданной при открытии
+	// страницы блога, как в браузере
+	r.Get("/posts/{id}/likes", h.GetLikes)
+	r.With(requireVisitor).Post("/posts/{id}/likes", h.AddLike)
+	r.With(requireVisitor).Delete("/posts/{id}/likes", h.RemoveLike)
+
+	r.Get("/posts/{id}/reactions", h.reactionHandler(models.ReactionOnPost, h.ListReactions))
+	r.With(requireVisitor).Post("/posts/{id}/reactions", h.reactionHandler(models.ReactionOnPost, h.AddReaction))
+	r.With(requireVisitor).Delete("/posts/{id}/reactions", h.reactionHandler(models.ReactionOnPost, h.RemoveReaction))
+	r.Get("/comments/{id}/reactions", h.reactionHandler(models.ReactionOnComment, h.ListReactions))
+	r.With(requireVisitor).Post("/comments/{id}/reactions", h.reactionHandler(models.ReactionOnComment, h.AddReaction))
+	r.With(requireVisitor).Delete("/comments/{id}/reactions", h.reactionHandler(models.ReactionOnComment, h.RemoveReaction))
+
+	r.Get("/categories", h.ListCategories)
+	r.Post("/categories", h.CreateCategory)
+	r.Get("/categories/{id}", h.GetCategory)
+	r.Put("/categories/{id}", h.UpdateCategory)
+	r.Put("/categories/{id}/parent", h.MoveCategory)
+	r.Delete("/categories/{id}", h.DeleteCategory)
+
+	r.Get("/tags", h.ListTags)
+	r.Get("/tags/{id}", h.GetTag)
+	r.Put("/tags/{id}", h.RenameTag)
+	r.Post("/tags/{id}/merge", h.MergeTags)
+	r.Delete("/tags/{id}", h.DeleteTag)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "маршрут не найден")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", "метод не поддерживается")
+	})
+
+	return r
+}
+
+// Spec отдаёт спецификацию OpenAPI
+func (h *APIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.spec)
+}
+
+// Тела запросов
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type postRequest struct {
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	CategoryID int    `json:"category_id"`
+	// Tags - названия тегов; в PUT без поля теги не меняются, [] снимает все
+	Tags []string `json:"tags,omitempty"`
+	// Status и PublishedAt - публикация поста; в PUT без них она не меняется
+	Status      models.PostStatus `json:"status,omitempty"`
+	PublishedAt *time.Time        `json:"published_at,omitempty"`
+	// Revision - ревизия, на которой основана правка (только для PUT)
+	Revision int `json:"revision,omitempty"`
+}
+
+type categoryRequest struct {
+	Name string `json:"name"`
+	// Slug - адрес для /category/{slug}; пустой строится из названия
+	Slug string `json:"slug,omitempty"`
+	// ParentID - родительская категория (только при создании)
+	ParentID *int `json:"parent_id,omitempty"`
+}
+
+type categoryParentRequest struct {
+	// ParentID - новая родительская категория; null - верхний уровень
+	ParentID *int `json:"parent_id"`
+}
+
+type tagRequest struct {
+	Name string `json:"name"`
+	// Slug - адрес для /tag/{slug}; пустой строится из названия
+	Slug string `json:"slug,omitempty"`
+}
+
+type tagMergeRequest struct {
+	// Into - тег, который остаётся вместо объединяемого
+	Into int `json:"into"`
+}
+
+type reactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+type commentRequest struct {
+	Author   string `json:"author,omitempty"`
+	Content  string `json:"content"`
+	ParentID *int   `json:"parent_id,omitempty"`
+}
+
+// Тела ответов
+
+type loginResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      *models.User `json:"user"`
+}
+
+type likesResponse struct {
+	PostID     int  `json:"post_id"`
+	LikesCount int  `json:"likes_count"`
+	Liked      bool `json:"liked"`
+}
+
+type reactionsResponse struct {
+	Target    models.ReactionTarget  `json:"target"`
+	ID        int                    `json:"id"`
+	Reactions []models.ReactionCount `json:"reactions"`
+}
+
+// Аккаунт
+
+// Login выдаёт токен сессии для заголовка Authorization: Bearer <token>
+func (h *APIHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, token, err := h.authService.Login(req.Username, req.Password)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, loginResponse{
+		Token:     token,
+		ExpiresAt: time.Now().Add(service.SessionTTL).UTC(),
+		User:      user,
+	})
+}
+
+func (h *APIHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.Logout(middleware.SessionToken(r)); err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *APIHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user := middleware.CurrentUser(r)
+	if user == nil {
+		writeAPIServiceError(w, service.ErrUnauthenticated)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+// Посты
+
+// ListPosts: GET /posts?per_page=20&category_id=2&tag_id=3&user_id=5&cursor=...
+// Следующая страница запрашивается с cursor из meta.next_cursor;
+// page=N (OFFSET) оставлен для совместимости и игнорируется вместе с cursor.
+// status=draft|scheduled|archived - неопубликованные посты автора запроса
+// (редактору - всех авторов).
+func (h *APIHandler) ListPosts(w http.ResponseWriter, r *http.Request) {
+	filter, page, ok := postFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+	filter.Status = models.PostStatus(r.URL.Query().Get("status"))
+	cursor, ok := queryCursor(w, r)
+	if !ok {
+		return
+	}
+	if cursor != nil {
+		filter.Before, filter.Offset, page = cursor, 0, 0
+	}
+
+	result, err := h.postService.ListPostsByStatus(middleware.CurrentUser(r), filter)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	posts := result.Posts
+	if posts == nil {
+		posts = []models.Post{}
+	}
+	if !h.loadViewerState(w, r, postPointers(posts)...) {
+		return
+	}
+
+	meta := listMeta{Total: result.Total, PerPage: filter.Limit, NextCursor: cursorString(result.Next)}
+	if page > 0 {
+		meta = pageMeta(result.Total, page, filter.Limit)
+		meta.NextCursor = cursorString(result.Next)
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Data: posts, Meta: meta})
+}
+
+// Search: GET /search?q=слова&category_id=2&tag_id=3&page=1&per_page=20.
+// Результаты отсортированы по релевантности, snippet содержит выделение <mark>.
+func (h *APIHandler) Search(w http.ResponseWriter, r *http.Request) {
+	filter, page, ok := postFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	results, total, err := h.postService.Search(r.URL.Query().Get("q"), filter)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	if results == nil {
+		results = []models.SearchResult{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Data: results, Meta: pageMeta(total, page, filter.Limit)})
+}
+
+// postFilterFromQuery читает page, per_page, category_id, tag_id и user_id
+func postFilterFromQuery(w http.ResponseWriter, r *http.Request) (repository.PostFilter, int, bool) {
+	page, err := queryInt(r, "page", 1)
+	if err == nil && page < 1 {
+		page = 1
+	}
+	perPage, err2 := queryInt(r, "per_page", defaultPerPage)
+	categoryID, err3 := queryInt(r, "category_id", 0)
+	tagID, err4 := queryInt(r, "tag_id", 0)
+	userID, err5 := queryInt(r, "user_id", 0)
+	for _, e := range []error{err, err2, err3, err4, err5} {
+		if e != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_query", e.Error())
+			return repository.PostFilter{}, 0, false
+		}
+	}
+	if perPage < 1 || perPage > maxPerPage {
+		perPage = defaultPerPage
+	}
+
+	return repository.PostFilter{
+		CategoryID: categoryID,
+		TagID:      tagID,
+		UserID:     userID,
+		Limit:      perPage,
+		Offset:     (page - 1) * perPage,
+	}, page, true
+}
+
+// queryCursor читает параметр cursor; без него возвращается nil - начало списка
+func queryCursor(w http.ResponseWriter, r *http.Request) (*repository.Cursor, bool) {
+	cursor, err := repository.ParseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_query", "параметр cursor: "+err.Error())
+		return nil, false
+	}
+	return cursor, true
+}
+
+func cursorString(c *repository.Cursor) string {
+	if c == nil {
+		return ""
+	}
+	return c.String()
+}
+
+func pageMeta(total, page, perPage int) listMeta {
+	return listMeta{
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+}
+
+func (h *APIHandler) GetPost(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	post, err := h.postService.GetPostByID(middleware.CurrentUser(r), id)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	if !h.loadViewerState(w, r, post) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, post)
+}
+
+func (h *APIHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
+	var req postRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	pub := service.Publication{Status: req.Status, At: req.PublishedAt}
+	post, err := h.postService.CreatePost(middleware.CurrentUser(r), req.Title, req.Content, req.CategoryID, req.Tags, pub, nil)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/posts/"+strconv.Itoa(post.ID))
+	writeJSON(w, http.StatusCreated, post)
+}
+
+func (h *APIHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req postRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Revision < 1 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]apiError{"error": {
+			Code:    "validation_failed",
+			Message: "укажите ревизию, на которой основана правка",
+			Field:   "revision",
+		}})
+		return
+	}
+
+	var pub *service.Publication
+	if req.Status != "" || req.PublishedAt != nil {
+		pub = &service.Publication{Status: req.Status, At: req.PublishedAt}
+	}
+	post, err := h.postService.UpdatePost(middleware.CurrentUser(r), id, req.Revision, req.Title, req.Content, req.CategoryID, req.Tags, pub)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, post)
+}
+
+func (h *APIHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.postService.DeletePost(middleware.CurrentUser(r), id); err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+// Вложения
+
+func (h *APIHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	postID, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachmentService.List(middleware.CurrentUser(r), postID)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	if attachments == nil {
+		attachments = []models.Attachment{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Data: attachments, Meta: listMeta{Total: len(attachments)}})
+}
+
+// CreateAttachments прикрепляет к посту файлы из multipart/form-data поля files
+func (h *APIHandler) CreateAttachments(w http.ResponseWriter, r *http.Request) {
+	postID, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	uploads, err := readUploads(w, r, h.attachmentService.MaxSize())
+	if err != nil {
+		if uploadTooLarge(err) {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, "request_too_large", "файлы слишком большие")
+		} else {
+			writeAPIError(w, http.StatusBadRequest, "invalid_multipart", "не удалось прочитать файлы: "+err.Error())
+		}
+		return
+	}
+	defer closeUploads(uploads)
+
+	attachments, err := h.attachmentService.Attach(middleware.CurrentUser(r), postID, uploads)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, listResponse{Data: attachments, Meta: listMeta{Total: len(attachments)}})
+}
+
+func (h *APIHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.attachmentService.Delete(middleware.CurrentUser(r), id); err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+// requireVisitor пропускает вошедших пользователей и гостей с cookie посетителя
+func requireVisitor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if middleware.CurrentUser(r) == nil && middleware.VisitorID(r) == "" {
+			writeAPIError(w, http.StatusForbidden, "no_visitor",
+				"нет cookie посетителя: откройте страницу блога или войдите")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Комментарии
+
+func (h *APIHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	postID, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	perPage, err := queryInt(r, "per_page", service.CommentsPerPage)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+	if perPage < 1 || perPage > maxPerPage {
+		perPage = service.CommentsPerPage
+	}
+	cursor, ok := queryCursor(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.postService.ListComments(middleware.CurrentUser(r), postID, cursor, perPage)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	comments := page.Comments
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+	pointers := make([]*models.Comment, len(comments))
+	for i := range comments {
+		pointers[i] = &comments[i]
+	}
+	if !h.loadCommentReactions(w, r, pointers...) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{
+		Data: comments,
+		Meta: listMeta{Total: page.Total, PerPage: perPage, NextCursor: cursorString(page.Next)},
+	})
+}
+
+func (h *APIHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	postID, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req commentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	source := spam.Source{IP: clientIP(r)}
+	comment, err := h.postService.AddComment(postID, req.ParentID, middleware.CurrentUser(r), req.Author, req.Content, source)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, comment)
+}
+
+// GetComment возвращает комментарий со всей веткой ответов
+func (h *APIHandler) GetComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	comment, err := h.postService.GetCommentThread(middleware.CurrentUser(r), id)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	if !h.loadCommentReactions(w, r, comment) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, comment)
+}
+
+func (h *APIHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if _, err := h.postService.DeleteComment(middleware.CurrentUser(r), id); err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+// Лайки
+
+func (h *APIHandler) GetLikes(w http.ResponseWriter, r *http.Request) {
+	postID, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	h.writeLikes(w, r, postID, http.StatusOK)
+}
+
+// AddLike ставит лайк; повторный запрос ничего не меняет
+func (h *APIHandler) AddLike(w http.ResponseWriter, r *http.Request) {
+	postID, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.postService.Like(postID, liker(r)); err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	h.writeLikes(w, r, postID, http.StatusCreated)
+}
+
+// RemoveLike снимает лайк, если он был
+func (h *APIHandler) RemoveLike(w http.ResponseWriter, r *http.Request) {
+	postID, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.postService.Unlike(postID, liker(r)); err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	h.writeLikes(w, r, postID, http.StatusOK)
+}
+
+func (h *APIHandler) writeLikes(w http.ResponseWriter, r *http.Request, postID, status int) {
+	post, err := h.postService.GetPostByID(middleware.CurrentUser(r), postID)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	if !h.loadViewerState(w, r, post) {
+		return
+	}
+
+	writeJSON(w, status, likesResponse{PostID: post.ID, LikesCount: post.LikesCount, Liked: post.Liked})
+}
+
+// loadViewerState загружает реакции постов и отмечает лайки и реакции автора
+// запроса; при ошибке пишет ответ и возвращает false
+func (h *APIHandler) loadViewerState(w http.ResponseWriter, r *http.Request, posts ...*models.Post) bool {
+	if err := h.postService.LoadLiked(liker(r), posts...); err != nil {
+		writeAPIServiceError(w, err)
+		return false
+	}
+	if err := h.reactionService.LoadPosts(liker(r), posts...); err != nil {
+		writeAPIServiceError(w, err)
+		return false
+	}
+	return true
+}
+
+// loadCommentReactions загружает реакции комментариев и их веток ответов
+func (h *APIHandler) loadCommentReactions(w http.ResponseWriter, r *http.Request, comments ...*models.Comment) bool {
+	if err := h.reactionService.LoadComments(liker(r), comments...); err != nil {
+		writeAPIServiceError(w, err)
+		return false
+	}
+	return true
+}
+
+func postPointers(posts []models.Post) []*models.Post {
+	pointers := make([]*models.Post, len(posts))
+	for i := range posts {
+		pointers[i] = &posts[i]
+	}
+	return pointers
+}
+
+// Реакции
+
+// reactionHandler передаёт handler'у реакций цель и её id из пути
+func (h *APIHandler) reactionHandler(
+	target models.ReactionTarget,
+	next func(http.ResponseWriter, *http.Request, models.ReactionTarget, int),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := apiURLParamInt(w, r, "id")
+		if !ok {
+			return
+		}
+		next(w, r, target, id)
+	}
+}
+
+// ListReactions: GET .../reactions?emoji=👍&page=1&per_page=20 - кто поставил
+// реакции, новые первыми; без emoji - все реакции цели
+func (h *APIHandler) ListReactions(w http.ResponseWriter, r *http.Request, target models.ReactionTarget, id int) {
+	page, err := queryInt(r, "page", 1)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+	perPage, err := queryInt(r, "per_page", defaultPerPage)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > maxPerPage {
+		perPage = defaultPerPage
+	}
+
+	reactions, total, err := h.reactionService.List(target, id, r.URL.Query().Get("emoji"), page, perPage)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	if reactions == nil {
+		reactions = []models.Reaction{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Data: reactions, Meta: pageMeta(total, page, perPage)})
+}
+
+// AddReaction ставит реакцию; повторный запрос ничего не меняет
+func (h *APIHandler) AddReaction(w http.ResponseWriter, r *http.Request, target models.ReactionTarget, id int) {
+	var req reactionRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	counts, err := h.reactionService.React(target, id, req.Emoji, liker(r))
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, reactionsResponse{Target: target, ID: id, Reactions: counts})
+}
+
+// RemoveReaction: DELETE .../reactions?emoji=👍 снимает реакцию, если она была
+func (h *APIHandler) RemoveReaction(w http.ResponseWriter, r *http.Request, target models.ReactionTarget, id int) {
+	counts, err := h.reactionService.Unreact(target, id, r.URL.Query().Get("emoji"), liker(r))
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reactionsResponse{Target: target, ID: id, Reactions: counts})
+}
+
+// Категории
+
+func (h *APIHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.postService.GetCategories()
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	if categories == nil {
+		categories = []models.Category{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{
+		Data: categories,
+		Meta: listMeta{Total: len(categories)},
+	})
+}
+
+func (h *APIHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	category, err := h.postService.GetCategoryByID(id)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (h *APIHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req categoryRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	category, err := h.categoryService.CreateCategory(middleware.CurrentUser(r), req.Name, req.Slug, req.ParentID)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/categories/"+strconv.Itoa(category.ID))
+	writeJSON(w, http.StatusCreated, category)
+}
+
+func (h *APIHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req categoryRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	category, err := h.categoryService.UpdateCategory(middleware.CurrentUser(r), id, req.Name, req.Slug)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// MoveCategory переносит категорию с подкатегориями в другую родительскую
+func (h *APIHandler) MoveCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req categoryParentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	category, err := h.categoryService.MoveCategory(middleware.CurrentUser(r), id, req.ParentID)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// DeleteCategory: DELETE /categories/{id}?reassign_to=2 - посты удалённой
+// категории переносятся в reassign_to
+func (h *APIHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+	reassignTo, err := queryInt(r, "reassign_to", 0)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	if _, err := h.categoryService.DeleteCategory(middleware.CurrentUser(r), id, reassignTo); err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+// Теги
+
+// ListTags: GET /tags?q=go&limit=20 - теги с постами, самые популярные первыми;
+// q - начало названия тега
+func (h *APIHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultPerPage)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+	if limit < 1 || limit > maxPerPage {
+		limit = defaultPerPage
+	}
+
+	tags, err := h.tagService.Popular(r.URL.Query().Get("q"), limit)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Data: tags, Meta: listMeta{Total: len(tags), PerPage: limit}})
+}
+
+func (h *APIHandler) GetTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	tag, err := h.tagService.GetTagByID(id)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+func (h *APIHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req tagRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tag, err := h.tagService.RenameTag(middleware.CurrentUser(r), id, req.Name, req.Slug)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+// MergeTags: POST /tags/{id}/merge {"into": 3} - посты тега id получают тег
+// into, тег id удаляется. В ответе тег into.
+func (h *APIHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req tagMergeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tag, err := h.tagService.MergeTags(middleware.CurrentUser(r), id, req.Into)
+	if err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+func (h *APIHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiURLParamInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.tagService.DeleteTag(middleware.CurrentUser(r), id); err != nil {
+		writeAPIServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+func apiURLParamInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	value, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_id", "неверный идентификатор: "+chi.URLParam(r, name))
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/s.usynin/testing/go-server/internal/openapi"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+// maxAPIBodySize ограничивает размер JSON тела запроса
+const maxAPIBodySize = 1 << 20
+
+// apiError - тело ответа с ошибкой: {"error": {"code": "...", "message": "..."}}
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// listResponse - ответ со списком и метаданными пагинации
+type listResponse struct {
+	Data any      `json:"data"`
+	Meta listMeta `json:"meta"`
+}
+
+type listMeta struct {
+	Total      int `json:"total"`
+	Page       int `json:"page,omitempty"`
+	PerPage    int `json:"per_page,omitempty"`
+	TotalPages int `json:"total_pages,omitempty"`
+	// NextCursor - курсор следующей страницы; отсутствует на последней
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// writeJSON отдаёт v в JSON со статусом status. Ответ без тела (v == nil,
+// например 204) уходит без Content-Type: JSON в нём нет.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	if v == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Ошибка кодирования JSON ответа: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]apiError{"error": {Code: code, Message: message}})
+}
+
+// writeAPIServiceError переводит ошибки service слоя в JSON ответы
+func writeAPIServiceError(w http.ResponseWriter, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]apiError{"error": {
+			Code:    "validation_failed",
+			Message: validationErr.Message,
+			Field:   validationErr.Field,
+		}})
+	case errors.Is(err, service.ErrUnauthenticated),
+		errors.Is(err, service.ErrInvalidCredentials):
+		writeAPIError(w, http.StatusUnauthorized, "unauthenticated", err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		writeAPIError(w, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, service.ErrNotFound):
+		writeAPIError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, service.ErrConflict):
+		writeAPIError(w, http.StatusConflict, "conflict", err.Error())
+	default:
+		log.Printf("API error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal", "внутренняя ошибка сервера")
+	}
+}
+
+// writeAPIRequestError отвечает на запрос, не прошедший проверку по спецификации
+func writeAPIRequestError(w http.ResponseWriter, r *http.Request, err *openapi.RequestError) {
+	writeJSON(w, err.Status, map[string]apiError{"error": {
+		Code:    err.Code,
+		Message: err.Message,
+		Field:   err.Field,
+	}})
+}
+
+// decodeJSON читает тело запроса в v, отклоняя неизвестные поля и лишние данные
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodySize))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", fmt.Sprintf("некорректный JSON: %v", err))
+		return false
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", "тело запроса должно содержать один JSON объект")
+		return false
+	}
+
+	return true
+}
+
+// queryInt читает целый параметр запроса; пустое значение даёт fallback
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("параметр %s должен быть неотрицательным целым числом", name)
+	}
+	return value, nil
+}
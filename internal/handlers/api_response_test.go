@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeJSON(rec, http.StatusCreated, map[string]int{"id": 1})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "application/json; charset=utf-8" || rec.Body.String() != "{\"id\":1}\n" {
+		t.Errorf("JSON ответ: %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+
+	// У ответа без тела нет и типа содержимого
+	rec = httptest.NewRecorder()
+	writeJSON(rec, http.StatusNoContent, nil)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Content-Type") != "" || rec.Body.Len() != 0 {
+		t.Errorf("204: %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s.usynin/testing/go-server/internal/middleware"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+const (
+	// uploadsField - поле multipart формы и запроса API с файлами вложений
+	uploadsField = "files"
+	// uploadMemory - сколько формы держать в памяти, остальное - во временных файлах
+	uploadMemory = 1 << 20
+	// uploadFormFields - запас на текстовые поля формы сверх самих файлов
+	uploadFormFields = 1 << 20
+)
+
+// AttachmentHandler отдаёт файлы вложений и их превью и открепляет файлы от постов
+type AttachmentHandler struct {
+	attachmentService *service.AttachmentService
+	templates         *template.Template
+}
+
+func NewAttachmentHandler(attachmentService *service.AttachmentService, templates *template.Template) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+		templates:         templates,
+	}
+}
+
+// File - /attachments/{id}/{name}: файл вложения; имя в адресе только для читаемости
+func (h *AttachmentHandler) File(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, false)
+}
+
+// Thumbnail - /attachments/{id}/thumb/{name}: превью изображения
+func (h *AttachmentHandler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, true)
+}
+
+// Delete открепляет файл в форме правки поста; пустой ответ убирает его из списка
+func (h *AttachmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID вложения", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.attachmentService.Delete(middleware.CurrentUser(r), id); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serve отдаёт файл из хранилища. Содержимое по ID не меняется, поэтому
+// файлы опубликованных постов кэшируются надолго; HTML и скрипты не
+// отдаются благодаря проверке типа при загрузке и nosniff.
+func (h *AttachmentHandler) serve(w http.ResponseWriter, r *http.Request, thumb bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := h.attachmentService.Open(middleware.CurrentUser(r), id, thumb)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+	defer file.Close()
+
+	etag := `"a` + strconv.Itoa(id) + `"`
+	if thumb {
+		etag = `"t` + strconv.Itoa(id) + `"`
+	}
+	header := w.Header()
+	header.Set("Content-Type", file.ContentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition(file.ContentType),
+		map[string]string{"filename": file.Attachment.Filename}))
+	header.Set("ETag", etag)
+	if file.Public {
+		header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		header.Set("Cache-Control", "private, no-cache")
+	}
+
+	// Файлы с диска отдаются с поддержкой Range, из S3 - потоком
+	if seeker, ok := file.ReadCloser.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", file.Attachment.CreatedAt, seeker)
+		return
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if !thumb {
+		header.Set("Content-Length", strconv.FormatInt(file.Attachment.Size, 10))
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Ошибка отдачи вложения %d: %v", id, err)
+	}
+}
+
+// disposition - изображения, PDF и текст открываются в браузере, архивы скачиваются
+func disposition(contentType string) string {
+	if strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "text/plain") ||
+		contentType == "application/pdf" {
+		return "inline"
+	}
+	return "attachment"
+}
+
+// readUploads читает файлы вложений из multipart формы. Тело ограничено так,
+// чтобы поместились service.MaxAttachmentsPerPost файлов размера maxSize.
+// В памяти остаётся не больше uploadMemory, остальное пишется во временные
+// файлы, которые сервер удаляет после ответа; из них файлы по частям
+// копируются в хранилище. Открытые файлы закрывает closeUploads. Запрос
+// без multipart (форма без файлов) - пустой список.
+func readUploads(w http.ResponseWriter, r *http.Request, maxSize int64) ([]service.Upload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxAttachmentsPerPost*maxSize+uploadFormFields)
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		if errors.Is(err, http.ErrNotMultipart) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var uploads []service.Upload
+	for _, header := range r.MultipartForm.File[uploadsField] {
+		// Пустое поле файла браузер присылает частью без имени и содержимого
+		if header.Filename == "" && header.Size == 0 {
+			continue
+		}
+		f, err := header.Open()
+		if err != nil {
+			closeUploads(uploads)
+			return nil, err
+		}
+		uploads = append(uploads, service.Upload{Filename: header.Filename, Size: header.Size, Content: f})
+	}
+	return uploads, nil
+}
+
+// closeUploads закрывает файлы, открытые readUploads
+func closeUploads(uploads []service.Upload) {
+	for _, upload := range uploads {
+		if c, ok := upload.Content.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// uploadTooLarge - тело запроса с файлами больше допустимого
+func uploadTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/s.usynin/testing/go-server/internal/middleware"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+type AuthHandler struct {
+	authService   *service.AuthService
+	templates     *template.Template
+	secureCookies bool
+}
+
+func NewAuthHandler(authService *service.AuthService, templates *template.Template, secureCookies bool) *AuthHandler {
+	return &AuthHandler{
+		authService:   authService,
+		templates:     templates,
+		secureCookies: secureCookies,
+	}
+}
+
+// authPageData - данные для страниц входа и регистрации
+type authPageData struct {
+	Username string
+	Error    string
+}
+
+func (h *AuthHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	if middleware.CurrentUser(r) != nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	h.render(w, "login.html", http.StatusOK, authPageData{})
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	username := r.FormValue("username")
+	password := r.FormValue("password")
+
+	_, token, err := h.authService.Login(username, password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		h.render(w, "login.html", http.StatusUnauthorized, authPageData{Username: username, Error: err.Error()})
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.setSessionCookie(w, token)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (h *AuthHandler) SignupPage(w http.ResponseWriter, r *http.Request) {
+	if middleware.CurrentUser(r) != nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	h.render(w, "signup.html", http.StatusOK, authPageData{})
+}
+
+func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
+	username := r.FormValue("username")
+	password := r.FormValue("password")
+
+	if password != r.FormValue("password_confirm") {
+		h.render(w, "signup.html", http.StatusBadRequest, authPageData{Username: username, Error: "Пароли не совпадают"})
+		return
+	}
+
+	user, err := h.authService.Register(username, password)
+	switch {
+	case errors.Is(err, service.ErrInvalidUsername),
+		errors.Is(err, service.ErrWeakPassword),
+		errors.Is(err, service.ErrUsernameTaken):
+		h.render(w, "signup.html", http.StatusBadRequest, authPageData{Username: username, Error: err.Error()})
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Сразу входим под новым пользователем
+	token, err := h.authService.CreateSession(user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.setSessionCookie(w, token)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(middleware.SessionCookieName); err == nil {
+		if err := h.authService.Logout(cookie.Value); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (h *AuthHandler) setSessionCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(service.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *AuthHandler) render(w http.ResponseWriter, name string, status int, data authPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+// renderError отдаёт ошибку HTML фрагментом error_fragment.html.
+// Для HTMX запросов фрагмент перенаправляется в контейнер #flash на странице,
+// чтобы ошибка не заменила собой элемент, на который нацелен запрос.
+func renderError(w http.ResponseWriter, r *http.Request, tpl *template.Template, status int, message string) {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Retarget", "#flash")
+		w.Header().Set("HX-Reswap", "innerHTML")
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := tpl.ExecuteTemplate(w, "error_fragment.html", struct{ Message string }{message}); err != nil {
+		log.Printf("Ошибка рендеринга фрагмента ошибки: %v", err)
+	}
+}
+
+// handleServiceError переводит ошибки service слоя в HTTP статусы
+func handleServiceError(w http.ResponseWriter, r *http.Request, tpl *template.Template, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		renderError(w, r, tpl, http.StatusBadRequest, validationErr.Message)
+	case errors.Is(err, service.ErrUnauthenticated):
+		renderError(w, r, tpl, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		renderError(w, r, tpl, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrNotFound):
+		renderError(w, r, tpl, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrConflict):
+		renderError(w, r, tpl, http.StatusConflict, err.Error())
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s.usynin/testing/go-server/internal/feed"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+const (
+	// blogTitle - название блога в лентах
+	blogTitle = "Простой блог"
+	// feedExcerptLength - длина отрывка поста в лентах, символов
+	feedExcerptLength = 300
+)
+
+// FeedHandler отдаёт ленты для читалок: RSS, Atom и JSON Feed всего блога,
+// категории (с подкатегориями) и тега. Только опубликованные посты, новые первыми.
+type FeedHandler struct {
+	postService *service.PostService
+	tagService  *service.TagService
+	templates   *template.Template
+	// baseURL - адрес блога для ссылок и ID записей; пустой - ленты отключены
+	baseURL string
+	size    int
+	// fullContent - полный текст постов в лентах; иначе только отрывки
+	fullContent bool
+}
+
+func NewFeedHandler(
+	postService *service.PostService,
+	tagService *service.TagService,
+	templates *template.Template,
+	baseURL string,
+	size int,
+	fullContent bool,
+) *FeedHandler {
+	return &FeedHandler{
+		postService: postService,
+		tagService:  tagService,
+		templates:   templates,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		size:        size,
+		fullContent: fullContent,
+	}
+}
+
+// Enabled сообщает, отдаются ли ленты. Без server.base_url их нет: адрес из
+// заголовка Host подделывается и меняется, а с ним менялись бы ID записей.
+func (h *FeedHandler) Enabled() bool {
+	return h.baseURL != ""
+}
+
+// Blog - лента всех постов /feed.{rss,atom,json}; category_id и tag_id сужают её
+func (h *FeedHandler) Blog(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, feedScope(r), blogTitle, "/")
+}
+
+// Category - лента категории /category/{slug}/feed.{rss,atom,json}
+func (h *FeedHandler) Category(w http.ResponseWriter, r *http.Request) {
+	category, err := h.postService.GetCategoryBySlug(chi.URLParam(r, "slug"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	scope := feedScope(r)
+	scope.CategoryID = category.ID
+	h.serve(w, r, scope, category.Name+" - "+blogTitle, "/category/"+category.Slug)
+}
+
+// Tag - лента тега /tag/{slug}/feed.{rss,atom,json}
+func (h *FeedHandler) Tag(w http.ResponseWriter, r *http.Request) {
+	tag, err := h.tagService.GetTagBySlug(chi.URLParam(r, "slug"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	scope := feedScope(r)
+	scope.TagID = tag.ID
+	h.serve(w, r, scope, "#"+tag.Name+" - "+blogTitle, "/tag/"+tag.Slug)
+}
+
+// serve строит ленту в формате {format} из пути и отдаёт её с ETag
+// (хеш содержимого) и Last-Modified (последнее изменение постов), отвечая
+// 304 на условные запросы, если лента не изменилась
+func (h *FeedHandler) serve(w http.ResponseWriter, r *http.Request, scope repository.PostFilter, title, page string) {
+	if !h.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	scope.Limit = h.size
+	result, err := h.postService.ListPosts(scope)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	base := h.baseURL
+	f := &feed.Feed{
+		Title:       title,
+		Description: "Новые посты: " + title,
+		Language:    "ru",
+		Link:        base + page,
+		Self:        base + r.URL.RequestURI(),
+	}
+	for i := range result.Posts {
+		item := h.item(base, &result.Posts[i])
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+		f.Items = append(f.Items, item)
+	}
+	if f.Updated.IsZero() {
+		// Пустой ленте нужна неизменная дата, иначе ETag менялся бы при каждом запросе
+		f.Updated = time.Unix(0, 0)
+	}
+
+	var body []byte
+	switch chi.URLParam(r, "format") {
+	case "rss":
+		w.Header().Set("Content-Type", feed.RSSContentType)
+		body, err = f.RSS()
+	case "atom":
+		w.Header().Set("Content-Type", feed.AtomContentType)
+		body, err = f.Atom()
+	default:
+		w.Header().Set("Content-Type", feed.JSONContentType)
+		body, err = f.JSON()
+	}
+	if err != nil {
+		log.Printf("Ошибка построения ленты %s: %v", r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(body))
+}
+
+// item переводит пост в запись ленты. ID записи - ссылка /posts/{id} без адреса
+// из заголовка: она не меняется при переименовании поста и ведёт на него через 301.
+// Время изменения - последняя правка, но не раньше публикации.
+func (h *FeedHandler) item(base string, post *models.Post) feed.Item {
+	item := feed.Item{
+		ID:        base + "/posts/" + strconv.Itoa(post.ID),
+		Link:      base + post.URL(),
+		Title:     post.Title,
+		Author:    post.AuthorName,
+		Summary:   feed.Excerpt(string(post.ContentHTML), feedExcerptLength),
+		Published: post.Date(),
+		Updated:   post.UpdatedAt,
+	}
+	if item.Updated.Before(item.Published) {
+		item.Updated = item.Published
+	}
+	if h.fullContent {
+		item.ContentHTML = string(post.ContentHTML)
+	}
+	if post.Category != nil {
+		item.Categories = append(item.Categories, post.Category.Name)
+	}
+	for _, tag := range post.Tags {
+		item.Categories = append(item.Categories, tag.Name)
+	}
+	return item
+}
+
+// feedLink - ссылка на ленту для <link rel="alternate"> и значка на странице
+type feedLink struct {
+	Title string
+	Type  string
+	URL   string
+}
+
+// feedLinks - ленты страницы блога: тега (в категории, если открыты оба),
+// категории или всего блога
+func feedLinks(category *models.Category, tag *models.Tag) []feedLink {
+	path, query := "/feed", ""
+	switch {
+	case tag != nil:
+		path = "/tag/" + tag.Slug + "/feed"
+		if category != nil {
+			query = "?category_id=" + strconv.Itoa(category.ID)
+		}
+	case category != nil:
+		path = "/category/" + category.Slug + "/feed"
+	}
+	return []feedLink{
+		{Title: "RSS", Type: "application/rss+xml", URL: path + ".rss" + query},
+		{Title: "Atom", Type: "application/atom+xml", URL: path + ".atom" + query},
+		{Title: "JSON Feed", Type: "application/feed+json", URL: path + ".json" + query},
+	}
+}
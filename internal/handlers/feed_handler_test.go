@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+	"github.com/s.usynin/testing/go-server/internal/feed"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+// Лента отдаётся с ETag и Last-Modified, а на условный запрос к неизменной
+// ленте отвечает 304 без тела
+func TestFeedConditionalGet(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		h := NewFeedHandler(f.postService, f.tagService, f.templates, "https://blog.example.com/", 20, false)
+		f.post("Первый пост", models.PostPublished, time.Now().Add(-time.Hour))
+
+		feeds := map[string]string{
+			"/feed.rss":  feed.RSSContentType,
+			"/feed.atom": feed.AtomContentType,
+			"/feed.json": feed.JSONContentType,
+		}
+		serve := func(target string, header http.Header) (int, http.Header, string) {
+			t.Helper()
+			rec := get("/feed.{format:rss|atom|json}", h.Blog, target, header)
+			return rec.Code, rec.Header(), rec.Body.String()
+		}
+
+		etags := make(map[string]string)
+		for target, contentType := range feeds {
+			status, header, body := serve(target, nil)
+			etag, modified := header.Get("ETag"), header.Get("Last-Modified")
+			if status != http.StatusOK || header.Get("Content-Type") != contentType || etag == "" || modified == "" {
+				t.Fatalf("%s: %d %q, ETag %q, Last-Modified %q", target, status, header.Get("Content-Type"), etag, modified)
+			}
+			if !strings.Contains(body, "https://blog.example.com/posts/") {
+				t.Errorf("%s: в ленте нет абсолютной ссылки на пост:\n%s", target, body)
+			}
+			etags[target] = etag
+
+			lastModified, err := http.ParseTime(modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tests := []struct {
+				name   string
+				header http.Header
+				status int
+			}{
+				{"тот же ETag", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+				{"один из ETag", http.Header{"If-None-Match": {`"old", ` + etag}}, http.StatusNotModified},
+				{"другой ETag", http.Header{"If-None-Match": {`"old"`}}, http.StatusOK},
+				{"не изменилась с", http.Header{"If-Modified-Since": {modified}}, http.StatusNotModified},
+				{"изменилась с", http.Header{"If-Modified-Since": {lastModified.Add(-time.Second).Format(http.TimeFormat)}}, http.StatusOK},
+				// If-None-Match важнее If-Modified-Since
+				{"другой ETag и дата", http.Header{"If-None-Match": {`"old"`}, "If-Modified-Since": {modified}}, http.StatusOK},
+			}
+			for _, tt := range tests {
+				status, _, body := serve(target, tt.header)
+				if status != tt.status {
+					t.Errorf("%s, %s: %d, want %d", target, tt.name, status, tt.status)
+				}
+				if status == http.StatusNotModified && body != "" {
+					t.Errorf("%s, %s: у ответа 304 есть тело", target, tt.name)
+				}
+			}
+		}
+
+		// Новый пост меняет ленту, и прежний ETag больше не подходит
+		f.post("Второй пост", models.PostPublished, time.Now())
+		for target, etag := range etags {
+			status, header, _ := serve(target, http.Header{"If-None-Match": {etag}})
+			if status != http.StatusOK || header.Get("ETag") == etag {
+				t.Errorf("%s после нового поста: %d, ETag %q", target, status, header.Get("ETag"))
+			}
+		}
+
+		// Без адреса блога лент нет
+		disabled := NewFeedHandler(f.postService, f.tagService, f.templates, "", 20, false)
+		if rec := get("/feed.{format:rss|atom|json}", disabled.Blog, "/feed.rss", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("лента без адреса блога: %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/markdown"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/service"
+	"github.com/s.usynin/testing/go-server/internal/spam"
+	"github.com/s.usynin/testing/go-server/internal/templates"
+)
+
+// Handler'ы проверяются с настоящими сервисами над тестовой базой
+// (см. пакет dbtest) и шаблонами из каталога templates
+
+// fixture - сервисы над тестовой базой, шаблоны и готовые автор и категория
+type fixture struct {
+	t          *testing.T
+	posts      repository.PostRepository
+	categories repository.CategoryRepository
+	tags       repository.TagRepository
+
+	postService     *service.PostService
+	reactionService *service.ReactionService
+	tagService      *service.TagService
+	sitemap         *service.Sitemap
+	templates       *template.Template
+
+	author   int
+	category int
+}
+
+func newFixture(t *testing.T, db *database.DB) *fixture {
+	t.Helper()
+	if err := templates.InitTemplates("../../templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fixture{
+		t:          t,
+		posts:      repository.NewPostRepository(db),
+		categories: repository.NewCategoryRepository(db),
+		tags:       repository.NewTagRepository(db),
+		templates:  templates.Tpl,
+	}
+	comments := repository.NewCommentRepository(db)
+	settings := repository.NewSettingsRepository(db)
+	f.sitemap = service.NewSitemap(f.posts, f.categories, f.tags)
+	moderation := service.NewModerationService(comments, f.posts, f.categories, settings,
+		repository.NewNotificationRepository(db), spam.NewFilter())
+	attachments := service.NewAttachmentService(repository.NewAttachmentRepository(db), f.posts, nil, 0)
+	f.postService = service.NewPostService(f.posts, comments, f.categories, f.tags, repository.NewLikeRepository(db),
+		repository.NewRevisionRepository(db), markdown.NewCache(markdown.NewRenderer(), 16),
+		moderation, attachments, f.sitemap, 0)
+	f.reactionService = service.NewReactionService(repository.NewReactionRepository(db), f.posts, comments, nil)
+	f.tagService = service.NewTagService(f.tags, f.sitemap)
+
+	author, err := repository.NewUserRepository(db).Create("author", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.author = int(author)
+	category, err := f.categories.Create("Общие", "general", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.category = int(category)
+	return f
+}
+
+// post создаёт пост; опубликованный - в момент publishedAt
+func (f *fixture) post(title string, status models.PostStatus, publishedAt time.Time) int {
+	f.t.Helper()
+	var at *time.Time
+	if status != models.PostDraft {
+		at = &publishedAt
+	}
+	id, err := f.posts.Create(f.author, title, "Текст поста", f.category, status, at)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return int(id)
+}
+
+// rename меняет заголовок поста новой ревизией
+func (f *fixture) rename(id int, title string) {
+	f.t.Helper()
+	post, err := f.posts.GetByID(id)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	ok, err := f.posts.Update(id, repository.PostUpdate{BaseRevision: post.Revision, EditorID: f.author,
+		Revise: true, Title: title, Content: post.Content, CategoryID: post.CategoryID})
+	if err != nil || !ok {
+		f.t.Fatalf("Update = %v, %v", ok, err)
+	}
+	f.sitemap.PostChanged(id)
+}
+
+// get отдаёт запрос target handler'у h, смонтированному на pattern
+func get(pattern string, h http.HandlerFunc, target string, header http.Header) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get(pattern, h)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
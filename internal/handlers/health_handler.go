@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// readyPingTimeout - сколько ждать ответа БД при проверке готовности
+const readyPingTimeout = 2 * time.Second
+
+// Pinger - проверка доступности БД
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// HealthHandler отвечает на проверки живости и готовности для оркестратора
+// и балансировщика. Сервер готов принимать запросы после запуска и до начала
+// остановки, пока доступна БД.
+type HealthHandler struct {
+	db    Pinger
+	ready atomic.Bool
+}
+
+func NewHealthHandler(db Pinger) *HealthHandler {
+	return &HealthHandler{db: db}
+}
+
+// SetReady отмечает, готов ли сервер принимать запросы
+func (h *HealthHandler) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Live - /healthz: процесс жив и обслуживает запросы
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, "ok")
+}
+
+// Ready - /readyz: 503, пока сервер запускается или останавливается
+// и когда БД недоступна
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if !h.ready.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, "draining")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyPingTimeout)
+	defer cancel()
+	if err := h.db.PingContext(ctx); err != nil {
+		log.Printf("Проверка готовности: БД недоступна: %v", err)
+		writeHealth(w, http.StatusServiceUnavailable, "database unavailable")
+		return
+	}
+
+	writeHealth(w, http.StatusOK, "ok")
+}
+
+func writeHealth(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write([]byte(message + "\n"))
+}
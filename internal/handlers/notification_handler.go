@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/s.usynin/testing/go-server/internal/middleware"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+	templates           *template.Template
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService, templates *template.Template) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		templates:           templates,
+	}
+}
+
+// List - страница уведомлений; открытие страницы отмечает их прочитанными
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := middleware.CurrentUser(r)
+	notifications, err := h.notificationService.List(user)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	data := struct {
+		User          *models.User
+		Notifications []models.Notification
+	}{
+		User:          user,
+		Notifications: notifications,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "notifications.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Count отдаёт число непрочитанных уведомлений для ссылки в шапке; пусто, если их нет
+func (h *NotificationHandler) Count(w http.ResponseWriter, r *http.Request) {
+	count, err := h.notificationService.UnreadCount(middleware.CurrentUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if count > 0 {
+		fmt.Fprintf(w, "%d", count)
+	}
+}
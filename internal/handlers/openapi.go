@@ -0,0 +1,922 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/openapi"
+	"github.com/s.usynin/testing/go-server/internal/service"
+	"github.com/s.usynin/testing/go-server/internal/spam"
+)
+
+// OpenAPISpec описывает все маршруты сервера: JSON API /api/v1 и HTML страницы.
+// При запуске документ сверяется с роутером (openapi.CheckRoutes), поэтому
+// новый маршрут нужно описать здесь же.
+func OpenAPISpec() *openapi.Document {
+	doc := openapi.New("Go Blog API", "1.0.0",
+		"JSON API блога (/api/v1) и HTML страницы с HTMX фрагментами. "+
+			"Авторизация - cookie session или заголовок Authorization: Bearer <token>.")
+
+	doc.Tags = []openapi.Tag{
+		{Name: "auth", Description: "Вход и текущий пользователь"},
+		{Name: "posts", Description: "Посты"},
+		{Name: "comments", Description: "Комментарии"},
+		{Name: "likes", Description: "Лайки. Гостю нужна cookie visitor, которая выдаётся при открытии страницы блога"},
+		{Name: "reactions", Description: "Реакции на посты и комментарии"},
+		{Name: "categories", Description: "Категории"},
+		{Name: "tags", Description: "Теги постов"},
+		{Name: "attachments", Description: "Файлы, прикреплённые к постам"},
+		{Name: "html", Description: "HTML страницы и HTMX фрагменты"},
+		{Name: "feeds", Description: "Ленты RSS, Atom и JSON Feed"},
+		{Name: "seo", Description: "Карта сайта и robots.txt"},
+		{Name: "health", Description: "Проверки живости и готовности сервера"},
+	}
+
+	doc.Components.SecuritySchemes["bearerAuth"] = &openapi.SecurityScheme{
+		Type: "http", Scheme: "bearer",
+		Description: "Токен из POST /api/v1/auth/login",
+	}
+	doc.Components.SecuritySchemes["sessionCookie"] = &openapi.SecurityScheme{
+		Type: "apiKey", In: "cookie", Name: "session",
+	}
+
+	addSchemas(doc)
+	addAPIOperations(doc)
+	addHTMLOperations(doc)
+	addFeedOperations(doc)
+	addSEOOperations(doc)
+	addHealthOperations(doc)
+
+	return doc
+}
+
+func addSchemas(doc *openapi.Document) {
+	roles := make([]string, len(models.Roles))
+	for i, role := range models.Roles {
+		roles[i] = string(role)
+	}
+
+	commentStatuses := make([]string, len(models.CommentStatuses))
+	for i, status := range models.CommentStatuses {
+		commentStatuses[i] = string(status)
+	}
+	moderationModes := make([]string, len(models.ModerationModes))
+	for i, mode := range models.ModerationModes {
+		moderationModes[i] = string(mode)
+	}
+	postStatuses := make([]string, len(models.PostStatuses))
+	for i, status := range models.PostStatuses {
+		postStatuses[i] = string(status)
+	}
+
+	s := doc.Components.Schemas
+
+	s["Error"] = openapi.Object(map[string]*openapi.Schema{
+		"error": openapi.Object(map[string]*openapi.Schema{
+			"code":    openapi.String(),
+			"message": openapi.String(),
+			"field":   openapi.String().Describe("Поле запроса, к которому относится ошибка"),
+		}, "code", "message"),
+	}, "error")
+
+	s["ListMeta"] = openapi.Object(map[string]*openapi.Schema{
+		"total":       openapi.Integer(),
+		"page":        openapi.Integer(),
+		"per_page":    openapi.Integer(),
+		"total_pages": openapi.Integer(),
+		"next_cursor": openapi.String().Describe("Курсор следующей страницы; отсутствует на последней"),
+	}, "total")
+
+	s["User"] = openapi.Object(map[string]*openapi.Schema{
+		"id":         openapi.Integer(),
+		"username":   openapi.String(),
+		"role":       openapi.Enum(roles...),
+		"created_at": openapi.DateTime(),
+	}, "id", "username", "role", "created_at")
+
+	s["Category"] = openapi.Object(map[string]*openapi.Schema{
+		"id":         openapi.Integer(),
+		"name":       openapi.String(),
+		"slug":       openapi.String(),
+		"created_at": openapi.DateTime(),
+		"comment_moderation": openapi.Enum(moderationModes...).Describe(
+			"Режим премодерации комментариев; отсутствует, если действует общий режим блога"),
+		"parent_id":   openapi.Integer().Describe("Родительская категория; отсутствует у категорий верхнего уровня"),
+		"posts_count": openapi.Integer().Describe("Посты самой категории, без подкатегорий"),
+		"ancestors": openapi.Array(openapi.Ref("Category")).Describe(
+			"Родители от верхнего уровня к ближайшему, для хлебных крошек"),
+	}, "id", "name", "slug", "created_at", "posts_count")
+
+	s["CategoryInput"] = openapi.Object(map[string]*openapi.Schema{
+		"name": openapi.String().Length(1, 64),
+		"slug": openapi.String().Describe("Адрес для /category/{slug}: латиница, цифры и дефисы; " +
+			"пустой строится из названия"),
+		"parent_id": openapi.Integer().Min(1).Describe("Родительская категория; учитывается только при создании"),
+	}, "name")
+
+	parentID := openapi.Integer().Min(1).Describe("Новая родительская категория; null - верхний уровень")
+	parentID.Nullable = true
+	s["CategoryParent"] = openapi.Object(map[string]*openapi.Schema{
+		"parent_id": parentID,
+	}, "parent_id")
+
+	s["Tag"] = openapi.Object(map[string]*openapi.Schema{
+		"id":          openapi.Integer(),
+		"name":        openapi.String(),
+		"slug":        openapi.String(),
+		"created_at":  openapi.DateTime(),
+		"posts_count": openapi.Integer(),
+	}, "id", "name", "slug", "created_at", "posts_count")
+	s["Attachment"] = openapi.Object(map[string]*openapi.Schema{
+		"id":            openapi.Integer(),
+		"post_id":       openapi.Integer(),
+		"user_id":       openapi.Integer().Describe("Кто загрузил файл"),
+		"filename":      openapi.String(),
+		"content_type":  openapi.String().Describe("Тип, определённый по содержимому файла"),
+		"size":          openapi.Integer().Describe("Размер в байтах"),
+		"width":         openapi.Integer().Describe("Ширина изображения; отсутствует у остальных файлов"),
+		"height":        openapi.Integer().Describe("Высота изображения; отсутствует у остальных файлов"),
+		"created_at":    openapi.DateTime(),
+		"url":           openapi.String().Describe("Адрес файла, можно вставить в текст поста"),
+		"thumbnail_url": openapi.String().Describe("Адрес превью; есть у изображений JPEG, PNG и GIF"),
+	}, "id", "post_id", "filename", "content_type", "size", "created_at", "url")
+	s["AttachmentUpload"] = openapi.Object(map[string]*openapi.Schema{
+		"files": openapi.Array(&openapi.Schema{Type: "string", Format: "binary"}).Describe(
+			"Файлы: изображения (JPEG, PNG, GIF, WebP), PDF, текст и ZIP; тип определяется по содержимому"),
+	}, "files")
+
+	s["TagInput"] = openapi.Object(map[string]*openapi.Schema{
+		"name": openapi.String().Length(1, 32),
+		"slug": openapi.String().Describe("Адрес для /tag/{slug}: латиница, цифры и дефисы; " +
+			"пустой строится из названия"),
+	}, "name")
+	s["TagMerge"] = openapi.Object(map[string]*openapi.Schema{
+		"into": openapi.Integer().Min(1).Describe("Тег, который получат посты объединяемого"),
+	}, "into")
+
+	s["Comment"] = openapi.Object(map[string]*openapi.Schema{
+		"id":           openapi.Integer(),
+		"post_id":      openapi.Integer(),
+		"user_id":      openapi.Integer().Describe("Отсутствует у комментариев гостей"),
+		"author":       openapi.String(),
+		"content":      openapi.String().Describe("Текст в Markdown"),
+		"content_html": openapi.String(),
+		"created_at":   openapi.DateTime(),
+		"status": openapi.Enum(commentStatuses...).Describe(
+			"pending - комментарий ждёт модерации и пока не показывается"),
+		"parent_id": openapi.Integer().Describe("Комментарий, на который это ответ"),
+		"deleted_at": openapi.DateTime().Describe(
+			"Комментарий удалён, но оставлен ради ответов; author и content пусты"),
+		"depth": openapi.Integer().Describe("Уровень вложенности при показе, 0 - верхний"),
+		"reply_to": openapi.String().Describe(
+			"Автор родителя, если ответ поднят выше из-за ограничения глубины веток"),
+		"replies_count": openapi.Integer().Describe("Число ответов в ветке на всех уровнях"),
+		"replies":       openapi.Array(openapi.Ref("Comment")),
+		"reactions":     openapi.Array(openapi.Ref("ReactionCount")),
+	}, "id", "post_id", "author", "content", "created_at", "status", "depth", "replies_count")
+
+	s["PostStatus"] = openapi.Enum(postStatuses...).Describe(
+		"draft - черновик, scheduled - ждёт published_at, published - опубликован, archived - в архиве")
+	s["Post"] = openapi.Object(map[string]*openapi.Schema{
+		"id":             openapi.Integer(),
+		"title":          openapi.String(),
+		"content":        openapi.String().Describe("Текст в Markdown"),
+		"content_html":   openapi.String().Describe("Content, переведённый в очищенный HTML"),
+		"category_id":    openapi.Integer(),
+		"user_id":        openapi.Integer(),
+		"revision":       openapi.Integer().Describe("Текущая ревизия, передаётся в PUT"),
+		"status":         openapi.Ref("PostStatus"),
+		"published_at":   openapi.DateTime().Describe("Время публикации; у запланированных - в будущем"),
+		"created_at":     openapi.DateTime(),
+		"updated_at":     openapi.DateTime(),
+		"author_name":    openapi.String(),
+		"category":       openapi.Ref("Category"),
+		"tags":           openapi.Array(openapi.Ref("Tag")),
+		"attachments":    openapi.Array(openapi.Ref("Attachment")),
+		"comments":       openapi.Array(openapi.Ref("Comment")),
+		"comments_count": openapi.Integer(),
+		"likes_count":    openapi.Integer(),
+		"liked":          openapi.Boolean().Describe("Пост лайкнул автор запроса"),
+		"reactions":      openapi.Array(openapi.Ref("ReactionCount")),
+		"comments_cursor": openapi.String().Describe(
+			"Курсор для GET /posts/{id}/comments, если показаны не все комментарии"),
+	}, "id", "title", "content", "category_id", "revision", "status", "created_at", "updated_at",
+		"comments_count", "likes_count", "liked")
+
+	s["SearchResult"] = &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"snippet": openapi.String().Describe("Фрагмент текста, совпадения выделены <mark>"),
+			"rank":    {Type: "number", Description: "Чем меньше, тем релевантнее"},
+		},
+		Required:    []string{"snippet", "rank"},
+		Description: "Поля Post плюс snippet и rank",
+	}
+	s["SearchResultList"] = openapi.Object(map[string]*openapi.Schema{
+		"data": openapi.Array(openapi.Ref("SearchResult")),
+		"meta": openapi.Ref("ListMeta"),
+	}, "data", "meta")
+
+	s["Likes"] = openapi.Object(map[string]*openapi.Schema{
+		"post_id":     openapi.Integer(),
+		"likes_count": openapi.Integer(),
+		"liked":       openapi.Boolean(),
+	}, "post_id", "likes_count", "liked")
+
+	s["ReactionCount"] = openapi.Object(map[string]*openapi.Schema{
+		"emoji":   openapi.String(),
+		"count":   openapi.Integer(),
+		"reacted": openapi.Boolean().Describe("Реакцию поставил автор запроса"),
+	}, "emoji", "count", "reacted")
+	s["Reactions"] = openapi.Object(map[string]*openapi.Schema{
+		"target":    openapi.Enum(string(models.ReactionOnPost), string(models.ReactionOnComment)),
+		"id":        openapi.Integer(),
+		"reactions": openapi.Array(openapi.Ref("ReactionCount")),
+	}, "target", "id", "reactions")
+	s["Reaction"] = openapi.Object(map[string]*openapi.Schema{
+		"emoji":      openapi.String(),
+		"user_id":    openapi.Integer().Describe("Отсутствует у реакций гостей"),
+		"username":   openapi.String(),
+		"created_at": openapi.DateTime(),
+	}, "emoji", "created_at")
+	s["ReactionInput"] = openapi.Object(map[string]*openapi.Schema{
+		"emoji": openapi.String().Length(1, 0).Describe("Реакция из набора блога"),
+	}, "emoji")
+
+	s["LoginRequest"] = openapi.Object(map[string]*openapi.Schema{
+		"username": openapi.String().Length(1, 0),
+		"password": openapi.String().Length(1, 0),
+	}, "username", "password")
+
+	s["LoginResponse"] = openapi.Object(map[string]*openapi.Schema{
+		"token":      openapi.String(),
+		"expires_at": openapi.DateTime(),
+		"user":       openapi.Ref("User"),
+	}, "token", "expires_at", "user")
+
+	s["PostInput"] = openapi.Object(map[string]*openapi.Schema{
+		"title":       openapi.String(),
+		"content":     openapi.String(),
+		"category_id": openapi.Integer().Min(1),
+		"tags":        openapi.Array(openapi.String()).Describe("Названия тегов, не больше 10"),
+		"status": openapi.Ref("PostStatus").Describe(
+			"По умолчанию published; archived при создании недопустим"),
+		"published_at": openapi.DateTime().Describe(
+			"Время публикации; в будущем - пост запланирован, без поля - сейчас"),
+	}, "title", "content", "category_id")
+
+	s["PostUpdate"] = openapi.Object(map[string]*openapi.Schema{
+		"title":       openapi.String(),
+		"content":     openapi.String(),
+		"category_id": openapi.Integer().Min(1),
+		"revision":    openapi.Integer().Min(1).Describe("Ревизия, на которой основана правка"),
+		"tags": openapi.Array(openapi.String()).Describe(
+			"Названия тегов; без поля теги не меняются, [] снимает все"),
+		"status": openapi.Ref("PostStatus").Describe(
+			"Без status и published_at публикация не меняется"),
+		"published_at": openapi.DateTime().Describe(
+			"Время публикации; в будущем - пост запланирован"),
+	}, "title", "content", "category_id", "revision")
+
+	s["CommentInput"] = openapi.Object(map[string]*openapi.Schema{
+		"author":    openapi.String().Describe("Имя гостя; для вошедших берётся имя пользователя"),
+		"content":   openapi.String(),
+		"parent_id": openapi.Integer().Min(1).Describe("ID комментария того же поста, если это ответ"),
+	}, "content")
+
+	for _, name := range []string{"User", "Category", "Comment", "Post", "Reaction", "Tag", "Attachment"} {
+		s[name+"List"] = openapi.Object(map[string]*openapi.Schema{
+			"data": openapi.Array(openapi.Ref(name)),
+			"meta": openapi.Ref("ListMeta"),
+		}, "data", "meta")
+	}
+}
+
+func addAPIOperations(doc *openapi.Document) {
+	const api = "/api/v1"
+	authRequired := []map[string][]string{{"bearerAuth": {}}, {"sessionCookie": {}}}
+
+	doc.Add(http.MethodGet, "/api/openapi.json", &openapi.Operation{
+		Summary: "Эта спецификация", OperationID: "getOpenAPI",
+		Responses: responses(ok("Документ OpenAPI", "application/json", nil)),
+	})
+
+	doc.Add(http.MethodPost, api+"/auth/login", &openapi.Operation{
+		Tags: []string{"auth"}, Summary: "Получить токен сессии", OperationID: "login",
+		RequestBody: jsonBody("LoginRequest"),
+		Responses:   responses(jsonOK("LoginResponse"), apiErrors(400, 401, 422)),
+	})
+	doc.Add(http.MethodPost, api+"/auth/logout", &openapi.Operation{
+		Tags: []string{"auth"}, Summary: "Завершить сессию", OperationID: "logout",
+		Security:  authRequired,
+		Responses: responses(noContent()),
+	})
+	doc.Add(http.MethodGet, api+"/me", &openapi.Operation{
+		Tags: []string{"auth"}, Summary: "Текущий пользователь", OperationID: "getMe",
+		Security:  authRequired,
+		Responses: responses(jsonOK("User"), apiErrors(401)),
+	})
+
+	doc.Add(http.MethodGet, api+"/posts", &openapi.Operation{
+		Tags: []string{"posts"}, Summary: "Список постов, новые первыми", OperationID: "listPosts",
+		Parameters: []*openapi.Parameter{
+			queryParam("page", "Номер страницы", openapi.Integer().Min(1)),
+			queryParam("per_page", "Постов на странице", openapi.Integer().Min(1).Max(maxPerPage)),
+			queryParam("category_id", "Фильтр по категории", openapi.Integer().Min(1)),
+			queryParam("tag_id", "Фильтр по тегу", openapi.Integer().Min(1)),
+			queryParam("user_id", "Фильтр по автору", openapi.Integer().Min(1)),
+			queryParam("cursor", "Курсор из meta.next_cursor; заменяет page", openapi.String()),
+			queryParam("status", "Состояние постов; кроме published - только свои (редактору - все)",
+				openapi.Ref("PostStatus")),
+		},
+		Responses: responses(jsonOK("PostList"), apiErrors(400, 401, 403)),
+	})
+	doc.Add(http.MethodGet, api+"/search", &openapi.Operation{
+		Tags: []string{"posts"}, Summary: "Полнотекстовый поиск по постам и комментариям", OperationID: "searchPosts",
+		Parameters: []*openapi.Parameter{
+			{Name: "q", In: "query", Required: true, Description: "Слова для поиска (префиксы, все обязательны)",
+				Schema: openapi.String().Length(1, 500)},
+			queryParam("category_id", "Фильтр по категории", openapi.Integer().Min(1)),
+			queryParam("tag_id", "Фильтр по тегу", openapi.Integer().Min(1)),
+			queryParam("page", "Номер страницы", openapi.Integer().Min(1)),
+			queryParam("per_page", "Результатов на странице", openapi.Integer().Min(1).Max(maxPerPage)),
+		},
+		Responses: responses(jsonOK("SearchResultList"), apiErrors(400, 422)),
+	})
+	doc.Add(http.MethodPost, api+"/posts", &openapi.Operation{
+		Tags: []string{"posts"}, Summary: "Создать пост", OperationID: "createPost",
+		Security:    authRequired,
+		RequestBody: jsonBody("PostInput"),
+		Responses:   responses(jsonCreated("Post"), apiErrors(400, 401, 403, 415, 422)),
+	})
+	doc.Add(http.MethodGet, api+"/posts/{id}", &openapi.Operation{
+		Tags: []string{"posts"}, Summary: "Пост с комментариями", OperationID: "getPost",
+		Parameters: []*openapi.Parameter{pathID("id", "ID поста")},
+		Responses:  responses(jsonOK("Post"), apiErrors(400, 404)),
+	})
+	doc.Add(http.MethodPut, api+"/posts/{id}", &openapi.Operation{
+		Tags: []string{"posts"}, Summary: "Изменить пост", OperationID: "updatePost",
+		Security:    authRequired,
+		Parameters:  []*openapi.Parameter{pathID("id", "ID поста")},
+		RequestBody: jsonBody("PostUpdate"),
+		Responses:   responses(jsonOK("Post"), apiErrors(400, 401, 403, 404, 409, 415, 422)),
+	})
+	doc.Add(http.MethodDelete, api+"/posts/{id}", &openapi.Operation{
+		Tags: []string{"posts"}, Summary: "Удалить пост", OperationID: "deletePost",
+		Security:   authRequired,
+		Parameters: []*openapi.Parameter{pathID("id", "ID поста")},
+		Responses:  responses(noContent(), apiErrors(400, 401, 403, 404)),
+	})
+
+	doc.Add(http.MethodGet, api+"/posts/{id}/attachments", &openapi.Operation{
+		Tags: []string{"attachments"}, Summary: "Вложения поста в порядке загрузки", OperationID: "listAttachments",
+		Parameters: []*openapi.Parameter{pathID("id", "ID поста")},
+		Responses:  responses(jsonOK("AttachmentList"), apiErrors(400, 404)),
+	})
+	doc.Add(http.MethodPost, api+"/posts/{id}/attachments", &openapi.Operation{
+		Tags: []string{"attachments"}, Summary: "Прикрепить файлы к посту", OperationID: "createAttachments",
+		Security:   authRequired,
+		Parameters: []*openapi.Parameter{pathID("id", "ID поста")},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]*openapi.MediaType{
+				"multipart/form-data": {Schema: openapi.Ref("AttachmentUpload")},
+			},
+		},
+		Responses: responses(
+			responseSet{"201": {
+				Description: "Прикреплённые файлы",
+				Content:     map[string]*openapi.MediaType{"application/json": {Schema: openapi.Ref("AttachmentList")}},
+			}},
+			apiErrors(400, 401, 403, 404, 413, 422)),
+	})
+	doc.Add(http.MethodDelete, api+"/attachments/{id}", &openapi.Operation{
+		Tags: []string{"attachments"}, Summary: "Открепить файл и удалить его из хранилища",
+		OperationID: "deleteAttachment",
+		Security:    authRequired,
+		Parameters:  []*openapi.Parameter{pathID("id", "ID вложения")},
+		Responses:   responses(noContent(), apiErrors(400, 401, 403, 404)),
+	})
+
+	doc.Add(http.MethodGet, api+"/posts/{id}/comments", &openapi.Operation{
+		Tags: []string{"comments"}, Summary: "Комментарии верхнего уровня с ветками ответов, новые первыми",
+		OperationID: "listComments",
+		Parameters: []*openapi.Parameter{
+			pathID("id", "ID поста"),
+			queryParam("per_page", "Комментариев на странице", openapi.Integer().Min(1).Max(maxPerPage)),
+			queryParam("cursor", "Курсор из meta.next_cursor или comments_cursor поста", openapi.String()),
+		},
+		Responses: responses(jsonOK("CommentList"), apiErrors(400, 404)),
+	})
+	doc.Add(http.MethodPost, api+"/posts/{id}/comments", &openapi.Operation{
+		Tags: []string{"comments"}, Summary: "Добавить комментарий", OperationID: "createComment",
+		Parameters:  []*openapi.Parameter{pathID("id", "ID поста")},
+		RequestBody: jsonBody("CommentInput"),
+		Responses:   responses(jsonCreated("Comment"), apiErrors(400, 404, 415, 422)),
+	})
+	doc.Add(http.MethodGet, api+"/comments/{id}", &openapi.Operation{
+		Tags: []string{"comments"}, Summary: "Комментарий с веткой ответов", OperationID: "getComment",
+		Parameters: []*openapi.Parameter{pathID("id", "ID комментария")},
+		Responses:  responses(jsonOK("Comment"), apiErrors(400, 404)),
+	})
+	doc.Add(http.MethodDelete, api+"/comments/{id}", &openapi.Operation{
+		Tags: []string{"comments"}, Summary: "Удалить комментарий (с ответами остаётся надгробием)",
+		OperationID: "deleteComment",
+		Security:    authRequired,
+		Parameters:  []*openapi.Parameter{pathID("id", "ID комментария")},
+		Responses:   responses(noContent(), apiErrors(400, 401, 403, 404)),
+	})
+
+	doc.Add(http.MethodGet, api+"/posts/{id}/likes", &openapi.Operation{
+		Tags: []string{"likes"}, Summary: "Число лайков", OperationID: "getLikes",
+		Parameters: []*openapi.Parameter{pathID("id", "ID поста")},
+		Responses:  responses(jsonOK("Likes"), apiErrors(400, 404)),
+	})
+	doc.Add(http.MethodPost, api+"/posts/{id}/likes", &openapi.Operation{
+		Tags: []string{"likes"}, Summary: "Поставить лайк (повторный ничего не меняет)", OperationID: "addLike",
+		Parameters: []*openapi.Parameter{pathID("id", "ID поста")},
+		Responses:  responses(jsonCreated("Likes"), apiErrors(400, 403, 404)),
+	})
+	doc.Add(http.MethodDelete, api+"/posts/{id}/likes", &openapi.Operation{
+		Tags: []string{"likes"}, Summary: "Снять лайк", OperationID: "removeLike",
+		Parameters: []*openapi.Parameter{pathID("id", "ID поста")},
+		Responses:  responses(jsonOK("Likes"), apiErrors(400, 403, 404)),
+	})
+
+	for _, target := range []struct{ path, name, id string }{
+		{"/posts/{id}/reactions", "Post", "ID поста"},
+		{"/comments/{id}/reactions", "Comment", "ID комментария"},
+	} {
+		doc.Add(http.MethodGet, api+target.path, &openapi.Operation{
+			Tags: []string{"reactions"}, Summary: "Кто поставил реакции, новые первыми",
+			OperationID: "list" + target.name + "Reactions",
+			Parameters: []*openapi.Parameter{
+				pathID("id", target.id),
+				queryParam("emoji", "Только эта реакция", openapi.String()),
+				queryParam("page", "Номер страницы", openapi.Integer().Min(1)),
+				queryParam("per_page", "Реакций на странице", openapi.Integer().Min(1).Max(maxPerPage)),
+			},
+			Responses: responses(jsonOK("ReactionList"), apiErrors(400, 404)),
+		})
+		doc.Add(http.MethodPost, api+target.path, &openapi.Operation{
+			Tags: []string{"reactions"}, Summary: "Поставить реакцию (повторная ничего не меняет)",
+			OperationID: "add" + target.name + "Reaction",
+			Parameters:  []*openapi.Parameter{pathID("id", target.id)},
+			RequestBody: jsonBody("ReactionInput"),
+			Responses:   responses(jsonCreated("Reactions"), apiErrors(400, 403, 404, 415, 422)),
+		})
+		doc.Add(http.MethodDelete, api+target.path, &openapi.Operation{
+			Tags: []string{"reactions"}, Summary: "Снять реакцию",
+			OperationID: "remove" + target.name + "Reaction",
+			Parameters: []*openapi.Parameter{
+				pathID("id", target.id),
+				{Name: "emoji", In: "query", Required: true, Description: "Снимаемая реакция",
+					Schema: openapi.String().Length(1, 0)},
+			},
+			Responses: responses(jsonOK("Reactions"), apiErrors(400, 403, 404)),
+		})
+	}
+
+	doc.Add(http.MethodGet, api+"/categories", &openapi.Operation{
+		Tags: []string{"categories"}, Summary: "Список категорий", OperationID: "listCategories",
+		Responses: responses(jsonOK("CategoryList")),
+	})
+	doc.Add(http.MethodGet, api+"/categories/{id}", &openapi.Operation{
+		Tags: []string{"categories"}, Summary: "Категория", OperationID: "getCategory",
+		Parameters: []*openapi.Parameter{pathID("id", "ID категории")},
+		Responses:  responses(jsonOK("Category"), apiErrors(400, 404)),
+	})
+	doc.Add(http.MethodPost, api+"/categories", &openapi.Operation{
+		Tags: []string{"categories"}, Summary: "Создать категорию", OperationID: "createCategory",
+		Security:    authRequired,
+		RequestBody: jsonBody("CategoryInput"),
+		Responses:   responses(jsonCreated("Category"), apiErrors(400, 401, 403, 415, 422)),
+	})
+	doc.Add(http.MethodPut, api+"/categories/{id}", &openapi.Operation{
+		Tags: []string{"categories"}, Summary: "Переименовать категорию или сменить адрес",
+		OperationID: "updateCategory",
+		Security:    authRequired,
+		Parameters:  []*openapi.Parameter{pathID("id", "ID категории")},
+		RequestBody: jsonBody("CategoryInput"),
+		Responses:   responses(jsonOK("Category"), apiErrors(400, 401, 403, 404, 415, 422)),
+	})
+	doc.Add(http.MethodPut, api+"/categories/{id}/parent", &openapi.Operation{
+		Tags: []string{"categories"}, Summary: "Перенести категорию с подкатегориями в другую родительскую",
+		OperationID: "moveCategory",
+		Security:    authRequired,
+		Parameters:  []*openapi.Parameter{pathID("id", "ID категории")},
+		RequestBody: jsonBody("CategoryParent"),
+		Responses:   responses(jsonOK("Category"), apiErrors(400, 401, 403, 404, 415, 422)),
+	})
+	doc.Add(http.MethodDelete, api+"/categories/{id}", &openapi.Operation{
+		Tags: []string{"categories"}, Summary: "Удалить категорию, перенеся её посты", OperationID: "deleteCategory",
+		Security: authRequired,
+		Parameters: []*openapi.Parameter{
+			pathID("id", "ID категории"),
+			{Name: "reassign_to", In: "query", Required: true, Description: "Категория для постов удаляемой",
+				Schema: openapi.Integer().Min(1)},
+		},
+		Responses: responses(noContent(), apiErrors(400, 401, 403, 404, 422)),
+	})
+
+	doc.Add(http.MethodGet, api+"/tags", &openapi.Operation{
+		Tags: []string{"tags"}, Summary: "Теги с постами, самые популярные первыми", OperationID: "listTags",
+		Parameters: []*openapi.Parameter{
+			queryParam("q", "Начало названия тега", openapi.String()),
+			queryParam("limit", "Сколько тегов вернуть", openapi.Integer().Min(1).Max(100)),
+		},
+		Responses: responses(jsonOK("TagList"), apiErrors(400)),
+	})
+	doc.Add(http.MethodGet, api+"/tags/{id}", &openapi.Operation{
+		Tags: []string{"tags"}, Summary: "Тег", OperationID: "getTag",
+		Parameters: []*openapi.Parameter{pathID("id", "ID тега")},
+		Responses:  responses(jsonOK("Tag"), apiErrors(400, 404)),
+	})
+	doc.Add(http.MethodPut, api+"/tags/{id}", &openapi.Operation{
+		Tags: []string{"tags"}, Summary: "Переименовать тег или сменить адрес", OperationID: "renameTag",
+		Security:    authRequired,
+		Parameters:  []*openapi.Parameter{pathID("id", "ID тега")},
+		RequestBody: jsonBody("TagInput"),
+		Responses:   responses(jsonOK("Tag"), apiErrors(400, 401, 403, 404, 415, 422)),
+	})
+	doc.Add(http.MethodPost, api+"/tags/{id}/merge", &openapi.Operation{
+		Tags: []string{"tags"}, Summary: "Объединить тег с другим и удалить его", OperationID: "mergeTags",
+		Security:    authRequired,
+		Parameters:  []*openapi.Parameter{pathID("id", "ID объединяемого тега")},
+		RequestBody: jsonBody("TagMerge"),
+		Responses:   responses(jsonOK("Tag"), apiErrors(400, 401, 403, 404, 415, 422)),
+	})
+	doc.Add(http.MethodDelete, api+"/tags/{id}", &openapi.Operation{
+		Tags: []string{"tags"}, Summary: "Удалить тег, сняв его с постов", OperationID: "deleteTag",
+		Security:   authRequired,
+		Parameters: []*openapi.Parameter{pathID("id", "ID тега")},
+		Responses:  responses(noContent(), apiErrors(400, 401, 403, 404)),
+	})
+}
+
+func addHTMLOperations(doc *openapi.Document) {
+	page := func(method, path, summary, id string, body *openapi.Schema, params ...*openapi.Parameter) {
+		op := &openapi.Operation{
+			Tags: []string{"html"}, Summary: summary, OperationID: id,
+			Parameters: params,
+			Responses:  responses(ok("HTML страница или фрагмент", "text/html", nil)),
+		}
+		if body != nil {
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content: map[string]*openapi.MediaType{
+					"application/x-www-form-urlencoded": {Schema: body},
+				},
+			}
+		}
+		doc.Add(method, path, op)
+	}
+	// upload - форма поста с файлами вложений в поле files
+	upload := func(method, path, summary, id string, body *openapi.Schema, params ...*openapi.Parameter) {
+		body.Properties["files"] = openapi.Array(&openapi.Schema{Type: "string", Format: "binary"}).Describe(
+			"Файлы вложений, необязательно")
+		doc.Add(method, path, &openapi.Operation{
+			Tags: []string{"html"}, Summary: summary, OperationID: id,
+			Parameters: params,
+			RequestBody: &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]*openapi.MediaType{"multipart/form-data": {Schema: body}},
+			},
+			Responses: responses(ok("HTML страница или фрагмент", "text/html", nil)),
+		})
+	}
+
+	credentials := openapi.Object(map[string]*openapi.Schema{
+		"username": openapi.String(),
+		"password": openapi.String(),
+	}, "username", "password")
+	signup := openapi.Object(map[string]*openapi.Schema{
+		"username":         openapi.String(),
+		"password":         openapi.String(),
+		"password_confirm": openapi.String(),
+	}, "username", "password", "password_confirm")
+	postForm := openapi.Object(map[string]*openapi.Schema{
+		"title":        openapi.String(),
+		"content":      openapi.String(),
+		"category_id":  openapi.Integer(),
+		"tags":         openapi.String().Describe("Теги через запятую"),
+		"status":       openapi.Ref("PostStatus"),
+		"published_at": openapi.String().Describe("Время публикации в UTC: 2024-05-01T09:30"),
+	}, "title", "content", "category_id")
+	postEditForm := openapi.Object(map[string]*openapi.Schema{
+		"title":        openapi.String(),
+		"content":      openapi.String(),
+		"category_id":  openapi.Integer(),
+		"revision":     openapi.Integer(),
+		"tags":         openapi.String().Describe("Теги через запятую"),
+		"status":       openapi.Ref("PostStatus"),
+		"published_at": openapi.String().Describe("Время публикации в UTC: 2024-05-01T09:30"),
+	}, "title", "content", "category_id", "revision")
+	commentForm := openapi.Object(map[string]*openapi.Schema{
+		"post_id":   openapi.Integer(),
+		"parent_id": openapi.Integer(),
+		"author":    openapi.String(),
+		"content":   openapi.String(),
+		// Поля спам фильтра: ловушка для ботов (должна быть пустой) и одноразовый токен
+		// времени показа формы; токен для следующего комментария - в заголовке ответа X-Form-Token
+		spam.HoneypotField:  openapi.String(),
+		spam.FormTokenField: openapi.String(),
+	}, "post_id", "content")
+	likeForm := openapi.Object(map[string]*openapi.Schema{
+		"post_id": openapi.Integer(),
+	}, "post_id")
+	reactionForm := openapi.Object(map[string]*openapi.Schema{
+		"target": openapi.Enum(string(models.ReactionOnPost), string(models.ReactionOnComment)),
+		"id":     openapi.Integer(),
+		"emoji":  openapi.String(),
+	}, "target", "id", "emoji")
+	categoryForm := openapi.Object(map[string]*openapi.Schema{
+		"name":      openapi.String(),
+		"slug":      openapi.String(),
+		"parent_id": openapi.Integer(),
+	}, "name")
+	roleForm := openapi.Object(map[string]*openapi.Schema{
+		"role": openapi.String(),
+	}, "role")
+
+	postID := pathID("id", "ID поста")
+
+	queueStatuses := make([]string, len(service.ModerationQueueStatuses))
+	for i, status := range service.ModerationQueueStatuses {
+		queueStatuses[i] = string(status)
+	}
+	moderationModes := make([]string, len(models.ModerationModes))
+	for i, mode := range models.ModerationModes {
+		moderationModes[i] = string(mode)
+	}
+
+	searchParams := []*openapi.Parameter{
+		queryParam("q", "Поисковый запрос; пустой - лента постов", openapi.String()),
+		queryParam("category_id", "Фильтр по категории", openapi.Integer().Min(1)),
+		queryParam("tag_id", "Фильтр по тегу", openapi.Integer().Min(1)),
+		queryParam("page", "Страница результатов поиска", openapi.Integer().Min(1)),
+		queryParam("cursor", "Курсор следующей страницы ленты", openapi.String()),
+	}
+	page(http.MethodGet, "/", "Главная страница", "homePage", nil, searchParams...)
+	page(http.MethodGet, "/category/{slug}", "Главная на вкладке категории", "categoryPage", nil,
+		&openapi.Parameter{Name: "slug", In: "path", Required: true, Description: "Адрес категории",
+			Schema: openapi.String()},
+		queryParam("q", "Поиск в категории", openapi.String()),
+		queryParam("tag_id", "Фильтр по тегу", openapi.Integer().Min(1)),
+		queryParam("page", "Страница результатов поиска", openapi.Integer().Min(1)),
+		queryParam("cursor", "Курсор следующей страницы ленты", openapi.String()))
+	page(http.MethodGet, "/tag/{slug}", "Посты с тегом", "tagPage", nil,
+		&openapi.Parameter{Name: "slug", In: "path", Required: true, Description: "Адрес тега",
+			Schema: openapi.String()},
+		queryParam("q", "Поиск среди постов с тегом", openapi.String()),
+		queryParam("category_id", "Фильтр по категории", openapi.Integer().Min(1)),
+		queryParam("page", "Страница результатов поиска", openapi.Integer().Min(1)),
+		queryParam("cursor", "Курсор следующей страницы ленты", openapi.String()))
+	page(http.MethodGet, "/tags/suggest", "Фрагмент подсказок тегов", "suggestTags", nil,
+		queryParam("tags", "Теги через запятую; дополняется последний", openapi.String()))
+	page(http.MethodGet, "/search", "Фрагмент списка постов или результатов поиска", "searchFragment", nil,
+		searchParams...)
+	page(http.MethodGet, "/login", "Форма входа", "loginPage", nil)
+	page(http.MethodPost, "/login", "Вход", "loginForm", credentials)
+	page(http.MethodGet, "/signup", "Форма регистрации", "signupPage", nil)
+	page(http.MethodPost, "/signup", "Регистрация", "signupForm", signup)
+	page(http.MethodPost, "/logout", "Выход", "logoutForm", nil)
+
+	upload(http.MethodPost, "/posts", "Создать пост", "createPostForm", postForm)
+	page(http.MethodPost, "/posts/preview", "Предпросмотр Markdown", "previewPost",
+		openapi.Object(map[string]*openapi.Schema{"content": openapi.String()}))
+	page(http.MethodGet, "/posts/{id}", "Страница поста со всеми комментариями", "postPage", nil,
+		&openapi.Parameter{Name: "id", In: "path", Required: true,
+			Description: "ID поста и адрес из заголовка: 12-privet-mir; другой адрес перенаправляется (301)",
+			Schema:      openapi.String()})
+	page(http.MethodGet, "/drafts", "Черновики, запланированные и архивные посты", "draftsPage", nil,
+		queryParam("status", "Состояние постов, по умолчанию draft", openapi.Ref("PostStatus")),
+		queryParam("cursor", "Курсор следующей страницы", openapi.String()))
+	page(http.MethodGet, "/posts/{id}/item", "Фрагмент карточки поста", "postItem", nil, postID)
+	page(http.MethodGet, "/posts/{id}/comments", "Фрагмент более ранних комментариев", "postComments", nil,
+		postID, queryParam("before", "Курсор самого раннего показанного комментария", openapi.String()))
+	page(http.MethodGet, "/posts/{id}/edit", "Форма редактирования", "editPostForm", nil, postID)
+	upload(http.MethodPut, "/posts/{id}", "Сохранить правку", "updatePostForm", postEditForm, postID)
+	page(http.MethodDelete, "/posts/{id}", "Удалить пост", "deletePostForm", nil, postID)
+	page(http.MethodGet, "/posts/{id}/revisions", "История ревизий", "postRevisions", nil, postID)
+	page(http.MethodGet, "/posts/{id}/revisions/diff", "Сравнение ревизий", "postRevisionDiff", nil, postID,
+		queryParam("from", "Ревизия слева", openapi.Integer().Min(1)),
+		queryParam("to", "Ревизия справа", openapi.Integer().Min(1)))
+	page(http.MethodPost, "/posts/{id}/revisions/{revision}/restore", "Восстановить ревизию", "restoreRevision", nil,
+		postID, pathID("revision", "Номер ревизии"))
+
+	attachmentID := pathID("id", "ID вложения")
+	fileName := &openapi.Parameter{Name: "name", In: "path", Required: true,
+		Description: "Имя файла, только для читаемости адреса", Schema: openapi.String()}
+	fileResponses := responses(
+		ok("Файл; ETag и долгое кэширование у опубликованных постов", "application/octet-stream", nil),
+		responseSet{"304": {Description: "Не изменился (If-None-Match)"}},
+		responseSet{"404": {Description: "Вложения нет или пост не виден"}},
+	)
+	doc.Add(http.MethodGet, "/attachments/{id}/{name}", &openapi.Operation{
+		Tags: []string{"attachments"}, Summary: "Файл вложения", OperationID: "attachmentFile",
+		Parameters: []*openapi.Parameter{attachmentID, fileName}, Responses: fileResponses,
+	})
+	doc.Add(http.MethodGet, "/attachments/{id}/thumb/{name}", &openapi.Operation{
+		Tags: []string{"attachments"}, Summary: "Превью изображения", OperationID: "attachmentThumbnail",
+		Parameters: []*openapi.Parameter{attachmentID, fileName}, Responses: fileResponses,
+	})
+	page(http.MethodDelete, "/attachments/{id}", "Открепить файл в форме правки", "deleteAttachmentForm", nil,
+		attachmentID)
+
+	page(http.MethodPost, "/comments", "Добавить комментарий или ответ", "createCommentForm", commentForm)
+	page(http.MethodGet, "/comments/{id}/replies", "Фрагмент ветки ответов", "commentReplies", nil,
+		pathID("id", "ID комментария"),
+		queryParam("collapsed", "Свернуть ветку до кнопки", openapi.Boolean()))
+	page(http.MethodDelete, "/comments/{id}", "Удалить комментарий", "deleteCommentForm", nil,
+		pathID("id", "ID комментария"))
+	page(http.MethodPost, "/likes", "Поставить или снять лайк", "toggleLikeForm", likeForm)
+	page(http.MethodPost, "/reactions", "Поставить или снять реакцию", "toggleReactionForm", reactionForm)
+
+	page(http.MethodGet, "/notifications", "Уведомления пользователя", "notificationsPage", nil)
+	page(http.MethodGet, "/notifications/count", "Число непрочитанных уведомлений", "notificationsCount", nil)
+
+	page(http.MethodGet, "/admin/users", "Управление пользователями", "adminUsers", nil)
+	page(http.MethodPut, "/admin/users/{id}/role", "Сменить роль", "setUserRole", roleForm,
+		pathID("id", "ID пользователя"))
+	page(http.MethodGet, "/admin/categories", "Управление категориями", "adminCategories", nil)
+	page(http.MethodPost, "/admin/categories", "Создать категорию", "createCategoryForm", categoryForm)
+	page(http.MethodPut, "/admin/categories/{id}", "Переименовать категорию", "updateCategoryForm", categoryForm,
+		pathID("id", "ID категории"))
+	page(http.MethodPut, "/admin/categories/{id}/parent", "Перенести категорию", "moveCategoryForm",
+		openapi.Object(map[string]*openapi.Schema{"parent_id": openapi.Integer()}),
+		pathID("id", "ID категории"))
+	page(http.MethodDelete, "/admin/categories/{id}", "Удалить категорию", "deleteCategoryForm", nil,
+		pathID("id", "ID категории"),
+		queryParam("reassign_to", "Категория, в которую переносятся посты", openapi.Integer().Min(1)))
+	page(http.MethodGet, "/admin/tags", "Управление тегами", "adminTags", nil)
+	page(http.MethodPut, "/admin/tags/{id}", "Переименовать тег", "renameTagForm",
+		openapi.Object(map[string]*openapi.Schema{
+			"name": openapi.String(),
+			"slug": openapi.String(),
+		}, "name"),
+		pathID("id", "ID тега"))
+	page(http.MethodPost, "/admin/tags/{id}/merge", "Объединить теги", "mergeTagsForm",
+		openapi.Object(map[string]*openapi.Schema{"into": openapi.Integer()}, "into"),
+		pathID("id", "ID тега"))
+	page(http.MethodDelete, "/admin/tags/{id}", "Удалить тег", "deleteTagForm", nil, pathID("id", "ID тега"))
+	page(http.MethodGet, "/admin/comments", "Очередь модерации комментариев", "adminComments", nil,
+		queryParam("status", "Состояние комментариев в очереди",
+			openapi.Enum(queueStatuses...)),
+		queryParam("page", "Страница очереди", openapi.Integer().Min(1)))
+	page(http.MethodPost, "/admin/comments/moderate", "Одобрить, отклонить или пометить спамом", "moderateComments",
+		openapi.Object(map[string]*openapi.Schema{
+			"ids":    openapi.Array(openapi.Integer()),
+			"action": openapi.Enum(string(models.CommentApproved), string(models.CommentRejected), string(models.CommentSpam)),
+			"status": openapi.Enum(queueStatuses...),
+			"page":   openapi.Integer(),
+		}, "ids", "action"))
+	// Кроме global форма содержит поле category_{id} для каждой категории
+	settingsForm := openapi.Object(map[string]*openapi.Schema{
+		"global": openapi.Enum(moderationModes...),
+	}, "global")
+	settingsForm.AdditionalProperties = nil
+	page(http.MethodPut, "/admin/comments/settings", "Режимы премодерации", "moderationSettings", settingsForm)
+	page(http.MethodPut, "/admin/comments/blocklist", "Списки блокировки спам фильтра", "spamBlocklist",
+		openapi.Object(map[string]*openapi.Schema{
+			"blocked_words": openapi.String(),
+			"blocked_ips":   openapi.String(),
+		}))
+
+	page(http.MethodGet, "/static/{path}", "Статические файлы", "static", nil,
+		&openapi.Parameter{Name: "path", In: "path", Required: true, Schema: openapi.String()})
+}
+
+// Помощники для описания операций
+
+func addFeedOperations(doc *openapi.Document) {
+	format := &openapi.Parameter{Name: "format", In: "path", Required: true,
+		Description: "rss - RSS 2.0, atom - Atom 1.0, json - JSON Feed 1.1",
+		Schema:      openapi.Enum("rss", "atom", "json")}
+	slug := func(description string) *openapi.Parameter {
+		return &openapi.Parameter{Name: "slug", In: "path", Required: true, Description: description,
+			Schema: openapi.String()}
+	}
+	feedResponses := responses(
+		responseSet{"200": {
+			Description: "Лента; ETag и Last-Modified для условных запросов",
+			Content: map[string]*openapi.MediaType{
+				"application/rss+xml":   {},
+				"application/atom+xml":  {},
+				"application/feed+json": {},
+			},
+		}},
+		responseSet{"304": {Description: "Лента не изменилась (If-None-Match, If-Modified-Since)"}},
+	)
+
+	feedOp := func(path, summary, id string, params ...*openapi.Parameter) {
+		doc.Add(http.MethodGet, path, &openapi.Operation{
+			Tags: []string{"feeds"}, Summary: summary, OperationID: id,
+			Parameters: params, Responses: feedResponses,
+		})
+	}
+	feedOp("/feed.{format}", "Лента всех опубликованных постов", "blogFeed", format,
+		queryParam("category_id", "Только посты категории", openapi.Integer().Min(1)),
+		queryParam("tag_id", "Только посты с тегом", openapi.Integer().Min(1)))
+	feedOp("/category/{slug}/feed.{format}", "Лента категории и её подкатегорий", "categoryFeed",
+		slug("Адрес категории"), format,
+		queryParam("tag_id", "Только посты с тегом", openapi.Integer().Min(1)))
+	feedOp("/tag/{slug}/feed.{format}", "Лента тега", "tagFeed", slug("Адрес тега"), format,
+		queryParam("category_id", "Только посты категории", openapi.Integer().Min(1)))
+}
+
+func addSEOOperations(doc *openapi.Document) {
+	xmlOK := responses(ok("Карта сайта sitemaps.org", "application/xml", nil),
+		responseSet{"304": {Description: "Не изменилась с If-Modified-Since"}})
+
+	doc.Add(http.MethodGet, "/sitemap.xml", &openapi.Operation{
+		Tags: []string{"seo"}, OperationID: "sitemap",
+		Summary: "Карта сайта: главная, категории, теги и опубликованные посты; " +
+			"больше 50 000 адресов - индекс карт /sitemap-{n}.xml",
+		Responses: xmlOK,
+	})
+	doc.Add(http.MethodGet, "/sitemap-{n}.xml", &openapi.Operation{
+		Tags: []string{"seo"}, Summary: "Часть карты сайта из индекса", OperationID: "sitemapChunk",
+		Parameters: []*openapi.Parameter{pathID("n", "Номер части, с 1")},
+		Responses:  xmlOK,
+	})
+	doc.Add(http.MethodGet, "/robots.txt", &openapi.Operation{
+		Tags: []string{"seo"}, Summary: "Правила для поисковых роботов и адрес карты сайта", OperationID: "robots",
+		Responses: responses(ok("robots.txt", "text/plain", nil)),
+	})
+}
+
+func addHealthOperations(doc *openapi.Document) {
+	doc.Add(http.MethodGet, "/healthz", &openapi.Operation{
+		Tags: []string{"health"}, Summary: "Процесс жив", OperationID: "healthz",
+		Responses: responses(ok("ok", "text/plain", nil)),
+	})
+	doc.Add(http.MethodGet, "/readyz", &openapi.Operation{
+		Tags: []string{"health"}, OperationID: "readyz",
+		Summary: "Сервер готов принимать запросы: запущен, не останавливается и БД доступна",
+		Responses: responses(ok("ok", "text/plain", nil),
+			responseSet{"503": {Description: "Сервер запускается, останавливается или БД недоступна"}}),
+	})
+}
+
+func pathID(name, description string) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "path", Required: true, Description: description,
+		Schema: openapi.Integer().Min(1)}
+}
+
+func queryParam(name, description string, schema *openapi.Schema) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func jsonBody(schema string) *openapi.RequestBody {
+	return &openapi.RequestBody{
+		Required: true,
+		Content:  map[string]*openapi.MediaType{"application/json": {Schema: openapi.Ref(schema)}},
+	}
+}
+
+type responseSet map[string]*openapi.Response
+
+func responses(sets ...responseSet) map[string]*openapi.Response {
+	all := make(map[string]*openapi.Response)
+	for _, set := range sets {
+		for code, resp := range set {
+			all[code] = resp
+		}
+	}
+	return all
+}
+
+func ok(description, contentType string, schema *openapi.Schema) responseSet {
+	return responseSet{"200": {
+		Description: description,
+		Content:     map[string]*openapi.MediaType{contentType: {Schema: schema}},
+	}}
+}
+
+func jsonOK(schema string) responseSet {
+	return ok("OK", "application/json", openapi.Ref(schema))
+}
+
+func jsonCreated(schema string) responseSet {
+	return responseSet{"201": {
+		Description: "Создано",
+		Content:     map[string]*openapi.MediaType{"application/json": {Schema: openapi.Ref(schema)}},
+	}}
+}
+
+func noContent() responseSet {
+	return responseSet{"204": {Description: "Нет содержимого"}}
+}
+
+func apiErrors(codes ...int) responseSet {
+	set := make(responseSet)
+	for _, code := range codes {
+		set[strconv.Itoa(code)] = &openapi.Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*openapi.MediaType{"application/json": {Schema: openapi.Ref("Error")}},
+		}
+	}
+	return set
+}
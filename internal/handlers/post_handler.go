@@ -3,41 +3,261 @@ package handlers
 import (
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/s.usynin/testing/go-server/internal/middleware"
 	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
 	"github.com/s.usynin/testing/go-server/internal/service"
+	"github.com/s.usynin/testing/go-server/internal/spam"
 )
 
 type PostHandler struct {
-	postService *service.PostService
-	templates   *template.Template
+	postService       *service.PostService
+	reactionService   *service.ReactionService
+	tagService        *service.TagService
+	attachmentService *service.AttachmentService
+	templates         *template.Template
+	// feeds - показывать ссылки на ленты (они есть только с BASE_URL)
+	feeds bool
 }
 
-func NewPostHandler(postService *service.PostService, templates *template.Template) *PostHandler {
+func NewPostHandler(
+	postService *service.PostService,
+	reactionService *service.ReactionService,
+	tagService *service.TagService,
+	attachmentService *service.AttachmentService,
+	templates *template.Template,
+	feeds bool,
+) *PostHandler {
 	return &PostHandler{
-		postService: postService,
-		templates:   templates,
+		postService:       postService,
+		reactionService:   reactionService,
+		tagService:        tagService,
+		attachmentService: attachmentService,
+		templates:         templates,
+		feeds:             feeds,
 	}
 }
 
+// postView - пост вместе с текущим пользователем, чтобы шаблоны
+// могли показывать формы в зависимости от того, кто смотрит страницу.
+// FormToken - токен спам фильтра для форм комментариев.
+type postView struct {
+	*models.Post
+	Viewer    *models.User
+	CanEdit   bool
+	CanDelete bool
+	Comments  []commentView
+	FormToken string
+}
+
+func (h *PostHandler) newPostView(post *models.Post, viewer *models.User) postView {
+	return postView{
+		Post:      post,
+		Viewer:    viewer,
+		CanEdit:   service.CanEditPost(viewer, post),
+		CanDelete: service.CanDeletePost(viewer, post),
+		Comments:  h.newCommentViews(post.Comments, viewer),
+		FormToken: h.postService.CommentFormToken(post.ID),
+	}
+}
+
+func (h *PostHandler) newPostViews(posts []models.Post, viewer *models.User) []postView {
+	views := make([]postView, len(posts))
+	for i := range posts {
+		views[i] = h.newPostView(&posts[i], viewer)
+	}
+	return views
+}
+
+// commentView - комментарий с веткой ответов для шаблонов comment_item.html
+// и comment_replies.html. Collapsed - ветка ответов свёрнута.
+type commentView struct {
+	*models.Comment
+	Viewer    *models.User
+	CanDelete bool
+	Collapsed bool
+	Replies   []commentView
+	FormToken string
+}
+
+func (h *PostHandler) newCommentView(comment *models.Comment, viewer *models.User) commentView {
+	return commentView{
+		Comment:   comment,
+		Viewer:    viewer,
+		CanDelete: service.CanDeleteComment(viewer, comment),
+		Replies:   h.newCommentViews(comment.Replies, viewer),
+		FormToken: h.postService.CommentFormToken(comment.PostID),
+	}
+}
+
+func (h *PostHandler) newCommentViews(comments []models.Comment, viewer *models.User) []commentView {
+	views := make([]commentView, len(comments))
+	for i := range comments {
+		views[i] = h.newCommentView(&comments[i], viewer)
+	}
+	return views
+}
+
 func (h *PostHandler) Home(w http.ResponseWriter, r *http.Request) {
-	posts, err := h.postService.GetAllPosts()
+	h.renderHome(w, r, feedScope(r))
+}
+
+// CategoryPage - главная, открытая на вкладке категории /category/{slug};
+// tag_id дополнительно оставляет только посты с тегом
+func (h *PostHandler) CategoryPage(w http.ResponseWriter, r *http.Request) {
+	category, err := h.postService.GetCategoryBySlug(chi.URLParam(r, "slug"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	scope := feedScope(r)
+	scope.CategoryID = category.ID
+	h.renderHome(w, r, scope)
+}
+
+// TagPage - посты с тегом /tag/{slug}; category_id дополнительно
+// оставляет только посты категории
+func (h *PostHandler) TagPage(w http.ResponseWriter, r *http.Request) {
+	tag, err := h.tagService.GetTagBySlug(chi.URLParam(r, "slug"))
 	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	scope := feedScope(r)
+	scope.TagID = tag.ID
+	h.renderHome(w, r, scope)
+}
+
+// PostPage - отдельная страница поста со всеми комментариями /posts/{id}-{адрес}.
+// Ссылки со старым адресом (заголовок поменяли) или без адреса перенаправляются
+// на текущую постоянную ссылку с кодом 301.
+func (h *PostHandler) PostPage(w http.ResponseWriter, r *http.Request) {
+	ref := chi.URLParam(r, "id")
+	idStr, _, _ := strings.Cut(ref, "-")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusNotFound, "Пост не найден")
+		return
+	}
+
+	post, err := h.postService.GetPostWithAllComments(middleware.CurrentUser(r), id)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+	if "/posts/"+ref != post.URL() {
+		target := post.URL()
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	if err := h.loadViewerState(r, post); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	categories, _ := h.postService.GetCategories()
+	user := middleware.CurrentUser(r)
+	data := struct {
+		Post        postView
+		User        *models.User
+		CanModerate bool
+	}{
+		Post:        h.newPostView(post, user),
+		User:        user,
+		CanModerate: service.Can(user, service.PermModerateComments),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "post_page.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// feedScope читает из запроса category_id и tag_id - какие посты показывать
+// в ленте и искать
+func feedScope(r *http.Request) repository.PostFilter {
+	categoryID, _ := strconv.Atoi(r.URL.Query().Get("category_id"))
+	tagID, _ := strconv.Atoi(r.URL.Query().Get("tag_id"))
+	return repository.PostFilter{CategoryID: categoryID, TagID: tagID}
+}
+
+// renderHome рендерит главную с лентой постов scope: категории и (или) тега;
+// пустой scope - все посты
+func (h *PostHandler) renderHome(w http.ResponseWriter, r *http.Request, scope repository.PostFilter) {
+	list, err := h.postList(r, scope)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+	cloud, err := h.tagService.Cloud(service.TagCloudSize)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	tree, err := h.postService.CategoryTree()
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+	user := middleware.CurrentUser(r)
 
 	data := struct {
-		Posts      []models.Post
+		List *postList
+		// Categories - все категории деревом, для формы нового поста
 		Categories []models.Category
+		// Tabs - вкладки категорий верхнего уровня, ActiveTab - открытая из них
+		Tabs      []models.Category
+		ActiveTab int
+		// Category - открытая категория с родителями, Subcategories - её подкатегории
+		Category      *models.Category
+		Subcategories []models.Category
+		// Tag - тег, по которому отфильтрована лента; TagCloud - облако тегов
+		Tag      *models.Tag
+		TagCloud []models.Tag
+		// Feeds - ленты открытой страницы для читалок
+		Feeds []feedLink
+		// UploadLimits - подсказка к полю файлов в форме
+		UploadLimits  string
+		User          *models.User
+		CanCreatePost bool
+		CanModerate   bool
 	}{
-		Posts:      posts,
-		Categories: categories,
+		List:          list,
+		Categories:    tree.Flatten(),
+		Tabs:          tree.Children(0),
+		TagCloud:      cloud,
+		UploadLimits:  h.attachmentService.Limits(),
+		User:          user,
+		CanCreatePost: service.Can(user, service.PermCreatePost),
+		CanModerate:   service.Can(user, service.PermModerateComments),
+	}
+	if category, ok := tree.Get(scope.CategoryID); ok {
+		data.Category = category
+		data.Subcategories = tree.Children(scope.CategoryID)
+		data.ActiveTab = tree.Root(scope.CategoryID)
+	}
+	if scope.TagID != 0 {
+		if data.Tag, err = h.tagService.GetTagByID(scope.TagID); err != nil {
+			handleServiceError(w, r, h.templates, err)
+			return
+		}
+	}
+	if h.feeds {
+		data.Feeds = feedLinks(data.Category, data.Tag)
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -49,6 +269,12 @@ func (h *PostHandler) Home(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
+	uploads, ok := h.formUploads(w, r)
+	if !ok {
+		return
+	}
+	defer closeUploads(uploads)
+
 	title := r.FormValue("title")
 	content := r.FormValue("content")
 	categoryIDStr := r.FormValue("category_id")
@@ -63,18 +289,259 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		categoryID, _ = strconv.Atoi(categoryIDStr)
 	}
 
-	post, err := h.postService.CreatePost(title, content, categoryID)
+	pub, err := formPublication(r)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	user := middleware.CurrentUser(r)
+	tags := service.SplitTags(r.FormValue("tags"))
+	post, err := h.postService.CreatePost(user, title, content, categoryID, tags, pub, uploads)
 	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderPostItem(w, r, post)
+}
+
+// formUploads читает файлы из формы поста; при ошибке сам отвечает на запрос
+func (h *PostHandler) formUploads(w http.ResponseWriter, r *http.Request) ([]service.Upload, bool) {
+	uploads, err := readUploads(w, r, h.attachmentService.MaxSize())
+	if err != nil {
+		if uploadTooLarge(err) {
+			renderError(w, r, h.templates, http.StatusRequestEntityTooLarge, "Файлы слишком большие")
+		} else {
+			renderError(w, r, h.templates, http.StatusBadRequest, "Не удалось прочитать файлы формы")
+		}
+		return nil, false
+	}
+	return uploads, true
+}
+
+// attachUploads прикрепляет к сохранённому посту файлы из формы, уже
+// проверенные AttachmentService.Check; при ошибке сам отвечает на запрос
+func (h *PostHandler) attachUploads(w http.ResponseWriter, r *http.Request, post *models.Post, uploads []service.Upload) bool {
+	if len(uploads) == 0 {
+		return true
+	}
+	attachments, err := h.attachmentService.Attach(middleware.CurrentUser(r), post.ID, uploads)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return false
+	}
+	post.Attachments = append(post.Attachments, attachments...)
+	return true
+}
+
+const (
+	// searchPerPage - результатов поиска на странице
+	searchPerPage = 10
+	// feedPerPage - постов в ленте на одну догрузку
+	feedPerPage = 10
+	// feedComments - последних комментариев под постом в ленте
+	feedComments = 3
+)
+
+// postList - содержимое #posts-list: лента постов или, если задан запрос,
+// страница результатов поиска
+type postList struct {
+	Query      string
+	CategoryID int
+	TagID      int
+	Posts      []postView
+	Results    []searchResultView
+	Page       int
+	Total      int
+	NextURL    string
+}
+
+type searchResultView struct {
+	postView
+	Snippet template.HTML
+}
+
+// Search отдаёт фрагмент #posts-list для строки поиска на главной
+func (h *PostHandler) Search(w http.ResponseWriter, r *http.Request) {
+	list, err := h.postList(r, feedScope(r))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "post_list.html", list); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// postList читает q, page и cursor из запроса и загружает ленту постов scope
+// (keyset пагинация по cursor) или результаты поиска в ней (по номеру страницы)
+func (h *PostHandler) postList(r *http.Request, scope repository.PostFilter) (*postList, error) {
+	viewer := middleware.CurrentUser(r)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	list := &postList{Query: query, CategoryID: scope.CategoryID, TagID: scope.TagID, Page: page}
+
+	if query == "" {
+		cursor, err := repository.ParseCursor(r.URL.Query().Get("cursor"))
+		if err != nil {
+			return nil, &service.ValidationError{Field: "cursor", Message: "Неверная ссылка на следующую страницу"}
+		}
+
+		filter := scope
+		filter.Before, filter.Limit = cursor, feedPerPage
+		feed, err := h.postService.ListPosts(filter)
+		if err != nil {
+			return nil, err
+		}
+		posts := make([]*models.Post, len(feed.Posts))
+		for i := range feed.Posts {
+			posts[i] = &feed.Posts[i]
+		}
+		if err := h.postService.LoadLatestComments(feedComments, posts...); err != nil {
+			return nil, err
+		}
+		if err := h.loadViewerState(r, posts...); err != nil {
+			return nil, err
+		}
+
+		list.Posts = h.newPostViews(feed.Posts, viewer)
+		if feed.Next != nil {
+			next := url.Values{"cursor": {feed.Next.String()}}
+			list.NextURL = "/search?" + list.withScope(next).Encode()
+		}
+		return list, nil
+	}
+
+	filter := scope
+	filter.Limit, filter.Offset = searchPerPage, (page-1)*searchPerPage
+	results, total, err := h.postService.Search(query, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Total = total
+	posts := make([]*models.Post, len(results))
+	for i := range results {
+		posts[i] = &results[i].Post
+	}
+	if err := h.loadViewerState(r, posts...); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		list.Results = append(list.Results, searchResultView{
+			postView: h.newPostView(&results[i].Post, viewer),
+			Snippet:  results[i].Snippet,
+		})
+	}
+	if page*searchPerPage < total {
+		next := url.Values{"q": {query}, "page": {strconv.Itoa(page + 1)}}
+		list.NextURL = "/search?" + list.withScope(next).Encode()
+	}
+
+	return list, nil
+}
+
+// withScope добавляет к параметрам ссылки категорию и тег ленты
+func (l *postList) withScope(values url.Values) url.Values {
+	if l.CategoryID > 0 {
+		values.Set("category_id", strconv.Itoa(l.CategoryID))
+	}
+	if l.TagID > 0 {
+		values.Set("tag_id", strconv.Itoa(l.TagID))
+	}
+	return values
+}
+
+// TagSuggest подсказывает теги для поля tags формы поста по мере ввода
+func (h *PostHandler) TagSuggest(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.tagService.Suggest(r.URL.Query().Get("tags"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	err = h.templates.ExecuteTemplate(w, "post_item.html", post)
+	if err := h.templates.ExecuteTemplate(w, "tag_suggest.html", tags); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Comments отдаёт комментарии, написанные раньше курсора before, для кнопки
+// "Показать более ранние" под постом
+func (h *PostHandler) Comments(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID поста", http.StatusBadRequest)
+		return
+	}
+	before, err := repository.ParseCursor(r.URL.Query().Get("before"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверная ссылка на комментарии")
+		return
+	}
+
+	page, err := h.postService.ListComments(middleware.CurrentUser(r), postID, before, service.CommentsPerPage)
 	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	comments := page.Chronological()
+	pointers := make([]*models.Comment, len(comments))
+	for i := range comments {
+		pointers[i] = &comments[i]
+	}
+	if err := h.reactionService.LoadComments(liker(r), pointers...); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	data := struct {
+		PostID   int
+		Comments []commentView
+		Older    string
+	}{
+		PostID:   postID,
+		Comments: h.newCommentViews(comments, middleware.CurrentUser(r)),
+	}
+	if page.Next != nil {
+		data.Older = page.Next.String()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "comment_page.html", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// maxPreviewSize ограничивает размер текста для предпросмотра
+const maxPreviewSize = 64 << 10
+
+// Preview рендерит Markdown из формы создания поста, пока автор печатает
+func (h *PostHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPreviewSize)
+	if err := r.ParseForm(); err != nil {
+		renderError(w, r, h.templates, http.StatusRequestEntityTooLarge, "Текст слишком большой для предпросмотра")
+		return
+	}
+
+	html, err := h.postService.PreviewMarkdown(r.FormValue("content"))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
 		return
 	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "post_preview.html", html); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
 func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
@@ -85,21 +552,24 @@ func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postService.DeletePost(id)
+	err = h.postService.DeletePost(middleware.CurrentUser(r), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handleServiceError(w, r, h.templates, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// AddComment добавляет комментарий к посту или, если в форме есть parent_id,
+// ответ на комментарий
 func (h *PostHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 	postIDStr := r.FormValue("post_id")
 	author := r.FormValue("author")
 	content := r.FormValue("content")
+	user := middleware.CurrentUser(r)
 
-	if author == "" || content == "" || postIDStr == "" {
+	if (user == nil && author == "") || content == "" || postIDStr == "" {
 		http.Error(w, "Все поля обязательны", http.StatusBadRequest)
 		return
 	}
@@ -110,21 +580,113 @@ func (h *PostHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comment, err := h.postService.AddComment(postID, author, content)
+	var parentID *int
+	if parentIDStr := r.FormValue("parent_id"); parentIDStr != "" {
+		id, err := strconv.Atoi(parentIDStr)
+		if err != nil {
+			http.Error(w, "Неверный ID комментария", http.StatusBadRequest)
+			return
+		}
+		parentID = &id
+	}
+
+	source := spam.Source{
+		IP:        clientIP(r),
+		Form:      true,
+		Honeypot:  r.FormValue(spam.HoneypotField),
+		FormToken: r.FormValue(spam.FormTokenField),
+	}
+	comment, err := h.postService.AddComment(postID, parentID, user, author, content, source)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handleServiceError(w, r, h.templates, err)
 		return
 	}
+	// Форма остаётся на странице: следующий комментарий из неё - с новым токеном
+	w.Header().Set(spam.FormTokenHeader, h.postService.CommentFormToken(postID))
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	err = h.templates.ExecuteTemplate(w, "comment_item.html", comment)
+	// Комментарий на премодерации или в спаме не показываем, только сообщаем, что он принят
+	if comment.Status != models.CommentApproved {
+		h.renderComment(w, r, "comment_pending.html", h.newCommentView(comment, user))
+		return
+	}
+	h.renderComment(w, r, "comment_item.html", h.newCommentView(comment, user))
+}
+
+// clientIP - адрес клиента без порта
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Replies отдаёт ветку ответов на комментарий - развёрнутую или, с collapsed=true,
+// свёрнутую до кнопки "Показать ответы"
+func (h *PostHandler) Replies(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID комментария", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.postService.GetCommentThread(middleware.CurrentUser(r), id)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	view := h.newCommentView(comment, middleware.CurrentUser(r))
+	view.Collapsed = r.URL.Query().Get("collapsed") == "true"
+	h.renderComment(w, r, "comment_replies.html", view)
+}
+
+// DeleteComment удаляет комментарий. Если он остался надгробием, в ответе его
+// новая разметка; если удалён полностью - пустой ответ, а HX-Retarget указывает
+// верхний удалённый комментарий, чтобы вместе с ним исчезли опустевшие надгробия.
+func (h *PostHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID комментария", http.StatusBadRequest)
+		return
+	}
+
+	user := middleware.CurrentUser(r)
+	deletion, err := h.postService.DeleteComment(user, id)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	if !deletion.Tombstoned {
+		w.Header().Set("HX-Retarget", fmt.Sprintf("#comment-%d", deletion.RemovedID))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	comment, err := h.postService.GetCommentThread(user, id)
 	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+	h.renderComment(w, r, "comment_item.html", h.newCommentView(comment, user))
+}
+
+func (h *PostHandler) renderComment(w http.ResponseWriter, r *http.Request, name string, view commentView) {
+	if err := h.reactionService.LoadComments(liker(r), view.Comment); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, name, view); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
-func (h *PostHandler) AddLike(w http.ResponseWriter, r *http.Request) {
+// ToggleLike ставит или снимает лайк и отдаёт кнопку лайка в новом состоянии.
+// Гостям выдаётся cookie посетителя (middleware.Visitors.Require).
+func (h *PostHandler) ToggleLike(w http.ResponseWriter, r *http.Request) {
 	postIDStr := r.FormValue("post_id")
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
@@ -132,19 +694,335 @@ func (h *PostHandler) AddLike(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.postService.AddLike(postID)
+	liked, err := h.postService.ToggleLike(postID, liker(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handleServiceError(w, r, h.templates, err)
 		return
 	}
 
 	// Получаем обновленное количество лайков
-	post, err := h.postService.GetPostByID(postID)
+	post, err := h.postService.GetPostByID(middleware.CurrentUser(r), postID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	post.Liked = liked
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "like_button.html", post); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ToggleReaction ставит или снимает реакцию (поля target, id, emoji)
+// и отдаёт панель реакций цели в новом состоянии
+func (h *PostHandler) ToggleReaction(w http.ResponseWriter, r *http.Request) {
+	target := models.ReactionTarget(r.FormValue("target"))
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	counts, err := h.reactionService.Toggle(target, id, r.FormValue("emoji"), liker(r))
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	bar := models.ReactionBar{Target: target, ID: id, Counts: counts}
+	if err := h.templates.ExecuteTemplate(w, "reactions.html", bar); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// loadViewerState отмечает лайки и реакции того, кто смотрит посты,
+// и загружает счётчики реакций постов и их комментариев
+func (h *PostHandler) loadViewerState(r *http.Request, posts ...*models.Post) error {
+	if err := h.postService.LoadLiked(liker(r), posts...); err != nil {
+		return err
+	}
+	return h.reactionService.LoadPosts(liker(r), posts...)
+}
+
+// liker - владелец лайков текущего запроса: пользователь или посетитель из cookie
+func liker(r *http.Request) repository.Liker {
+	if user := middleware.CurrentUser(r); user != nil {
+		return repository.Liker{UserID: user.ID}
+	}
+	return repository.Liker{VisitorID: middleware.VisitorID(r)}
+}
+
+// PostItem отдаёт карточку поста (например, при отмене редактирования)
+func (h *PostHandler) PostItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID поста", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.postService.GetPostByID(middleware.CurrentUser(r), id)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderPostItem(w, r, post)
+}
+
+// EditForm отдаёт форму редактирования, которая заменяет карточку поста
+func (h *PostHandler) EditForm(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID поста", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.postService.GetPostForEdit(middleware.CurrentUser(r), id)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	categories, _ := h.postService.GetCategories()
+
+	data := struct {
+		Post         *models.Post
+		Categories   []models.Category
+		Statuses     []models.PostStatus
+		UploadLimits string
+	}{
+		Post:         post,
+		Categories:   categories,
+		Statuses:     models.PostStatuses,
+		UploadLimits: h.attachmentService.Limits(),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = h.templates.ExecuteTemplate(w, "post_edit.html", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID поста", http.StatusBadRequest)
+		return
+	}
+	uploads, ok := h.formUploads(w, r)
+	if !ok {
+		return
+	}
+	defer closeUploads(uploads)
+
+	title := r.FormValue("title")
+	content := r.FormValue("content")
+	if title == "" || content == "" {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Заголовок и содержание обязательны")
+		return
+	}
+
+	categoryID, err := strconv.Atoi(r.FormValue("category_id"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверная категория")
+		return
+	}
+	revision, err := strconv.Atoi(r.FormValue("revision"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверная ревизия")
+		return
+	}
+
+	pub, err := formPublication(r)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	if err := h.attachmentService.Check(id, uploads); err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	user := middleware.CurrentUser(r)
+	tags := service.SplitTags(r.FormValue("tags"))
+	post, err := h.postService.UpdatePost(user, id, revision, title, content, categoryID, tags, &pub)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+	if !h.attachUploads(w, r, post, uploads) {
+		return
+	}
+
+	h.renderPostItem(w, r, post)
+}
+
+// publishedAtLayout - формат поля datetime-local; время в формах - UTC,
+// как и везде на страницах блога
+const publishedAtLayout = "2006-01-02T15:04"
+
+// formPublication читает из формы состояние (status) и время публикации
+// (published_at); пустые поля - "опубликовать сейчас" или "оставить дату"
+func formPublication(r *http.Request) (service.Publication, error) {
+	pub := service.Publication{Status: models.PostStatus(r.FormValue("status"))}
+	if v := r.FormValue("published_at"); v != "" {
+		at, err := time.ParseInLocation(publishedAtLayout, v, time.UTC)
+		if err != nil {
+			return pub, &service.ValidationError{Field: "published_at", Message: "Неверное время публикации"}
+		}
+		pub.At = &at
+	}
+	return pub, nil
+}
+
+// draftsPerPage - постов на странице черновиков
+const draftsPerPage = 20
+
+// Drafts - страница неопубликованных постов /drafts?status=draft|scheduled|archived:
+// автор видит свои, редактор и администратор - всех авторов
+func (h *PostHandler) Drafts(w http.ResponseWriter, r *http.Request) {
+	status := models.PostStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		status = models.PostDraft
+	}
+	cursor, err := repository.ParseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Неверная ссылка на следующую страницу")
+		return
+	}
+
+	user := middleware.CurrentUser(r)
+	filter := repository.PostFilter{Status: status, Before: cursor, Limit: draftsPerPage}
+	result, err := h.postService.ListPostsByStatus(user, filter)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+	posts := make([]*models.Post, len(result.Posts))
+	for i := range result.Posts {
+		posts[i] = &result.Posts[i]
+	}
+	if err := h.loadViewerState(r, posts...); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		User     *models.User
+		Status   models.PostStatus
+		Statuses []models.PostStatus
+		Posts    []postView
+		NextURL  string
+	}{
+		User:     user,
+		Status:   status,
+		Statuses: []models.PostStatus{models.PostDraft, models.PostScheduled, models.PostArchived},
+		Posts:    h.newPostViews(result.Posts, user),
+	}
+	if result.Next != nil {
+		data.NextURL = "/drafts?" + url.Values{"status": {string(status)}, "cursor": {result.Next.String()}}.Encode()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "drafts.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *PostHandler) Revisions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID поста", http.StatusBadRequest)
+		return
+	}
+
+	post, revisions, err := h.postService.GetRevisions(middleware.CurrentUser(r), id)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	data := struct {
+		Post      *models.Post
+		Revisions []models.PostRevision
+	}{
+		Post:      post,
+		Revisions: revisions,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = h.templates.ExecuteTemplate(w, "post_revisions.html", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *PostHandler) RevisionDiff(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID поста", http.StatusBadRequest)
+		return
+	}
+
+	from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+	to, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+	if errFrom != nil || errTo != nil {
+		renderError(w, r, h.templates, http.StatusBadRequest, "Укажите номера ревизий для сравнения")
+		return
+	}
+
+	d, err := h.postService.DiffRevisions(middleware.CurrentUser(r), id, from, to)
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = h.templates.ExecuteTemplate(w, "post_diff.html", d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *PostHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Неверный ID поста", http.StatusBadRequest)
+		return
+	}
+	revision, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil {
+		http.Error(w, "Неверный номер ревизии", http.StatusBadRequest)
+		return
+	}
+
+	user := middleware.CurrentUser(r)
+	post, err := h.postService.RestoreRevision(user, id, revision)
 	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	h.renderPostItem(w, r, post)
+}
+
+func (h *PostHandler) renderPostItem(w http.ResponseWriter, r *http.Request, post *models.Post) {
+	if err := h.loadViewerState(r, post); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprintf(w, "%d", post.LikesCount)
+	err := h.templates.ExecuteTemplate(w, "post_item.html", h.newPostView(post, middleware.CurrentUser(r)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
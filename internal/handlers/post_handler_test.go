@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+// Страница поста открывается только по текущей постоянной ссылке, остальные
+// адреса с его ID перенаправляются на неё с кодом 301
+func TestPostPagePermalink(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		h := NewPostHandler(f.postService, f.reactionService, f.tagService, nil, f.templates, false)
+		id := f.post("Привет, мир!", models.PostPublished, time.Now())
+		draft := f.post("Черновик", models.PostDraft, time.Time{})
+		untitled := f.post("!!!", models.PostPublished, time.Now())
+		prefix := "/posts/" + strconv.Itoa(id)
+
+		checkPermalinks(t, h, []redirectCase{
+			{"постоянная ссылка", prefix + "-privet-mir", http.StatusOK, ""},
+			{"без адреса", prefix, http.StatusMovedPermanently, prefix + "-privet-mir"},
+			{"чужой адрес", prefix + "-drugoy", http.StatusMovedPermanently, prefix + "-privet-mir"},
+			{"с запросом", prefix + "?page=2", http.StatusMovedPermanently, prefix + "-privet-mir?page=2"},
+			{"заголовок без букв", "/posts/" + strconv.Itoa(untitled), http.StatusOK, ""},
+			{"черновик", "/posts/" + strconv.Itoa(draft) + "-chernovik", http.StatusNotFound, ""},
+			{"нет поста", "/posts/100500", http.StatusNotFound, ""},
+			{"не ID", "/posts/privet-mir", http.StatusNotFound, ""},
+		})
+
+		// После смены заголовка старая ссылка ведёт на новую
+		f.rename(id, "Новый заголовок")
+		checkPermalinks(t, h, []redirectCase{
+			{"новая ссылка", prefix + "-novyy-zagolovok", http.StatusOK, ""},
+			{"старая ссылка", prefix + "-privet-mir", http.StatusMovedPermanently, prefix + "-novyy-zagolovok"},
+			{"без адреса", prefix, http.StatusMovedPermanently, prefix + "-novyy-zagolovok"},
+		})
+	})
+}
+
+type redirectCase struct {
+	name, target string
+	status       int
+	location     string
+}
+
+func checkPermalinks(t *testing.T, h *PostHandler, tests []redirectCase) {
+	t.Helper()
+	for _, tt := range tests {
+		rec := get("/posts/{id}", h.PostPage, tt.target, nil)
+		if rec.Code != tt.status || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, rec.Code, rec.Header().Get("Location"), tt.status, tt.location)
+		}
+	}
+}
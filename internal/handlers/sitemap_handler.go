@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s.usynin/testing/go-server/internal/service"
+	"github.com/s.usynin/testing/go-server/internal/sitemap"
+)
+
+// DefaultRobots - правила robots.txt по умолчанию: закрыты служебные страницы,
+// HTMX фрагменты поиска и API
+const DefaultRobots = `User-agent: *
+Disallow: /admin/
+Disallow: /api/
+Disallow: /drafts
+Disallow: /notifications
+Disallow: /search
+Disallow: /login
+Disallow: /signup
+`
+
+// SitemapHandler отдаёт карту сайта для поисковиков и robots.txt
+type SitemapHandler struct {
+	sitemap   *service.Sitemap
+	templates *template.Template
+	// baseURL - адрес блога для абсолютных ссылок; пустой - карта сайта отключена
+	baseURL string
+	// robots - правила robots.txt без строки Sitemap
+	robots string
+}
+
+func NewSitemapHandler(sitemap *service.Sitemap, templates *template.Template, baseURL, robots string) *SitemapHandler {
+	return &SitemapHandler{
+		sitemap:   sitemap,
+		templates: templates,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		robots:    robots,
+	}
+}
+
+// Sitemap - /sitemap.xml: список адресов или, если их больше sitemap.MaxURLs,
+// индекс карт /sitemap-{n}.xml
+func (h *SitemapHandler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	if h.baseURL == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	urls, err := h.sitemap.URLs()
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	if len(urls) <= sitemap.MaxURLs {
+		h.serve(w, r, sitemap.URLSet, absoluteURLs(h.baseURL, urls))
+		return
+	}
+	h.serve(w, r, sitemap.Index, chunkIndex(h.baseURL, urls))
+}
+
+// Chunk - /sitemap-{n}.xml: n-я (с 1) часть карты сайта из индекса
+func (h *SitemapHandler) Chunk(w http.ResponseWriter, r *http.Request) {
+	if h.baseURL == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	urls, err := h.sitemap.URLs()
+	if err != nil {
+		handleServiceError(w, r, h.templates, err)
+		return
+	}
+
+	n, _ := strconv.Atoi(chi.URLParam(r, "n"))
+	if n < 1 || n > sitemap.Chunks(len(urls)) {
+		http.NotFound(w, r)
+		return
+	}
+	h.serve(w, r, sitemap.URLSet, absoluteURLs(h.baseURL, chunk(urls, n)))
+}
+
+// Robots - /robots.txt: правила из ROBOTS_FILE (или DefaultRobots) и адрес
+// карты сайта, если задан BASE_URL
+func (h *SitemapHandler) Robots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rules := strings.TrimRight(h.robots, "\n")
+	if h.baseURL == "" {
+		w.Write([]byte(rules + "\n"))
+		return
+	}
+	if rules != "" {
+		rules += "\n\n"
+	}
+	w.Write([]byte(rules + "Sitemap: " + h.baseURL + "/sitemap.xml\n"))
+}
+
+// serve отдаёт карту или индекс с Last-Modified самой свежей страницы
+func (h *SitemapHandler) serve(w http.ResponseWriter, r *http.Request, build func([]sitemap.URL) ([]byte, error), urls []sitemap.URL) {
+	body, err := build(urls)
+	if err != nil {
+		log.Printf("Ошибка построения карты сайта: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var lastMod time.Time
+	for _, u := range urls {
+		if u.LastMod.After(lastMod) {
+			lastMod = u.LastMod
+		}
+	}
+	w.Header().Set("Content-Type", sitemap.ContentType)
+	http.ServeContent(w, r, "", lastMod, bytes.NewReader(body))
+}
+
+// chunk - n-я (с 1) часть адресов по sitemap.MaxURLs
+func chunk(urls []service.SitemapURL, n int) []service.SitemapURL {
+	start := (n - 1) * sitemap.MaxURLs
+	return urls[start:min(start+sitemap.MaxURLs, len(urls))]
+}
+
+// chunkIndex - адреса частей карты сайта /sitemap-{n}.xml для индекса, у каждой
+// время изменения самой свежей её страницы
+func chunkIndex(base string, urls []service.SitemapURL) []sitemap.URL {
+	chunks := make([]sitemap.URL, sitemap.Chunks(len(urls)))
+	for i := range chunks {
+		chunks[i].Loc = base + "/sitemap-" + strconv.Itoa(i+1) + ".xml"
+		for _, u := range chunk(urls, i+1) {
+			if u.LastMod.After(chunks[i].LastMod) {
+				chunks[i].LastMod = u.LastMod
+			}
+		}
+	}
+	return chunks
+}
+
+func absoluteURLs(base string, urls []service.SitemapURL) []sitemap.URL {
+	result := make([]sitemap.URL, len(urls))
+	for i, u := range urls {
+		result[i] = sitemap.URL{Loc: base + u.Path, LastMod: u.LastMod}
+	}
+	return result
+}
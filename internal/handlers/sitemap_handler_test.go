@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/service"
+	"github.com/s.usynin/testing/go-server/internal/sitemap"
+)
+
+// Адреса делятся на части по sitemap.MaxURLs; время части в индексе -
+// самая свежая её страница
+func TestSitemapChunks(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	urls := make([]service.SitemapURL, 2*sitemap.MaxURLs+1)
+	for i := range urls {
+		urls[i] = service.SitemapURL{Path: "/posts/" + strconv.Itoa(i), LastMod: start.Add(time.Duration(i%1000) * time.Minute)}
+	}
+	// Самая свежая страница первой части - в её середине, последней - без времени
+	urls[sitemap.MaxURLs/2].LastMod = start.AddDate(0, 1, 0)
+	urls[len(urls)-1].LastMod = time.Time{}
+
+	for n, want := range map[int][2]string{
+		1: {"/posts/0", "/posts/" + strconv.Itoa(sitemap.MaxURLs-1)},
+		2: {"/posts/" + strconv.Itoa(sitemap.MaxURLs), "/posts/" + strconv.Itoa(2*sitemap.MaxURLs-1)},
+		3: {"/posts/" + strconv.Itoa(2*sitemap.MaxURLs), "/posts/" + strconv.Itoa(2*sitemap.MaxURLs)},
+	} {
+		part := chunk(urls, n)
+		if part[0].Path != want[0] || part[len(part)-1].Path != want[1] {
+			t.Errorf("часть %d: %s - %s, want %s - %s", n, part[0].Path, part[len(part)-1].Path, want[0], want[1])
+		}
+	}
+
+	index := chunkIndex("https://blog.example.com", urls)
+	want := []sitemap.URL{
+		{Loc: "https://blog.example.com/sitemap-1.xml", LastMod: start.AddDate(0, 1, 0)},
+		{Loc: "https://blog.example.com/sitemap-2.xml", LastMod: start.Add(999 * time.Minute)},
+		{Loc: "https://blog.example.com/sitemap-3.xml"},
+	}
+	if len(index) != len(want) {
+		t.Fatalf("индекс из %d частей, want %d", len(index), len(want))
+	}
+	for i := range want {
+		if index[i].Loc != want[i].Loc || !index[i].LastMod.Equal(want[i].LastMod) {
+			t.Errorf("часть %d: %+v, want %+v", i+1, index[i], want[i])
+		}
+	}
+}
+
+// Пока адресов не больше sitemap.MaxURLs, /sitemap.xml - сама карта,
+// а из частей есть только первая
+func TestSitemapHandler(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		h := NewSitemapHandler(f.sitemap, f.templates, "https://blog.example.com/", DefaultRobots)
+		id := f.post("Привет, мир!", models.PostPublished, time.Now())
+		f.post("Черновик", models.PostDraft, time.Time{})
+
+		rec := get("/sitemap.xml", h.Sitemap, "/sitemap.xml", nil)
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != sitemap.ContentType {
+			t.Fatalf("/sitemap.xml: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(body, "<urlset") || !strings.Contains(body, "<loc>https://blog.example.com/posts/"+strconv.Itoa(id)+"-privet-mir</loc>") {
+			t.Errorf("в карте нет поста:\n%s", body)
+		}
+		if strings.Contains(body, "chernovik") {
+			t.Errorf("в карте черновик:\n%s", body)
+		}
+
+		modified := rec.Header().Get("Last-Modified")
+		if rec := get("/sitemap.xml", h.Sitemap, "/sitemap.xml", http.Header{"If-Modified-Since": {modified}}); rec.Code != http.StatusNotModified {
+			t.Errorf("условный запрос карты: %d", rec.Code)
+		}
+
+		for target, status := range map[string]int{
+			"/sitemap-1.xml": http.StatusOK,
+			"/sitemap-2.xml": http.StatusNotFound,
+			"/sitemap-0.xml": http.StatusNotFound,
+		} {
+			if rec := get("/sitemap-{n:[0-9]+}.xml", h.Chunk, target, nil); rec.Code != status {
+				t.Errorf("%s: %d, want %d", target, rec.Code, status)
+			}
+		}
+
+		rec = get("/robots.txt", h.Robots, "/robots.txt", nil)
+		if want := DefaultRobots + "\nSitemap: https://blog.example.com/sitemap.xml\n"; rec.Body.String() != want {
+			t.Errorf("robots.txt:\n%s\nwant:\n%s", rec.Body, want)
+		}
+
+		// Без адреса блога карты нет, а robots.txt - только правила
+		disabled := NewSitemapHandler(f.sitemap, f.templates, "", DefaultRobots)
+		if rec := get("/sitemap.xml", disabled.Sitemap, "/sitemap.xml", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("карта без адреса блога: %d", rec.Code)
+		}
+		if rec := get("/robots.txt", disabled.Robots, "/robots.txt", nil); rec.Body.String() != DefaultRobots {
+			t.Errorf("robots.txt без адреса блога:\n%s", rec.Body)
+		}
+	})
+}
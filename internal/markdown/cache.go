@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"container/list"
+	"html/template"
+	"sync"
+)
+
+// Cache хранит готовый HTML для последних size ключей.
+// Ключ должен меняться вместе с текстом: для поста это id и номер ревизии,
+// для комментария (он не редактируется) - его id.
+type Cache struct {
+	renderer *Renderer
+	size     int
+
+	mu      sync.Mutex
+	order   *list.List // от недавно использованных к давним
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	html template.HTML
+}
+
+func NewCache(renderer *Renderer, size int) *Cache {
+	return &Cache{
+		renderer: renderer,
+		size:     size,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Render возвращает HTML из кэша или рендерит source и запоминает результат
+func (c *Cache) Render(key, source string) (template.HTML, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		html := elem.Value.(*cacheEntry).html
+		c.mu.Unlock()
+		return html, nil
+	}
+	c.mu.Unlock()
+
+	// Рендер идёт без блокировки: одновременные запросы одного ключа
+	// просто посчитают одно и то же
+	html, err := c.renderer.Render(source)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(&cacheEntry{key: key, html: html})
+		for c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*cacheEntry).key)
+		}
+	}
+
+	return html, nil
+}
+
+// Preview рендерит текст без кэширования - для черновиков из формы
+func (c *Cache) Preview(source string) (template.HTML, error) {
+	return c.renderer.Render(source)
+}
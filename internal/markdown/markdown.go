@@ -0,0 +1,62 @@
+// Package markdown переводит Markdown (CommonMark + GFM) постов и комментариев
+// в безопасный HTML с подсветкой кода
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+)
+
+// highlightStyle - стиль chroma для блоков кода. Цвета пишутся в атрибут style,
+// поэтому отдельный CSS файл не нужен.
+const highlightStyle = "github"
+
+// Renderer переводит Markdown в HTML и очищает результат.
+// Сырой HTML из текста не выводится, а всё, что осталось после goldmark,
+// дополнительно проходит через белый список тегов bluemonday.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+func NewRenderer() *Renderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM, // таблицы, зачёркивание, автоссылки, списки задач
+			highlighting.NewHighlighting(highlighting.WithStyle(highlightStyle)),
+		),
+	)
+
+	return &Renderer{md: md, policy: newPolicy()}
+}
+
+// Render возвращает HTML, который можно вставлять в шаблон без экранирования
+func (r *Renderer) Render(source string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := r.md.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return template.HTML(r.policy.SanitizeBytes(buf.Bytes())), nil
+}
+
+// newPolicy - политика для пользовательского контента плюс то, что выводят
+// подсветка кода (цвета в style) и списки задач GFM (отключённые чекбоксы)
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration").
+		OnElements("span", "pre")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	return p
+}
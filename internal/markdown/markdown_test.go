@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string
+		notWant []string
+	}{
+		{"разметка", "# Заголовок\n\n**жирный** и ~~зачёркнутый~~",
+			[]string{"<h1", "Заголовок</h1>", "<strong>жирный</strong>", "<del>зачёркнутый</del>"}, nil},
+		{"таблица", "| a | b |\n|---|---|\n| 1 | 2 |",
+			[]string{"<table>", "<td>1</td>"}, nil},
+		{"список задач", "- [x] готово\n- [ ] нет",
+			[]string{`checked=""`, `disabled=""`, `type="checkbox"`}, nil},
+		{"подсветка кода", "```go\nfunc main() {}\n```",
+			[]string{"<pre", `style="color:`, "main"}, nil},
+		{"автоссылка", "https://example.com",
+			[]string{`<a href="https://example.com"`, `rel="nofollow"`}, nil},
+		{"сырой HTML", "<script>alert(1)</script>\n\nтекст <b onclick=\"x()\">жирный</b>",
+			[]string{"текст"}, []string{"<script", "alert(1)", "onclick", "<b"}},
+		{"javascript: в ссылке", "[нажми](javascript:alert(1))",
+			[]string{"нажми"}, []string{"javascript:"}},
+		{"javascript: в картинке", "![x](javascript:alert(1))",
+			nil, []string{"javascript:"}},
+	}
+
+	renderer := NewRenderer()
+	for _, tt := range tests {
+		html, err := renderer.Render(tt.source)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(string(html), s) {
+				t.Errorf("%s: нет %q в\n%s", tt.name, s, html)
+			}
+		}
+		for _, s := range tt.notWant {
+			if strings.Contains(string(html), s) {
+				t.Errorf("%s: остался %q в\n%s", tt.name, s, html)
+			}
+		}
+	}
+}
+
+// Политика очищает и HTML, который мог бы пропустить goldmark
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"script", `<p>a<script>alert(1)</script>b</p>`, `<p>ab</p>`},
+		{"javascript: в href", `<a href="javascript:alert(1)">x</a>`, `x`},
+		{"обработчик события", `<img src="/a.png" onerror="alert(1)">`, `<img src="/a.png">`},
+		{"iframe", `<iframe src="https://evil.example"></iframe>`, ``},
+		{"style вне кода", `<p style="position:fixed">x</p>`, `<p>x</p>`},
+		{"style подсветки", `<span style="color: #d73a49">func</span>`, `<span style="color: #d73a49">func</span>`},
+		{"класс языка", `<code class="language-go">x</code>`, `<code class="language-go">x</code>`},
+		{"чужой класс", `<code class="evil">x</code>`, `<code>x</code>`},
+		{"не чекбокс", `<input type="text" value="x">`, ``},
+	}
+
+	policy := newPolicy()
+	for _, tt := range tests {
+		if got := policy.Sanitize(tt.html); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(NewRenderer(), 2)
+
+	first, err := cache.Render("post:1:1", "первый")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Тот же ключ отдаётся из кэша, даже если текст другой
+	if html, _ := cache.Render("post:1:1", "другой"); html != first {
+		t.Errorf("ключ не взят из кэша: %s", html)
+	}
+
+	cache.Render("post:2:1", "второй")
+	cache.Render("post:1:1", "первый") // post:1:1 снова недавний
+	cache.Render("post:3:1", "третий") // вытесняет post:2:1
+
+	if _, ok := cache.entries["post:2:1"]; ok || len(cache.entries) != 2 {
+		t.Errorf("после вытеснения в кэше: %v", cache.entries)
+	}
+	if html, _ := cache.Render("post:1:1", "новый"); html != first {
+		t.Errorf("недавний ключ вытеснен: %s", html)
+	}
+}
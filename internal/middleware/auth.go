@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+// SessionCookieName - имя cookie с токеном сессии
+const SessionCookieName = "session"
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// SessionMiddleware загружает пользователя по токену сессии и кладёт его в контекст запроса
+func SessionMiddleware(authService *service.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token := SessionToken(r); token != "" {
+				if user, err := authService.UserBySession(token); err == nil {
+					r = r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SessionToken возвращает токен сессии из заголовка Authorization: Bearer
+// (для API клиентов) или из cookie (для браузера)
+func SessionToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// RequireUser пропускает только вошедших пользователей.
+// Обычные запросы перенаправляются на /login, HTMX запросы получают HX-Redirect.
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if CurrentUser(r) == nil {
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", "/login")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CurrentUser возвращает вошедшего пользователя или nil для гостя
+func CurrentUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userContextKey).(*models.User)
+	return user
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+)
+
+// MaxBodySize ограничивает тело запроса limit байтами, а тело multipart формы
+// с файлами - uploadLimit. Запрос с большим Content-Length сразу получает 413,
+// тело без длины обрывается на пределе: handler получает *http.MaxBytesError,
+// а соединение закрывается после ответа.
+func MaxBodySize(limit, uploadLimit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			size := limit
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+				size = uploadLimit
+			}
+
+			if r.ContentLength > size {
+				w.Header().Set("Connection", "close")
+				http.Error(w, "Слишком большой запрос", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, size)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// VisitorCookieName - имя cookie анонимного посетителя
+const VisitorCookieName = "visitor"
+
+const visitorContextKey contextKey = "visitor"
+
+// visitorCookieMaxAge - срок жизни cookie посетителя, год
+const visitorCookieMaxAge = 365 * 24 * 60 * 60
+
+// Visitors узнаёт анонимных посетителей по cookie "<id>.<подпись>". Подпись
+// не даёт подобрать чужой id или выдумать новый без запроса к серверу.
+// Cookie выдаётся только при открытии страницы, поэтому лайк или реакцию
+// нельзя поставить повторно, просто отправив запрос без cookie.
+type Visitors struct {
+	secret []byte
+	secure bool
+}
+
+// NewVisitors создаёт Visitors с ключом подписи secret; пустой ключ не принимается,
+// иначе после перезапуска все посетители получили бы новые id.
+// secure включает флаг Secure у cookie (для HTTPS).
+func NewVisitors(secret string, secure bool) (*Visitors, error) {
+	if secret == "" {
+		return nil, errors.New("не задан ключ подписи cookie посетителей")
+	}
+	return &Visitors{secret: []byte(secret), secure: secure}, nil
+}
+
+// Load кладёт в контекст id посетителя из cookie, если подпись верна.
+// Гостю без cookie, открывшему страницу (GET с Accept: text/html), выдаёт
+// новый id. Должен стоять после SessionMiddleware.
+func (v *Visitors) Load(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := ""
+		if cookie, err := r.Cookie(VisitorCookieName); err == nil {
+			id, _ = v.verify(cookie.Value)
+		}
+		if id == "" && CurrentUser(r) == nil && isPageRequest(r) {
+			var err error
+			if id, err = v.issue(w); err != nil {
+				http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+				return
+			}
+		}
+		if id != "" {
+			r = r.WithContext(context.WithValue(r.Context(), visitorContextKey, id))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Require пропускает вошедших пользователей и гостей с cookie посетителя;
+// остальным отвечает 403. Должен стоять после Load.
+func (v *Visitors) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if CurrentUser(r) == nil && VisitorID(r) == "" {
+			http.Error(w, "Обновите страницу, чтобы оценить пост", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// issue выдаёт новый id посетителя в cookie
+func (v *Visitors) issue(w http.ResponseWriter) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(raw)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     VisitorCookieName,
+		Value:    id + "." + v.sign(id),
+		Path:     "/",
+		MaxAge:   visitorCookieMaxAge,
+		HttpOnly: true,
+		Secure:   v.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id, nil
+}
+
+// isPageRequest - браузер открывает страницу, а не загружает фрагмент или файл
+func isPageRequest(r *http.Request) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		r.Header.Get("HX-Request") == "" &&
+		strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+func (v *Visitors) sign(id string) string {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (v *Visitors) verify(value string) (string, bool) {
+	id, signature, ok := strings.Cut(value, ".")
+	if !ok || id == "" || !hmac.Equal([]byte(signature), []byte(v.sign(id))) {
+		return "", false
+	}
+	return id, true
+}
+
+// VisitorID возвращает id анонимного посетителя или пустую строку
+func VisitorID(r *http.Request) string {
+	id, _ := r.Context().Value(visitorContextKey).(string)
+	return id
+}
@@ -1,6 +1,14 @@
 package models
 
-import "time"
+import (
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/slug"
+)
 
 // Post представляет блог-пост
 type Post struct {
@@ -8,14 +16,57 @@ type Post struct {
 	Title      string    `json:"title" db:"title"`
 	Content    string    `json:"content" db:"content"`
 	CategoryID int       `json:"category_id" db:"category_id"`
+	UserID     *int      `json:"user_id,omitempty" db:"user_id"`
+	Revision   int       `json:"revision" db:"revision"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	// Status - черновик, запланирован, опубликован или в архиве
+	Status PostStatus `json:"status" db:"status"`
+	// PublishedAt - время публикации, у запланированного поста - назначенное;
+	// nil у черновиков
+	PublishedAt *time.Time `json:"published_at,omitempty" db:"published_at"`
+
+	// ContentHTML - Content, переведённый из Markdown в очищенный HTML
+	ContentHTML template.HTML `json:"content_html,omitempty"`
 
 	// Связи (для загрузки)
-	Category      *Category `json:"category,omitempty"`
-	Comments      []Comment `json:"comments,omitempty"`
-	CommentsCount int       `json:"comments_count"`
-	LikesCount    int       `json:"likes_count"`
+	AuthorName    string       `json:"author_name,omitempty"`
+	Category      *Category    `json:"category,omitempty"`
+	Tags          []Tag        `json:"tags,omitempty"`
+	Attachments   []Attachment `json:"attachments,omitempty"`
+	Comments      []Comment    `json:"comments,omitempty"`
+	CommentsCount int          `json:"comments_count"`
+	LikesCount    int          `json:"likes_count"`
+	// Liked - пост лайкнул тот, кто его смотрит
+	Liked bool `json:"liked"`
+	// Reactions - счётчики реакций в порядке набора блога
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+
+	// CommentsCursor - курсор для загрузки комментариев раньше показанных в Comments
+	CommentsCursor string `json:"comments_cursor,omitempty"`
+}
+
+// SearchResult - пост, найденный поиском, с фрагментом текста,
+// в котором совпадения выделены тегом <mark>
+type SearchResult struct {
+	Post
+	Snippet template.HTML `json:"snippet"`
+	Rank    float64       `json:"rank"`
+}
+
+// PostRevision - сохранённая версия поста. Ревизии нумеруются с 1,
+// последняя совпадает с текущим содержимым поста.
+type PostRevision struct {
+	ID         int       `json:"id" db:"id"`
+	PostID     int       `json:"post_id" db:"post_id"`
+	Revision   int       `json:"revision" db:"revision"`
+	Title      string    `json:"title" db:"title"`
+	Content    string    `json:"content" db:"content"`
+	CategoryID int       `json:"category_id" db:"category_id"`
+	EditorID   *int      `json:"editor_id,omitempty" db:"editor_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+
+	EditorName string `json:"editor_name,omitempty"`
 }
 
 // Category представляет категорию поста
@@ -24,20 +75,369 @@ type Category struct {
 	Name      string    `json:"name" db:"name"`
 	Slug      string    `json:"slug" db:"slug"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// CommentModeration - режим премодерации в категории; nil - глобальный
+	CommentModeration *ModerationMode `json:"comment_moderation,omitempty" db:"comment_moderation"`
+	// ParentID - родительская категория; nil - категория верхнего уровня
+	ParentID *int `json:"parent_id,omitempty" db:"parent_id"`
+	// PostsCount - опубликованные посты самой категории, без подкатегорий
+	PostsCount int `json:"posts_count"`
+	// Ancestors - цепочка родителей от верхнего уровня, для хлебных крошек
+	Ancestors []Category `json:"ancestors,omitempty"`
+	// Depth - уровень в дереве категорий, 0 - верхний
+	Depth int `json:"-"`
+}
+
+// ChildOf проверяет, что категория - прямая подкатегория parentID
+func (c Category) ChildOf(parentID int) bool {
+	return c.ParentID != nil && *c.ParentID == parentID
+}
+
+// Indent - отступ названия по уровню категории в выпадающих списках
+func (c Category) Indent() string {
+	return strings.Repeat("— ", c.Depth)
+}
+
+// Tag - свободная метка поста; у поста может быть несколько тегов
+type Tag struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// PostsCount - число опубликованных постов с тегом
+	PostsCount int `json:"posts_count"`
+	// Weight - размер тега в облаке тегов, от 1 до TagWeights
+	Weight int `json:"-"`
+}
+
+// TagWeights - число размеров шрифта в облаке тегов
+const TagWeights = 5
+
+// Attachment - файл, прикреплённый к посту. Содержимое лежит в хранилище
+// файлов под StorageKey, у изображений есть превью под ThumbKey.
+type Attachment struct {
+	ID          int       `json:"id" db:"id"`
+	PostID      int       `json:"post_id" db:"post_id"`
+	UserID      *int      `json:"user_id,omitempty" db:"user_id"`
+	Filename    string    `json:"filename" db:"filename"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	StorageKey  string    `json:"-" db:"storage_key"`
+	ThumbKey    string    `json:"-" db:"thumb_key"`
+	Width       int       `json:"width,omitempty" db:"width"`
+	Height      int       `json:"height,omitempty" db:"height"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+
+	// URL и ThumbnailURL - адреса файла и превью (пустой, если превью нет)
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
 }
 
 // Comment представляет комментарий к посту
 type Comment struct {
 	ID        int       `json:"id" db:"id"`
 	PostID    int       `json:"post_id" db:"post_id"`
+	UserID    *int      `json:"user_id,omitempty" db:"user_id"`
 	Author    string    `json:"author" db:"author"`
 	Content   string    `json:"content" db:"content"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// ParentID - комментарий, на который это ответ; nil у комментариев верхнего уровня
+	ParentID *int `json:"parent_id,omitempty" db:"parent_id"`
+	// DeletedAt заполнен у удалённого комментария, оставленного ради его ответов
+	DeletedAt *time.Time    `json:"deleted_at,omitempty" db:"deleted_at"`
+	Status    CommentStatus `json:"status" db:"status"`
+
+	// Итог спам фильтра и адрес отправителя - только для модераторов.
+	// SpamLabel - чему модератор обучил классификатор этим комментарием.
+	SpamScore   float64    `json:"-" db:"spam_score"`
+	SpamReasons string     `json:"-" db:"spam_reasons"`
+	IP          string     `json:"-" db:"ip"`
+	SpamLabel   *SpamLabel `json:"-" db:"spam_label"`
+
+	ContentHTML template.HTML `json:"content_html,omitempty"`
+
+	// Поля дерева ответов, заполняются service слоем.
+	// Depth - уровень вложенности при показе (0 - верхний уровень);
+	// ReplyTo - автор родителя, если ответ показан не под ним, а выше
+	// из-за ограничения глубины; RepliesCount - число всех ответов в ветке.
+	Depth        int       `json:"depth"`
+	ReplyTo      string    `json:"reply_to,omitempty"`
+	RepliesCount int       `json:"replies_count"`
+	Replies      []Comment `json:"replies,omitempty"`
+	// Reactions - счётчики реакций в порядке набора блога
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+	// AtMaxDepth - комментарий на пределе глубины: ответы на него показываются рядом
+	AtMaxDepth bool `json:"-"`
+}
+
+// Deleted - удалён ли комментарий (остался только как узел ветки)
+func (c *Comment) Deleted() bool {
+	return c.DeletedAt != nil
+}
+
+// Published - пост опубликован: виден всем и открыт для комментариев
+func (p *Post) Published() bool {
+	return p.Status == PostPublished
+}
+
+// Date - дата поста для показа и порядка в списках: время публикации,
+// а у черновиков - время создания
+func (p *Post) Date() time.Time {
+	if p.PublishedAt != nil {
+		return *p.PublishedAt
+	}
+	return p.CreatedAt
+}
+
+// URL - постоянная ссылка на страницу поста: /posts/{id}-{адрес из заголовка}.
+// Адрес нужен только для читаемости: пост находится по ID, поэтому посты
+// с одинаковыми заголовками не конфликтуют, а после смены заголовка старые
+// ссылки перенаправляются на новую.
+func (p *Post) URL() string {
+	path := "/posts/" + strconv.Itoa(p.ID)
+	if postSlug := slug.Make(p.Title); postSlug != "" {
+		path += "-" + postSlug
+	}
+	return path
+}
+
+// TagNames - теги поста через запятую, как их вводят в форме
+func (p *Post) TagNames() string {
+	names := make([]string, len(p.Tags))
+	for i, tag := range p.Tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Images - вложения поста, у которых есть превью, для галереи под текстом
+func (p *Post) Images() []Attachment {
+	var images []Attachment
+	for _, a := range p.Attachments {
+		if a.ThumbnailURL != "" {
+			images = append(images, a)
+		}
+	}
+	return images
+}
+
+// Files - остальные вложения поста, для списка файлов
+func (p *Post) Files() []Attachment {
+	var files []Attachment
+	for _, a := range p.Attachments {
+		if a.ThumbnailURL == "" {
+			files = append(files, a)
+		}
+	}
+	return files
+}
+
+// SetURLs заполняет URL и ThumbnailURL по ID и имени файла
+func (a *Attachment) SetURLs() {
+	name := url.PathEscape(a.Filename)
+	a.URL = "/attachments/" + strconv.Itoa(a.ID) + "/" + name
+	a.ThumbnailURL = ""
+	if a.ThumbKey != "" {
+		a.ThumbnailURL = "/attachments/" + strconv.Itoa(a.ID) + "/thumb/" + name
+	}
+}
+
+// HumanSize - размер файла для показа: 512 Б, 14 КБ, 2.3 МБ
+func (a *Attachment) HumanSize() string {
+	switch {
+	case a.Size < 1<<10:
+		return strconv.FormatInt(a.Size, 10) + " Б"
+	case a.Size < 1<<20:
+		return strconv.FormatInt(a.Size>>10, 10) + " КБ"
+	default:
+		return strconv.FormatFloat(float64(a.Size)/(1<<20), 'f', 1, 64) + " МБ"
+	}
+}
+
+// ReactionBar - данные для шаблона reactions.html
+func (p *Post) ReactionBar() ReactionBar {
+	return ReactionBar{Target: ReactionOnPost, ID: p.ID, Counts: p.Reactions}
+}
+
+// ReactionBar - данные для шаблона reactions.html
+func (c *Comment) ReactionBar() ReactionBar {
+	return ReactionBar{Target: ReactionOnComment, ID: c.ID, Counts: c.Reactions}
+}
+
+// ReactionTarget - на что ставится реакция
+type ReactionTarget string
+
+const (
+	ReactionOnPost    ReactionTarget = "post"
+	ReactionOnComment ReactionTarget = "comment"
+)
+
+// Valid проверяет, что цель реакции известна
+func (t ReactionTarget) Valid() bool {
+	return t == ReactionOnPost || t == ReactionOnComment
 }
 
-// Like представляет лайк к посту
+// ReactionCount - сколько раз поставлена реакция Emoji. Reacted - её поставил
+// тот, кто смотрит; Available - она есть в наборе блога и её можно поставить.
+type ReactionCount struct {
+	Emoji     string `json:"emoji"`
+	Count     int    `json:"count"`
+	Reacted   bool   `json:"reacted"`
+	Available bool   `json:"-"`
+}
+
+// ReactionBar - реакции на пост или комментарий вместе с целью, к которой
+// отправляет запросы панель реакций
+type ReactionBar struct {
+	Target ReactionTarget
+	ID     int
+	Counts []ReactionCount
+}
+
+// Reaction - кто и когда поставил реакцию; у гостя нет UserID и Username
+type Reaction struct {
+	Emoji     string    `json:"emoji"`
+	UserID    *int      `json:"user_id,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Like представляет лайк к посту от пользователя или анонимного посетителя
 type Like struct {
 	ID        int       `json:"id" db:"id"`
 	PostID    int       `json:"post_id" db:"post_id"`
+	UserID    *int      `json:"user_id,omitempty" db:"user_id"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
+
+// User представляет зарегистрированного пользователя
+type User struct {
+	ID           int       `json:"id" db:"id"`
+	Username     string    `json:"username" db:"username"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	Role         Role      `json:"role" db:"role"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Role - роль пользователя, определяет его права
+type Role string
+
+const (
+	RoleReader Role = "reader" // может только комментировать
+	RoleAuthor Role = "author" // пишет посты, правит и удаляет свои
+	RoleEditor Role = "editor" // правит и удаляет любые посты
+	RoleAdmin  Role = "admin"  // управляет категориями и пользователями
+)
+
+// Roles - все роли в порядке возрастания прав
+var Roles = []Role{RoleReader, RoleAuthor, RoleEditor, RoleAdmin}
+
+// Valid проверяет, что роль известна
+func (r Role) Valid() bool {
+	for _, role := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// PostStatus - состояние публикации поста
+type PostStatus string
+
+const (
+	PostDraft     PostStatus = "draft"     // черновик, виден только тем, кто может его править
+	PostScheduled PostStatus = "scheduled" // будет опубликован в published_at
+	PostPublished PostStatus = "published" // в ленте, поиске и счётчиках
+	PostArchived  PostStatus = "archived"  // снят с публикации, но доступен по ссылке
+)
+
+// PostStatuses - все состояния поста
+var PostStatuses = []PostStatus{PostDraft, PostScheduled, PostPublished, PostArchived}
+
+// Valid проверяет, что состояние известно
+func (s PostStatus) Valid() bool {
+	for _, status := range PostStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Title - название состояния для страниц
+func (s PostStatus) Title() string {
+	switch s {
+	case PostDraft:
+		return "Черновик"
+	case PostScheduled:
+		return "Запланирован"
+	case PostArchived:
+		return "В архиве"
+	default:
+		return "Опубликован"
+	}
+}
+
+// CommentStatus - состояние комментария в очереди модерации
+type CommentStatus string
+
+const (
+	CommentPending  CommentStatus = "pending"  // ждёт проверки модератором
+	CommentApproved CommentStatus = "approved" // опубликован
+	CommentRejected CommentStatus = "rejected" // отклонён модератором
+	CommentSpam     CommentStatus = "spam"     // помечен как спам
+)
+
+// CommentStatuses - все состояния комментария
+var CommentStatuses = []CommentStatus{CommentPending, CommentApproved, CommentRejected, CommentSpam}
+
+// Valid проверяет, что состояние известно
+func (s CommentStatus) Valid() bool {
+	for _, status := range CommentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ModerationMode - какие новые комментарии уходят на премодерацию
+type ModerationMode string
+
+const (
+	ModerationOff       ModerationMode = "off"        // все публикуются сразу
+	ModerationFirstTime ModerationMode = "first_time" // первый комментарий пользователя и комментарии гостей
+	ModerationAll       ModerationMode = "all"        // все комментарии
+)
+
+// ModerationModes - все режимы премодерации
+var ModerationModes = []ModerationMode{ModerationOff, ModerationFirstTime, ModerationAll}
+
+// Valid проверяет, что режим известен
+func (m ModerationMode) Valid() bool {
+	for _, mode := range ModerationModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// SpamLabel - метка, которой модератор обучил спам классификатор
+type SpamLabel string
+
+const (
+	SpamLabelSpam SpamLabel = "spam"
+	SpamLabelHam  SpamLabel = "ham" // нормальный комментарий
+)
+
+// Notification - уведомление пользователю, например о комментарии к его посту
+type Notification struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"user_id" db:"user_id"`
+	PostID    *int       `json:"post_id,omitempty" db:"post_id"`
+	CommentID *int       `json:"comment_id,omitempty" db:"comment_id"`
+	Message   string     `json:"message" db:"message"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty" db:"read_at"`
+}
@@ -0,0 +1,186 @@
+// Package openapi описывает HTTP API документом OpenAPI 3, проверяет запросы
+// по его схемам и сверяет документ с маршрутами chi
+package openapi
+
+import (
+	"sort"
+	"strings"
+)
+
+// Version - версия формата OpenAPI
+const Version = "3.0.3"
+
+// Document - корень спецификации OpenAPI
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Tags       []Tag                `json:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem - операции одного пути по HTTP методам в нижнем регистре ("get", "post", ...)
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+}
+
+// New создаёт пустой документ
+func New(title, version, description string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version, Description: description},
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+	}
+}
+
+// Add регистрирует операцию для метода и пути вида /posts/{id}
+func (d *Document) Add(method, path string, op *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// Operations возвращает все пары "МЕТОД путь", описанные в документе, по порядку
+func (d *Document) Operations() []string {
+	var ops []string
+	for path, item := range d.Paths {
+		for method := range *item {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// Find ищет операцию по методу и фактическому пути запроса.
+// Возвращает также значения параметров пути. Литеральные сегменты
+// предпочтительнее параметров: /posts/new выигрывает у /posts/{id}.
+func (d *Document) Find(method, path string) (*Operation, map[string]string) {
+	segments := splitPath(path)
+	method = strings.ToLower(method)
+
+	var (
+		found     *Operation
+		foundVars map[string]string
+		bestScore = -1
+	)
+	for template, item := range d.Paths {
+		op, ok := (*item)[method]
+		if !ok {
+			continue
+		}
+		vars, score, ok := matchPath(splitPath(template), segments)
+		if ok && score > bestScore {
+			found, foundVars, bestScore = op, vars, score
+		}
+	}
+
+	return found, foundVars
+}
+
+// Resolve возвращает схему из components, если s - ссылка $ref
+func (d *Document) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, refPrefix)]
+	}
+	return s
+}
+
+// matchPath сопоставляет сегменты шаблона с сегментами пути.
+// score - число совпавших литеральных сегментов.
+func matchPath(template, segments []string) (map[string]string, int, bool) {
+	if len(template) != len(segments) {
+		return nil, 0, false
+	}
+
+	vars := make(map[string]string)
+	score := 0
+	for i, part := range template {
+		if name, ok := pathParam(part); ok {
+			if segments[i] == "" {
+				return nil, 0, false
+			}
+			vars[name] = segments[i]
+			continue
+		}
+		if part != segments[i] {
+			return nil, 0, false
+		}
+		score++
+	}
+
+	return vars, score, true
+}
+
+func pathParam(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
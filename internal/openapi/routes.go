@@ -0,0 +1,64 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// chiParam - параметр chi с необязательным регулярным выражением: {id} или {id:[0-9]+}
+var chiParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// PathFromChi переводит шаблон маршрута chi в путь OpenAPI:
+// регулярные выражения параметров отбрасываются, "*" в конце становится {path}
+func PathFromChi(pattern string) string {
+	path := chiParam.ReplaceAllString(pattern, "{$1}")
+	if strings.HasSuffix(path, "/*") {
+		path = strings.TrimSuffix(path, "*") + "{path}"
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// CheckRoutes сверяет маршруты роутера с документом. Ошибка перечисляет
+// маршруты без описания и описания без маршрута, так что расхождение
+// обнаруживается при запуске сервера, а не клиентами API.
+func CheckRoutes(doc *Document, routes chi.Routes) error {
+	registered := make(map[string]bool)
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		registered[method+" "+PathFromChi(route)] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	described := make(map[string]bool)
+	for _, op := range doc.Operations() {
+		described[op] = true
+	}
+
+	var problems []string
+	for route := range registered {
+		if !described[route] {
+			problems = append(problems, "нет в спецификации: "+route)
+		}
+	}
+	for op := range described {
+		if !registered[op] {
+			problems = append(problems, "нет в роутере: "+op)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	return fmt.Errorf("маршруты расходятся со спецификацией OpenAPI:\n  %s", strings.Join(problems, "\n  "))
+}
@@ -0,0 +1,58 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPathFromChi(t *testing.T) {
+	tests := map[string]string{
+		"/posts/{id}":                           "/posts/{id}",
+		"/sitemap-{n:[0-9]+}.xml":               "/sitemap-{n}.xml",
+		"/feed.{format:rss|atom|json}":          "/feed.{format}",
+		"/static/*":                             "/static/{path}",
+		"/api/v1/posts/":                        "/api/v1/posts",
+		"/":                                     "/",
+		"/category/{slug}/feed.{format:[a-z]+}": "/category/{slug}/feed.{format}",
+	}
+	for pattern, want := range tests {
+		if got := PathFromChi(pattern); got != want {
+			t.Errorf("PathFromChi(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestCheckRoutes(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	newRouter := func() chi.Router {
+		r := chi.NewRouter()
+		r.Get("/posts/{id:[0-9]+}", noop)
+		api := chi.NewRouter()
+		api.Post("/posts", noop)
+		r.Mount("/api/v1", api)
+		return r
+	}
+
+	doc := New("test", "1", "")
+	doc.Add(http.MethodGet, "/posts/{id}", &Operation{})
+	doc.Add(http.MethodPost, "/api/v1/posts", &Operation{})
+	if err := CheckRoutes(doc, newRouter()); err != nil {
+		t.Fatalf("совпадающие маршруты: %v", err)
+	}
+
+	r := newRouter()
+	r.Delete("/posts/{id}", noop)
+	doc.Add(http.MethodGet, "/tags", &Operation{})
+	err := CheckRoutes(doc, r)
+	if err == nil {
+		t.Fatal("расхождение не обнаружено")
+	}
+	for _, want := range []string{"нет в спецификации: DELETE /posts/{id}", "нет в роутере: GET /tags"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("в ошибке нет %q:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+const refPrefix = "#/components/schemas/"
+
+// Schema - подмножество JSON Schema из OpenAPI 3.0, которого достаточно для API блога
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// Конструкторы схем
+
+// Ref ссылается на схему из components.schemas
+func Ref(name string) *Schema { return &Schema{Ref: refPrefix + name} }
+
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+// DateTime - строка в формате RFC 3339
+func DateTime() *Schema { return &Schema{Type: "string", Format: "date-time"} }
+
+func Array(items *Schema) *Schema { return &Schema{Type: "array", Items: items} }
+
+// Object - объект без дополнительных полей; required перечисляет обязательные свойства
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	closed := false
+	return &Schema{
+		Type:                 "object",
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: &closed,
+	}
+}
+
+// Enum ограничивает значения строки списком
+func Enum(values ...string) *Schema {
+	s := String()
+	for _, v := range values {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+// Length задаёт допустимую длину строки в символах; 0 означает "без ограничения"
+func (s *Schema) Length(min, max int) *Schema {
+	if min > 0 {
+		s.MinLength = &min
+	}
+	if max > 0 {
+		s.MaxLength = &max
+	}
+	return s
+}
+
+// Min задаёт минимальное значение числа
+func (s *Schema) Min(min float64) *Schema {
+	s.Minimum = &min
+	return s
+}
+
+// Max задаёт максимальное значение числа
+func (s *Schema) Max(max float64) *Schema {
+	s.Maximum = &max
+	return s
+}
+
+// Describe добавляет описание
+func (s *Schema) Describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+// AsReadOnly помечает поле, которое сервер заполняет сам
+func (s *Schema) AsReadOnly() *Schema {
+	s.ReadOnly = true
+	return s
+}
+
+// SchemaError - значение не соответствует схеме. Field - путь к полю: "title", "items[2].id".
+type SchemaError struct {
+	Field   string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// Validate проверяет значение, разобранное json.Decoder с UseNumber, по схеме s
+func (d *Document) Validate(s *Schema, value any) error {
+	return d.validate(s, value, "")
+}
+
+func (d *Document) validate(s *Schema, value any, field string) error {
+	s = d.Resolve(s)
+	if s == nil {
+		return nil
+	}
+
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return &SchemaError{field, "значение не может быть null"}
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return &SchemaError{field, "ожидается объект"}
+		}
+		return d.validateObject(s, obj, field)
+
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return &SchemaError{field, "ожидается массив"}
+		}
+		for i, item := range items {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", field, i)); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return &SchemaError{field, "ожидается строка"}
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			return &SchemaError{field, fmt.Sprintf("минимальная длина - %d символов", *s.MinLength)}
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return &SchemaError{field, fmt.Sprintf("максимальная длина - %d символов", *s.MaxLength)}
+		}
+
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return &SchemaError{field, "ожидается число"}
+		}
+		f, err := num.Float64()
+		if err != nil {
+			return &SchemaError{field, "ожидается число"}
+		}
+		if s.Type == "integer" {
+			if _, err := num.Int64(); err != nil {
+				return &SchemaError{field, "ожидается целое число"}
+			}
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return &SchemaError{field, fmt.Sprintf("значение должно быть не меньше %v", *s.Minimum)}
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return &SchemaError{field, fmt.Sprintf("значение должно быть не больше %v", *s.Maximum)}
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return &SchemaError{field, "ожидается true или false"}
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		return &SchemaError{field, fmt.Sprintf("допустимые значения: %v", s.Enum)}
+	}
+
+	return nil
+}
+
+func (d *Document) validateObject(s *Schema, obj map[string]any, field string) error {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return &SchemaError{joinField(field, name), "обязательное поле"}
+		}
+	}
+
+	// Поля проверяются в алфавитном порядке, чтобы ошибка была стабильной
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return &SchemaError{joinField(field, name), "неизвестное поле"}
+			}
+			continue
+		}
+		if prop.ReadOnly {
+			continue
+		}
+		if err := d.validate(prop, obj[name], joinField(field, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseValue переводит строковое значение параметра пути или запроса в тип схемы
+func (d *Document) ParseValue(s *Schema, raw string) (any, bool) {
+	s = d.Resolve(s)
+	if s == nil {
+		return raw, true
+	}
+
+	switch s.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(raw), true
+	case "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(raw), true
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		return b, err == nil
+	}
+	return raw, true
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, v := range enum {
+		if fmt.Sprint(v) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,156 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const jsonContentType = "application/json"
+
+// RequestError - запрос не прошёл проверку по спецификации
+type RequestError struct {
+	Status  int
+	Code    string
+	Field   string
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// ErrorHandler отвечает клиенту на отклонённый запрос
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err *RequestError)
+
+// Validator проверяет параметры пути, параметры запроса и JSON тело по операции
+// из документа. Запросы к путям, которых нет в документе, пропускаются дальше -
+// на них ответит роутер. Тело длиннее maxBodySize отклоняется.
+func Validator(doc *Document, maxBodySize int64, onError ErrorHandler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, pathVars := doc.Find(r.Method, r.URL.Path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := doc.validateParams(op, r, pathVars); err != nil {
+				onError(w, r, err)
+				return
+			}
+
+			if op.RequestBody != nil {
+				if err := doc.validateBody(op.RequestBody, r, maxBodySize); err != nil {
+					onError(w, r, err)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (d *Document) validateParams(op *Operation, r *http.Request, pathVars map[string]string) *RequestError {
+	query := r.URL.Query()
+
+	for _, param := range op.Parameters {
+		var (
+			raw     string
+			present bool
+			code    string
+		)
+		switch param.In {
+		case "path":
+			raw, present = pathVars[param.Name]
+			code = "invalid_parameter"
+		case "query":
+			raw, present = query.Get(param.Name), query.Has(param.Name)
+			code = "invalid_query"
+		default:
+			continue
+		}
+
+		if !present || raw == "" {
+			if param.Required {
+				return &RequestError{http.StatusBadRequest, code, param.Name,
+					fmt.Sprintf("параметр %s обязателен", param.Name)}
+			}
+			continue
+		}
+
+		value, ok := d.ParseValue(param.Schema, raw)
+		if !ok {
+			return &RequestError{http.StatusBadRequest, code, param.Name,
+				fmt.Sprintf("параметр %s: неверное значение %q", param.Name, raw)}
+		}
+		if err := d.Validate(param.Schema, value); err != nil {
+			return &RequestError{http.StatusBadRequest, code, param.Name,
+				fmt.Sprintf("параметр %s: %s", param.Name, err.(*SchemaError).Message)}
+		}
+	}
+
+	return nil
+}
+
+// validateBody проверяет JSON тело и возвращает его в r.Body для handler'а.
+// Тела других типов (формы HTML страниц) не проверяются.
+func (d *Document) validateBody(body *RequestBody, r *http.Request, maxBodySize int64) *RequestError {
+	media, ok := body.Content[jsonContentType]
+	if !ok {
+		return nil
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != jsonContentType {
+			return &RequestError{Status: http.StatusUnsupportedMediaType, Code: "unsupported_media_type",
+				Message: "тело запроса должно быть в формате application/json"}
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	r.Body.Close()
+	if err != nil {
+		return &RequestError{Status: http.StatusBadRequest, Code: "invalid_json",
+			Message: "не удалось прочитать тело запроса"}
+	}
+	if int64(len(data)) > maxBodySize {
+		return &RequestError{Status: http.StatusRequestEntityTooLarge, Code: "request_too_large",
+			Message: fmt.Sprintf("тело запроса больше %d байт", maxBodySize)}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return &RequestError{Status: http.StatusBadRequest, Code: "invalid_json",
+				Message: "тело запроса обязательно"}
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return &RequestError{Status: http.StatusBadRequest, Code: "invalid_json",
+			Message: fmt.Sprintf("некорректный JSON: %v", err)}
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return &RequestError{Status: http.StatusBadRequest, Code: "invalid_json",
+			Message: "тело запроса должно содержать один JSON объект"}
+	}
+
+	if err := d.Validate(media.Schema, value); err != nil {
+		schemaErr := err.(*SchemaError)
+		return &RequestError{Status: http.StatusUnprocessableEntity, Code: "validation_failed",
+			Field: schemaErr.Field, Message: schemaErr.Message}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testDocument - POST /posts/{id}/comments с JSON телом и GET /posts с параметрами запроса
+func testDocument() *Document {
+	doc := New("test", "1", "")
+	doc.Components.Schemas["CommentInput"] = Object(map[string]*Schema{
+		"author":  String().Length(1, 50),
+		"content": String().Length(1, 0),
+	}, "content")
+	doc.Add(http.MethodPost, "/posts/{id}/comments", &Operation{
+		Parameters: []*Parameter{{Name: "id", In: "path", Required: true, Schema: Integer().Min(1)}},
+		RequestBody: &RequestBody{Required: true, Content: map[string]*MediaType{
+			"application/json": {Schema: Ref("CommentInput")},
+		}},
+	})
+	doc.Add(http.MethodGet, "/posts", &Operation{
+		Parameters: []*Parameter{
+			{Name: "page", In: "query", Schema: Integer().Min(1)},
+			{Name: "status", In: "query", Schema: Enum("draft", "published")},
+		},
+	})
+	return doc
+}
+
+// serve пропускает запрос через Validator и возвращает ошибку проверки
+// (nil, если запрос дошёл до handler'а) и тело, которое получил handler
+func serve(t *testing.T, r *http.Request) (*RequestError, string) {
+	t.Helper()
+	var (
+		reqErr *RequestError
+		body   string
+	)
+	onError := func(w http.ResponseWriter, r *http.Request, err *RequestError) {
+		reqErr = err
+		w.WriteHeader(err.Status)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = string(data)
+	})
+
+	Validator(testDocument(), 256, onError)(next).ServeHTTP(httptest.NewRecorder(), r)
+	return reqErr, body
+}
+
+func jsonRequest(path, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return r
+}
+
+func TestValidatorAcceptsValidRequests(t *testing.T) {
+	body := `{"author": "Гость", "content": "Привет"}`
+	reqErr, got := serve(t, jsonRequest("/posts/1/comments", body))
+	if reqErr != nil {
+		t.Fatalf("корректный запрос отклонён: %v", reqErr)
+	}
+	if got != body {
+		t.Errorf("handler получил тело %q, want %q", got, body)
+	}
+
+	if reqErr, _ := serve(t, httptest.NewRequest(http.MethodGet, "/posts?page=2&status=draft", nil)); reqErr != nil {
+		t.Errorf("корректные параметры отклонены: %v", reqErr)
+	}
+	// Путей не из документа проверка не касается
+	if reqErr, _ := serve(t, httptest.NewRequest(http.MethodPost, "/unknown?page=x", strings.NewReader("{"))); reqErr != nil {
+		t.Errorf("запрос к неописанному пути отклонён: %v", reqErr)
+	}
+}
+
+func TestValidatorRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+		code    string
+		field   string
+	}{
+		{"параметр пути не число", jsonRequest("/posts/abc/comments", `{"content": "x"}`),
+			http.StatusBadRequest, "invalid_parameter", "id"},
+		{"параметр пути меньше минимума", jsonRequest("/posts/0/comments", `{"content": "x"}`),
+			http.StatusBadRequest, "invalid_parameter", "id"},
+		{"параметр запроса не число", httptest.NewRequest(http.MethodGet, "/posts?page=abc", nil),
+			http.StatusBadRequest, "invalid_query", "page"},
+		{"значение не из перечня", httptest.NewRequest(http.MethodGet, "/posts?status=deleted", nil),
+			http.StatusBadRequest, "invalid_query", "status"},
+		{"некорректный JSON", jsonRequest("/posts/1/comments", `{"content": `),
+			http.StatusBadRequest, "invalid_json", ""},
+		{"два JSON объекта", jsonRequest("/posts/1/comments", `{"content": "a"} {}`),
+			http.StatusBadRequest, "invalid_json", ""},
+		{"пустое обязательное тело", jsonRequest("/posts/1/comments", ""),
+			http.StatusBadRequest, "invalid_json", ""},
+		{"тело не JSON", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/posts/1/comments", strings.NewReader("content=x"))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return r
+		}(), http.StatusUnsupportedMediaType, "unsupported_media_type", ""},
+		{"тело больше предела", jsonRequest("/posts/1/comments", `{"content": "`+strings.Repeat("a", 300)+`"}`),
+			http.StatusRequestEntityTooLarge, "request_too_large", ""},
+		{"нет обязательного поля", jsonRequest("/posts/1/comments", `{"author": "Гость"}`),
+			http.StatusUnprocessableEntity, "validation_failed", "content"},
+		{"неверный тип поля", jsonRequest("/posts/1/comments", `{"content": 42}`),
+			http.StatusUnprocessableEntity, "validation_failed", "content"},
+		{"лишнее поле", jsonRequest("/posts/1/comments", `{"content": "x", "admin": true}`),
+			http.StatusUnprocessableEntity, "validation_failed", "admin"},
+		{"строка длиннее предела", jsonRequest("/posts/1/comments", `{"content": "x", "author": "`+strings.Repeat("я", 51)+`"}`),
+			http.StatusUnprocessableEntity, "validation_failed", "author"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqErr, _ := serve(t, tt.request)
+			if reqErr == nil {
+				t.Fatal("запрос не отклонён")
+			}
+			if reqErr.Status != tt.status || reqErr.Code != tt.code || reqErr.Field != tt.field {
+				t.Errorf("ошибка %d %s (поле %q), want %d %s (поле %q): %s",
+					reqErr.Status, reqErr.Code, reqErr.Field, tt.status, tt.code, tt.field, reqErr.Message)
+			}
+		})
+	}
+}
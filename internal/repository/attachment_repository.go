@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+type attachmentRepository struct {
+	db *database.DB
+}
+
+func NewAttachmentRepository(db *database.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+const attachmentColumns = `id, post_id, user_id, filename, content_type, size,
+	storage_key, thumb_key, width, height, created_at`
+
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.PostID, &a.UserID, &a.Filename, &a.ContentType, &a.Size,
+		&a.StorageKey, &a.ThumbKey, &a.Width, &a.Height, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.SetURLs()
+	return &a, nil
+}
+
+func (r *attachmentRepository) GetByID(id int) (*models.Attachment, error) {
+	return scanAttachment(r.db.QueryRow(`SELECT `+attachmentColumns+` FROM attachments WHERE id = ?`, id))
+}
+
+func (r *attachmentRepository) ListByPosts(postIDs []int) (map[int][]models.Attachment, error) {
+	attachments := make(map[int][]models.Attachment)
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+
+	args := make([]any, len(postIDs))
+	for i, id := range postIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(postIDs)), ", ")
+
+	query := `SELECT ` + attachmentColumns + ` FROM attachments
+		WHERE post_id IN (` + placeholders + `)
+		ORDER BY id ASC`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments[a.PostID] = append(attachments[a.PostID], *a)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (r *attachmentRepository) CountByPost(postID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM attachments WHERE post_id = ?`, postID).Scan(&count)
+	return count, err
+}
+
+func (r *attachmentRepository) Create(a *models.Attachment) (int64, error) {
+	query := `
+		INSERT INTO attachments (post_id, user_id, filename, content_type, size,
+		                         storage_key, thumb_key, width, height, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(query, a.PostID, a.UserID, a.Filename, a.ContentType, a.Size,
+		a.StorageKey, a.ThumbKey, a.Width, a.Height).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *attachmentRepository) Delete(id int) error {
+	_, err := r.db.Exec(`DELETE FROM attachments WHERE id = ?`, id)
+	return err
+}
@@ -1,22 +1,30 @@
 package repository
 
 import (
-	"database/sql"
-	"time"
-
+	"github.com/s.usynin/testing/go-server/internal/database"
 	"github.com/s.usynin/testing/go-server/internal/models"
 )
 
-type CategoryRepository struct {
-	db *sql.DB
+type categoryRepository struct {
+	db *database.DB
+}
+
+func NewCategoryRepository(db *database.DB) CategoryRepository {
+	return &categoryRepository{db: db}
 }
 
-func NewCategoryRepository(db *sql.DB) *CategoryRepository {
-	return &CategoryRepository{db: db}
+const categoryColumns = `id, name, slug, created_at, comment_moderation, parent_id,
+	(SELECT COUNT(*) FROM posts WHERE posts.category_id = categories.id AND posts.status = 'published')`
+
+func scanCategory(row rowScanner) (*models.Category, error) {
+	var category models.Category
+	err := row.Scan(&category.ID, &category.Name, &category.Slug, &category.CreatedAt,
+		&category.CommentModeration, &category.ParentID, &category.PostsCount)
+	return &category, err
 }
 
-func (r *CategoryRepository) GetAll() ([]models.Category, error) {
-	query := `SELECT id, name, slug, created_at FROM categories ORDER BY name ASC`
+func (r *categoryRepository) GetAll() ([]models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories ORDER BY name ASC`
 
 	rows, err := r.db.Query(query)
 	if err != nil {
@@ -26,49 +34,112 @@ func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 
 	var categories []models.Category
 	for rows.Next() {
-		var category models.Category
-		var createdAt string
-		err := rows.Scan(&category.ID, &category.Name, &category.Slug, &createdAt)
+		category, err := scanCategory(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		category.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-		categories = append(categories, category)
+		categories = append(categories, *category)
 	}
 
-	return categories, nil
+	return categories, rows.Err()
 }
 
-func (r *CategoryRepository) GetByID(id int) (*models.Category, error) {
-	query := `SELECT id, name, slug, created_at FROM categories WHERE id = ?`
+func (r *categoryRepository) GetByID(id int) (*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id = ?`
 
-	var category models.Category
-	var createdAt string
-	err := r.db.QueryRow(query, id).Scan(&category.ID, &category.Name, &category.Slug, &createdAt)
+	category, err := scanCategory(r.db.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (r *categoryRepository) GetBySlug(slug string) (*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE slug = ?`
+
+	category, err := scanCategory(r.db.QueryRow(query, slug))
 	if err != nil {
 		return nil, err
 	}
 
-	category.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-	return &category, nil
+	return category, nil
 }
 
-func (r *CategoryRepository) Create(name, slug string) (int64, error) {
+func (r *categoryRepository) GetByName(name string) (*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE name = ?`
+
+	category, err := scanCategory(r.db.QueryRow(query, name))
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (r *categoryRepository) Create(name, slug string, parentID *int) (int64, error) {
 	query := `
-		INSERT INTO categories (name, slug, created_at) 
-		VALUES (?, ?, datetime('now'))
+		INSERT INTO categories (name, slug, parent_id, created_at) 
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		RETURNING id
 	`
 
-	result, err := r.db.Exec(query, name, slug)
+	var id int64
+	err := r.db.QueryRow(query, name, slug, parentID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *categoryRepository) Update(id int, name, slug string) error {
+	query := `UPDATE categories SET name = ?, slug = ? WHERE id = ?`
+	_, err := r.db.Exec(query, name, slug, id)
+	return err
+}
+
+func (r *categoryRepository) SetParent(id int, parentID *int) error {
+	query := `UPDATE categories SET parent_id = ? WHERE id = ?`
+	_, err := r.db.Exec(query, parentID, id)
+	return err
+}
+
+func (r *categoryRepository) Delete(id, reassignTo int) (int64, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
 
-	id, err := result.LastInsertId()
+	result, err := tx.Exec(`UPDATE posts SET category_id = ? WHERE category_id = ?`, reassignTo, id)
+	if err != nil {
+		return 0, err
+	}
+	moved, err := result.RowsAffected()
 	if err != nil {
 		return 0, err
 	}
 
-	return id, nil
+	// Иначе ревизию с удалённой категорией нельзя было бы восстановить
+	if _, err := tx.Exec(`UPDATE post_revisions SET category_id = ? WHERE category_id = ?`, reassignTo, id); err != nil {
+		return 0, err
+	}
+	// Подкатегории поднимаются на уровень удаляемой категории
+	query := `UPDATE categories SET parent_id = (SELECT parent_id FROM categories WHERE id = ?) WHERE parent_id = ?`
+	if _, err := tx.Exec(query, id, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM categories WHERE id = ?`, id); err != nil {
+		return 0, err
+	}
+
+	return moved, tx.Commit()
+}
+
+func (r *categoryRepository) SetCommentModeration(id int, mode *models.ModerationMode) error {
+	query := `UPDATE categories SET comment_moderation = ? WHERE id = ?`
+	_, err := r.db.Exec(query, mode, id)
+	return err
 }
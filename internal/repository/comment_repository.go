@@ -2,28 +2,34 @@ package repository
 
 import (
 	"database/sql"
-	"time"
+	"strings"
 
+	"github.com/s.usynin/testing/go-server/internal/database"
 	"github.com/s.usynin/testing/go-server/internal/models"
 )
 
-type CommentRepository struct {
-	db *sql.DB
+type commentRepository struct {
+	db *database.DB
 }
 
-func NewCommentRepository(db *sql.DB) *CommentRepository {
-	return &CommentRepository{db: db}
+func NewCommentRepository(db *database.DB) CommentRepository {
+	return &commentRepository{db: db}
 }
 
-func (r *CommentRepository) GetByPostID(postID int) ([]models.Comment, error) {
-	query := `
-		SELECT id, post_id, author, content, created_at 
-		FROM comments 
-		WHERE post_id = ? 
-		ORDER BY created_at ASC
-	`
+// commentColumns - колонки комментария в порядке scanComment
+const commentColumns = `c.id, c.post_id, c.parent_id, c.user_id, c.author, c.content, c.created_at, c.deleted_at, c.status,
+	c.spam_score, c.spam_reasons, COALESCE(c.ip, ''), c.spam_label`
+
+func scanComment(row rowScanner) (models.Comment, error) {
+	var comment models.Comment
+	err := row.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.UserID,
+		&comment.Author, &comment.Content, &comment.CreatedAt, &comment.DeletedAt, &comment.Status,
+		&comment.SpamScore, &comment.SpamReasons, &comment.IP, &comment.SpamLabel)
+	return comment, err
+}
 
-	rows, err := r.db.Query(query, postID)
+func (r *commentRepository) queryComments(query string, args ...any) ([]models.Comment, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -31,33 +37,133 @@ func (r *CommentRepository) GetByPostID(postID int) ([]models.Comment, error) {
 
 	var comments []models.Comment
 	for rows.Next() {
-		var comment models.Comment
-		var createdAt string
-		err := rows.Scan(&comment.ID, &comment.PostID, &comment.Author,
-			&comment.Content, &createdAt)
+		comment, err := scanComment(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		comment.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
 		comments = append(comments, comment)
 	}
 
-	return comments, nil
+	return comments, rows.Err()
 }
 
-func (r *CommentRepository) Create(postID int, author, content string) (int64, error) {
+func (r *commentRepository) ListByPost(postID int, before *Cursor, limit int) ([]models.Comment, error) {
+	where := "c.post_id = ? AND c.parent_id IS NULL AND c.status = ?"
+	args := []any{postID, models.CommentApproved}
+	if before != nil {
+		cond, condArgs := keysetBefore(r.db.Dialect, "c", before)
+		where += " AND " + cond
+		args = append(args, condArgs...)
+	}
+
 	query := `
-		INSERT INTO comments (post_id, author, content, created_at) 
-		VALUES (?, ?, ?, datetime('now'))
+		SELECT ` + commentColumns + `
+		FROM comments c
+		WHERE ` + where + `
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT ?
 	`
 
-	result, err := r.db.Exec(query, postID, author, content)
+	return r.queryComments(query, append(args, limit)...)
+}
+
+func (r *commentRepository) ListLatestByPosts(postIDs []int, limit int) (map[int][]models.Comment, error) {
+	byPost := make(map[int][]models.Comment)
+	if len(postIDs) == 0 {
+		return byPost, nil
+	}
+
+	args := make([]any, 0, len(postIDs)+2)
+	for _, id := range postIDs {
+		args = append(args, id)
+	}
+	args = append(args, models.CommentApproved, limit)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(postIDs)), ", ")
+
+	// Номер комментария в своём посте от новых к старым отсекает лишние
+	query := `
+		SELECT ` + commentColumns + `
+		FROM (
+			SELECT comments.*, ROW_NUMBER() OVER (
+				PARTITION BY post_id ORDER BY created_at DESC, id DESC
+			) AS position
+			FROM comments
+			WHERE post_id IN (` + placeholders + `) AND parent_id IS NULL AND status = ?
+		) c
+		WHERE c.position <= ?
+		ORDER BY c.post_id, c.created_at DESC, c.id DESC
+	`
+	comments, err := r.queryComments(query, args...)
 	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		byPost[comment.PostID] = append(byPost[comment.PostID], comment)
+	}
+	return byPost, nil
+}
+
+func (r *commentRepository) ListReplies(parentIDs []int) ([]models.Comment, error) {
+	if len(parentIDs) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, 0, len(parentIDs)+2)
+	for _, id := range parentIDs {
+		args = append(args, id)
+	}
+	args = append(args, models.CommentApproved, models.CommentApproved)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(parentIDs)), ", ")
+
+	// id растут в порядке создания, поэтому родитель всегда идёт раньше ответа.
+	// Неодобренный ответ скрывает и свою ветку.
+	query := `
+		WITH RECURSIVE thread(id) AS (
+			SELECT id FROM comments WHERE parent_id IN (` + placeholders + `) AND status = ?
+			UNION ALL
+			SELECT c.id FROM comments c JOIN thread t ON c.parent_id = t.id WHERE c.status = ?
+		)
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN thread t ON t.id = c.id
+		ORDER BY c.id
+	`
+
+	return r.queryComments(query, args...)
+}
+
+func (r *commentRepository) Depth(id int) (int, error) {
+	query := `
+		WITH RECURSIVE ancestors(id, parent_id, depth) AS (
+			SELECT id, parent_id, 0 FROM comments WHERE id = ?
+			UNION ALL
+			SELECT c.id, c.parent_id, a.depth + 1 FROM comments c JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT MAX(depth) FROM ancestors
+	`
+
+	var depth *int
+	if err := r.db.QueryRow(query, id).Scan(&depth); err != nil {
 		return 0, err
 	}
+	if depth == nil {
+		return 0, sql.ErrNoRows
+	}
+	return *depth, nil
+}
 
-	id, err := result.LastInsertId()
+func (r *commentRepository) Create(comment *models.Comment) (int64, error) {
+	query := `
+		INSERT INTO comments (post_id, parent_id, user_id, author, content, status,
+		                      spam_score, spam_reasons, ip, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), CURRENT_TIMESTAMP)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(query, comment.PostID, comment.ParentID, comment.UserID, comment.Author,
+		comment.Content, comment.Status, comment.SpamScore, comment.SpamReasons, comment.IP).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
@@ -65,17 +171,152 @@ func (r *CommentRepository) Create(postID int, author, content string) (int64, e
 	return id, nil
 }
 
-func (r *CommentRepository) GetByID(id int) (*models.Comment, error) {
-	query := `SELECT id, post_id, author, content, created_at FROM comments WHERE id = ?`
+func (r *commentRepository) GetByID(id int) (*models.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments c WHERE c.id = ?`
 
-	var comment models.Comment
-	var createdAt string
-	err := r.db.QueryRow(query, id).Scan(&comment.ID, &comment.PostID,
-		&comment.Author, &comment.Content, &createdAt)
+	comment, err := scanComment(r.db.QueryRow(query, id))
 	if err != nil {
 		return nil, err
 	}
 
-	comment.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
 	return &comment, nil
 }
+
+func (r *commentRepository) ListByStatus(status models.CommentStatus, limit, offset int) ([]models.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		WHERE c.status = ? AND c.deleted_at IS NULL
+		ORDER BY c.created_at, c.id
+		LIMIT ? OFFSET ?
+	`
+
+	return r.queryComments(query, status, limit, offset)
+}
+
+func (r *commentRepository) CountByStatus(status models.CommentStatus) (int, error) {
+	query := `SELECT COUNT(*) FROM comments WHERE status = ? AND deleted_at IS NULL`
+
+	var count int
+	err := r.db.QueryRow(query, status).Scan(&count)
+	return count, err
+}
+
+func (r *commentRepository) Moderate(ids []int, status models.CommentStatus, training []SpamTraining) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, status)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE comments SET status = ? WHERE id IN (`+placeholders+`)`, args...); err != nil {
+		return err
+	}
+	for _, tr := range training {
+		if tr.Previous != "" {
+			if err := learnTokens(tx, tr.Tokens, tr.Previous, -1); err != nil {
+				return err
+			}
+		}
+		var label *models.SpamLabel
+		if tr.Label != "" {
+			if err := learnTokens(tx, tr.Tokens, tr.Label, 1); err != nil {
+				return err
+			}
+			label = &tr.Label
+		}
+		if _, err := tx.Exec(`UPDATE comments SET spam_label = ? WHERE id = ?`, label, tr.CommentID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *commentRepository) MarkNotified(id int) (bool, error) {
+	result, err := r.db.Exec(`UPDATE comments SET notified_at = CURRENT_TIMESTAMP WHERE id = ? AND notified_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (r *commentRepository) HasApproved(userID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM comments WHERE user_id = ? AND status = ?)`
+
+	var exists bool
+	err := r.db.QueryRow(query, userID, models.CommentApproved).Scan(&exists)
+	return exists, err
+}
+
+func (r *commentRepository) Delete(id int) (*CommentDeletion, error) {
+	// Родитель комментария, удалён ли он сам и сколько у него прямых ответов
+	const nodeQuery = `
+		SELECT c.parent_id, c.deleted_at IS NOT NULL,
+		       (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id)
+		FROM comments c
+		WHERE c.id = ?
+	`
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		parentID *int
+		deleted  bool
+		replies  int
+	)
+	if err := tx.QueryRow(nodeQuery, id).Scan(&parentID, &deleted, &replies); err != nil {
+		return nil, err
+	}
+
+	// Ответы остаются на месте, от комментария остаётся только надгробие
+	if replies > 0 {
+		_, err := tx.Exec(`
+			UPDATE comments
+			SET author = '', content = '', user_id = NULL, deleted_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, id)
+		if err != nil {
+			return nil, err
+		}
+		return &CommentDeletion{Tombstoned: true}, tx.Commit()
+	}
+
+	if _, err := tx.Exec(`DELETE FROM comments WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	removed := id
+
+	// Надгробия, у которых не осталось ответов, больше ничего не держат
+	for parentID != nil {
+		var grandparentID *int
+		if err := tx.QueryRow(nodeQuery, *parentID).Scan(&grandparentID, &deleted, &replies); err != nil {
+			return nil, err
+		}
+		if !deleted || replies > 0 {
+			break
+		}
+		if _, err := tx.Exec(`DELETE FROM comments WHERE id = ?`, *parentID); err != nil {
+			return nil, err
+		}
+		removed, parentID = *parentID, grandparentID
+	}
+
+	return &CommentDeletion{RemovedID: removed}, tx.Commit()
+}
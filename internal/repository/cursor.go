@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+)
+
+// Cursor - позиция в списке для keyset пагинации по паре (created_at, id).
+// В отличие от OFFSET, следующая страница не съезжает, когда в начало
+// списка добавляются новые записи, и не требует пропускать строки.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// ErrInvalidCursor - строка курсора повреждена
+var ErrInvalidCursor = errors.New("неверный курсор")
+
+// CursorOf возвращает курсор, указывающий на запись
+func CursorOf(createdAt time.Time, id int) *Cursor {
+	return &Cursor{CreatedAt: createdAt, ID: id}
+}
+
+// String кодирует курсор в непрозрачную строку для URL и JSON. Время хранится
+// в наносекундах: PostgreSQL пишет created_at с микросекундами, и курсор с
+// точностью до секунды пропускал бы записи, созданные в ту же секунду.
+func (c *Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor разбирает строку из Cursor.String. Пустая строка даёт nil - начало списка.
+func ParseCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	unixNano, err1 := strconv.ParseInt(nanos, 10, 64)
+	cursorID, err2 := strconv.Atoi(id)
+	if err1 != nil || err2 != nil || cursorID < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	return CursorOf(time.Unix(0, unixNano), cursorID), nil
+}
+
+// keysetBefore - условие "запись раньше курсора" для сортировки по убыванию (created_at, id)
+func keysetBefore(d database.Dialect, table string, c *Cursor) (string, []any) {
+	return keysetBeforeOn(d, table+".created_at", table+".id", c)
+}
+
+// keysetBeforeOn - то же для сортировки по произвольному выражению времени
+func keysetBeforeOn(d database.Dialect, timeExpr, idColumn string, c *Cursor) (string, []any) {
+	ts := dbTime(d, c.CreatedAt)
+	cond := fmt.Sprintf("(%[1]s < ? OR (%[1]s = ? AND %[2]s < ?))", timeExpr, idColumn)
+	return cond, []any{ts, ts, c.ID}
+}
+
+// dbTime переводит время в значение для сравнения с колонками времени.
+// PostgreSQL получает time.Time в UTC и сравнивает с полной точностью.
+// SQLite сравнивает даты как строки, поэтому время форматируется так же,
+// как CURRENT_TIMESTAMP (UTC, с точностью до секунды).
+func dbTime(d database.Dialect, t time.Time) any {
+	if d == database.DialectPostgres {
+		return t.UTC()
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 45, 123456789, time.UTC)
+	cursor, err := ParseCursor(CursorOf(at, 42).String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cursor.CreatedAt.Equal(at) || cursor.ID != 42 {
+		t.Errorf("курсор после разбора: %v %d, want %v 42", cursor.CreatedAt, cursor.ID, at)
+	}
+
+	if cursor, err := ParseCursor(""); cursor != nil || err != nil {
+		t.Errorf("ParseCursor(\"\") = %v, %v; want nil, nil", cursor, err)
+	}
+	for _, s := range []string{"!!!", "MTIz", "YWJjOjE", "MTIzOjA", "MTIzOi0x"} {
+		if _, err := ParseCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ParseCursor(%q) = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestDBTime(t *testing.T) {
+	at := time.Date(2024, 5, 1, 15, 30, 45, 123456000, time.FixedZone("MSK", 3*60*60))
+
+	if got := dbTime(database.DialectSQLite, at); got != "2024-05-01 12:30:45" {
+		t.Errorf("SQLite: %v, want 2024-05-01 12:30:45", got)
+	}
+	got, ok := dbTime(database.DialectPostgres, at).(time.Time)
+	if !ok || !got.Equal(at) || got.Location() != time.UTC {
+		t.Errorf("PostgreSQL: %v, want %v в UTC", got, at)
+	}
+}
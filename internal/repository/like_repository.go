@@ -2,45 +2,108 @@ package repository
 
 import (
 	"database/sql"
-	"time"
+	"errors"
+	"strings"
 
+	"github.com/s.usynin/testing/go-server/internal/database"
 	"github.com/s.usynin/testing/go-server/internal/models"
 )
 
-type LikeRepository struct {
-	db *sql.DB
+type likeRepository struct {
+	db *database.DB
 }
 
-func NewLikeRepository(db *sql.DB) *LikeRepository {
-	return &LikeRepository{db: db}
+func NewLikeRepository(db *database.DB) LikeRepository {
+	return &likeRepository{db: db}
 }
 
-func (r *LikeRepository) Add(postID int) (int64, error) {
-	query := `INSERT INTO likes (post_id, created_at) VALUES (?, datetime('now'))`
+// owner - условие на владельца лайка и его аргумент
+func (l Liker) owner() (string, any) {
+	if l.UserID != 0 {
+		return "user_id = ?", l.UserID
+	}
+	return "visitor_id = ?", l.VisitorID
+}
+
+func (r *likeRepository) Add(postID int, liker Liker) (bool, error) {
+	var userID *int
+	var visitorID *string
+	if liker.UserID != 0 {
+		userID = &liker.UserID
+	} else {
+		visitorID = &liker.VisitorID
+	}
+
+	// Повторный лайк упирается в уникальный индекс и ничего не вставляет
+	query := `
+		INSERT INTO likes (post_id, user_id, visitor_id, created_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(query, postID, userID, visitorID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
 
-	result, err := r.db.Exec(query, postID)
+func (r *likeRepository) Remove(postID int, liker Liker) (bool, error) {
+	cond, arg := liker.owner()
+	result, err := r.db.Exec(`DELETE FROM likes WHERE post_id = ? AND `+cond, postID, arg)
 	if err != nil {
-		return 0, err
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *likeRepository) Liked(liker Liker, postIDs []int) (map[int]bool, error) {
+	liked := make(map[int]bool)
+	if len(postIDs) == 0 || liker.Anonymous() {
+		return liked, nil
 	}
 
-	id, err := result.LastInsertId()
+	cond, arg := liker.owner()
+	args := []any{arg}
+	for _, id := range postIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(postIDs)), ", ")
+
+	query := `SELECT post_id FROM likes WHERE ` + cond + ` AND post_id IN (` + placeholders + `)`
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		if err := rows.Scan(&postID); err != nil {
+			return nil, err
+		}
+		liked[postID] = true
 	}
 
-	return id, nil
+	return liked, rows.Err()
 }
 
-func (r *LikeRepository) GetByID(id int) (*models.Like, error) {
-	query := `SELECT id, post_id, created_at FROM likes WHERE id = ?`
+func (r *likeRepository) GetByID(id int) (*models.Like, error) {
+	query := `SELECT id, post_id, user_id, created_at FROM likes WHERE id = ?`
 
 	var like models.Like
-	var createdAt string
-	err := r.db.QueryRow(query, id).Scan(&like.ID, &like.PostID, &createdAt)
+	err := r.db.QueryRow(query, id).Scan(&like.ID, &like.PostID, &like.UserID, &like.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 
-	like.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
 	return &like, nil
 }
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+type notificationRepository struct {
+	db *database.DB
+}
+
+func NewNotificationRepository(db *database.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) Create(userID int, postID, commentID *int, message string) (int64, error) {
+	query := `
+		INSERT INTO notifications (user_id, post_id, comment_id, message, created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(query, userID, postID, commentID, message).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *notificationRepository) ListByUser(userID, limit int) ([]models.Notification, error) {
+	query := `
+		SELECT id, user_id, post_id, comment_id, message, created_at, read_at
+		FROM notifications
+		WHERE user_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(&n.ID, &n.UserID, &n.PostID, &n.CommentID, &n.Message, &n.CreatedAt, &n.ReadAt)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *notificationRepository) CountUnread(userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`
+
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *notificationRepository) MarkAllRead(userID int) error {
+	query := `UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
@@ -2,121 +2,261 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
+	"github.com/s.usynin/testing/go-server/internal/database"
 	"github.com/s.usynin/testing/go-server/internal/models"
 )
 
-type PostRepository struct {
-	db *sql.DB
+// postColumns - колонки поста для scanPost (таблицы posts p и users u).
+// Счётчики комментариев и лайков - подзапросы по индексам на post_id:
+// JOIN обеих таблиц размножал бы строки поста на их произведение.
+// Реакции считаются отдельно, по готовым счётчикам reaction_counts.
+const postColumns = `
+	p.id, p.title, p.content, p.category_id, p.user_id, p.revision, p.created_at, p.updated_at,
+	p.status, p.published_at,
+	COALESCE(u.username, '') AS author_name,
+	(SELECT COUNT(*) FROM comments c
+	 WHERE c.post_id = p.id AND c.deleted_at IS NULL AND c.status = 'approved') AS comments_count,
+	(SELECT COUNT(*) FROM likes l WHERE l.post_id = p.id) AS likes_count
+`
+
+// postSelect - общая часть запросов постов; запросы дописывают WHERE
+const postSelect = `
+	SELECT ` + postColumns + `
+	FROM posts p
+	LEFT JOIN users u ON u.id = p.user_id
+`
+
+// postDate - дата поста для сортировки ленты, как models.Post.Date
+const postDate = "COALESCE(p.published_at, p.created_at)"
+
+// rowScanner позволяет сканировать и *sql.Row, и *sql.Rows одной функцией
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func NewPostRepository(db *sql.DB) *PostRepository {
-	return &PostRepository{db: db}
+func scanPost(row rowScanner) (models.Post, error) {
+	var post models.Post
+	err := row.Scan(&post.ID, &post.Title, &post.Content, &post.CategoryID, &post.UserID,
+		&post.Revision, &post.CreatedAt, &post.UpdatedAt, &post.Status, &post.PublishedAt,
+		&post.AuthorName, &post.CommentsCount, &post.LikesCount)
+	return post, err
 }
 
-func (r *PostRepository) GetAll() ([]models.Post, error) {
-	query := `
-		SELECT p.id, p.title, p.content, p.category_id, p.created_at, p.updated_at,
-		       COUNT(DISTINCT c.id) as comments_count,
-		       COUNT(DISTINCT l.id) as likes_count
-		FROM posts p
-		LEFT JOIN comments c ON p.id = c.post_id
-		LEFT JOIN likes l ON p.id = l.post_id
-		GROUP BY p.id
-		ORDER BY p.created_at DESC
+type postRepository struct {
+	db *database.DB
+}
+
+func NewPostRepository(db *database.DB) PostRepository {
+	return &postRepository{db: db}
+}
+
+func (r *postRepository) GetByID(id int) (*models.Post, error) {
+	query := postSelect + `
+		WHERE p.id = ?
 	`
 
-	rows, err := r.db.Query(query)
+	post, err := scanPost(r.db.QueryRow(query, id))
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var posts []models.Post
-	for rows.Next() {
-		var post models.Post
-		var createdAt, updatedAt string
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CategoryID,
-			&createdAt, &updatedAt, &post.CommentsCount, &post.LikesCount)
+	return &post, nil
+}
+
+// Create создаёт пост вместе с его первой ревизией
+func (r *postRepository) Create(userID int, title, content string, categoryID int, status models.PostStatus, publishedAt *time.Time) (int64, error) {
+	query := `
+		INSERT INTO posts (title, content, category_id, user_id, revision, status, published_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id
+	`
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(query, title, content, categoryID, userID, status, nullTime(r.db.Dialect, publishedAt)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertRevision(tx, id, 1, userID); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Update сохраняет правку, теги и состояние публикации поста вместе:
+// если что-то не сохранилось, пост остаётся прежним
+func (r *postRepository) Update(id int, update PostUpdate) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if update.Revise {
+		query := `
+			UPDATE posts
+			SET title = ?, content = ?, category_id = ?, revision = revision + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND revision = ?
+		`
+		result, err := tx.Exec(query, update.Title, update.Content, update.CategoryID, id, update.BaseRevision)
 		if err != nil {
-			return nil, err
+			return false, err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return false, err
 		}
 
-		post.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-		post.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+		if err := insertRevision(tx, int64(id), update.BaseRevision+1, update.EditorID); err != nil {
+			return false, err
+		}
+	}
 
-		posts = append(posts, post)
+	if update.Tags != nil {
+		if err := setPostTags(tx, id, update.Tags); err != nil {
+			return false, err
+		}
 	}
 
-	return posts, nil
-}
+	if update.Status != "" {
+		query := `UPDATE posts SET status = ?, published_at = ? WHERE id = ?`
+		if _, err := tx.Exec(query, update.Status, nullTime(r.db.Dialect, update.PublishedAt), id); err != nil {
+			return false, err
+		}
+	}
 
-func (r *PostRepository) GetByID(id int) (*models.Post, error) {
-	query := `
-		SELECT p.id, p.title, p.content, p.category_id, p.created_at, p.updated_at,
-		       COUNT(DISTINCT c.id) as comments_count,
-		       COUNT(DISTINCT l.id) as likes_count
-		FROM posts p
-		LEFT JOIN comments c ON p.id = c.post_id
-		LEFT JOIN likes l ON p.id = l.post_id
-		WHERE p.id = ?
-		GROUP BY p.id
-	`
+	return true, tx.Commit()
+}
 
-	var post models.Post
-	var createdAt, updatedAt string
-	err := r.db.QueryRow(query, id).Scan(&post.ID, &post.Title, &post.Content,
-		&post.CategoryID, &createdAt, &updatedAt, &post.CommentsCount, &post.LikesCount)
+// PublishDue публикует запланированные посты, время которых наступило к now,
+// и возвращает их ID
+func (r *postRepository) PublishDue(now time.Time) ([]int, error) {
+	query := `UPDATE posts SET status = ? WHERE status = ? AND published_at <= ? RETURNING id`
+	rows, err := r.db.Query(query, models.PostPublished, models.PostScheduled, dbTime(r.db.Dialect, now))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	post.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-	post.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
-
-	return &post, nil
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
-func (r *PostRepository) Create(title, content string, categoryID int) (int64, error) {
-	query := `
-		INSERT INTO posts (title, content, category_id, created_at, updated_at) 
-		VALUES (?, ?, ?, datetime('now'), datetime('now'))
-	`
+// NextScheduled возвращает время ближайшей запланированной публикации, nil - если их нет
+func (r *postRepository) NextScheduled() (*time.Time, error) {
+	query := `SELECT published_at FROM posts WHERE status = ? ORDER BY published_at LIMIT 1`
 
-	result, err := r.db.Exec(query, title, content, categoryID)
+	var next time.Time
+	err := r.db.QueryRow(query, models.PostScheduled).Scan(&next)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	return &next, nil
+}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+// nullTime переводит необязательное время в значение для запроса
+func nullTime(d database.Dialect, t *time.Time) any {
+	if t == nil {
+		return nil
 	}
+	return dbTime(d, *t)
+}
+
+// insertRevision копирует текущее состояние поста в post_revisions
+func insertRevision(tx *database.Tx, postID int64, revision, editorID int) error {
+	query := `
+		INSERT INTO post_revisions (post_id, revision, title, content, category_id, editor_id, created_at)
+		SELECT id, ?, title, content, category_id, ?, updated_at FROM posts WHERE id = ?
+	`
 
-	return id, nil
+	_, err := tx.Exec(query, revision, editorID, postID)
+	return err
 }
 
-func (r *PostRepository) Delete(id int) error {
+func (r *postRepository) Delete(id int) error {
 	query := `DELETE FROM posts WHERE id = ?`
 	_, err := r.db.Exec(query, id)
 	return err
 }
 
-func (r *PostRepository) GetByCategoryID(categoryID int) ([]models.Post, error) {
-	query := `
-		SELECT p.id, p.title, p.content, p.category_id, p.created_at, p.updated_at,
-		       COUNT(DISTINCT c.id) as comments_count,
-		       COUNT(DISTINCT l.id) as likes_count
-		FROM posts p
-		LEFT JOIN comments c ON p.id = c.post_id
-		LEFT JOIN likes l ON p.id = l.post_id
-		WHERE p.category_id = ?
-		GROUP BY p.id
-		ORDER BY p.created_at DESC
+// List возвращает страницу постов, подходящих под фильтр, начиная с новых
+func (r *postRepository) List(filter PostFilter) ([]models.Post, error) {
+	where, args := filter.where(r.db.Dialect)
+	query := postSelect + where + `
+		ORDER BY ` + postDate + ` DESC, p.id DESC
+		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.Query(query, categoryID)
+	return r.queryPosts(query, append(args, filter.Limit, filter.Offset)...)
+}
+
+// Count возвращает число постов, подходящих под фильтр
+func (r *postRepository) Count(filter PostFilter) (int, error) {
+	where, args := filter.where(r.db.Dialect)
+
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM posts p `+where, args...).Scan(&count)
+	return count, err
+}
+
+func (f PostFilter) where(d database.Dialect) (string, []any) {
+	status := f.Status
+	if status == "" {
+		status = models.PostPublished
+	}
+	conds := []string{"p.status = ?"}
+	args := []any{status}
+
+	if f.CategoryID != 0 {
+		conds = append(conds, `p.category_id IN (
+			WITH RECURSIVE subtree(id) AS (
+				SELECT id FROM categories WHERE id = ?
+				UNION ALL
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+			)
+			SELECT id FROM subtree
+		)`)
+		args = append(args, f.CategoryID)
+	}
+	if f.TagID != 0 {
+		conds = append(conds, "p.id IN (SELECT post_id FROM post_tags WHERE tag_id = ?)")
+		args = append(args, f.TagID)
+	}
+	if f.UserID != 0 {
+		conds = append(conds, "p.user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.Before != nil {
+		cond, condArgs := keysetBeforeOn(d, postDate, "p.id", f.Before)
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (r *postRepository) queryPosts(query string, args ...any) ([]models.Post, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -124,19 +264,13 @@ func (r *PostRepository) GetByCategoryID(categoryID int) ([]models.Post, error)
 
 	var posts []models.Post
 	for rows.Next() {
-		var post models.Post
-		var createdAt, updatedAt string
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CategoryID,
-			&createdAt, &updatedAt, &post.CommentsCount, &post.LikesCount)
+		post, err := scanPost(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		post.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-		post.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
-
 		posts = append(posts, post)
 	}
 
-	return posts, nil
+	return posts, rows.Err()
 }
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+)
+
+// Полнотекстовый поиск: в SQLite - виртуальная таблица FTS5 posts_fts,
+// в PostgreSQL - таблица posts_search с tsvector. Обе поддерживаются триггерами
+// (миграция 0005_search). Термы приходят из service слоя уже разбитыми
+// на слова из букв и цифр, поэтому их можно подставлять в синтаксис запроса.
+
+// snippetWords - длина фрагмента с совпадением в словах
+const snippetWords = 24
+
+// SQLite: bm25 с весами колонок title, content, comments.
+// MATERIALIZED не даёт планировщику встроить CTE во внешний запрос,
+// где вспомогательные функции FTS5 уже недоступны.
+const sqliteSearchHits = `
+	WITH hits AS MATERIALIZED (
+		SELECT rowid AS post_id,
+		       snippet(posts_fts, -1, ?, ?, '…', ?) AS snippet,
+		       bm25(posts_fts, 10.0, 4.0, 1.0) AS rank
+		FROM posts_fts
+		WHERE posts_fts MATCH ?
+	)
+`
+
+// PostgreSQL: ts_rank_cd растёт с релевантностью, поэтому берётся со знаком минус
+const postgresSearchHits = `
+	WITH hits AS (
+		SELECT s.post_id,
+		       ts_headline('simple', s.title || ' ' || s.content || ' ' || s.comments, q.query, ?) AS snippet,
+		       -ts_rank_cd(s.document, q.query) AS rank
+		FROM posts_search s, to_tsquery('simple', ?) AS q(query)
+		WHERE s.document @@ q.query
+	)
+`
+
+// Search возвращает страницу постов, подходящих под все термы (префиксный поиск),
+// от более релевантных к менее
+func (r *postRepository) Search(terms []string, filter PostFilter) ([]SearchHit, error) {
+	hits, hitArgs := r.searchHits(terms)
+	where, args := filter.where(r.db.Dialect)
+
+	query := hits + `
+		SELECT ` + postColumns + `, h.snippet, h.rank
+		FROM hits h
+		JOIN posts p ON p.id = h.post_id
+		LEFT JOIN users u ON u.id = p.user_id
+	` + where + `
+		ORDER BY h.rank, p.id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	args = append(hitArgs, args...)
+	rows, err := r.db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		post := &hit.Post
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CategoryID, &post.UserID,
+			&post.Revision, &post.CreatedAt, &post.UpdatedAt, &post.Status, &post.PublishedAt, &post.AuthorName, &post.CommentsCount, &post.LikesCount,
+			&hit.Snippet, &hit.Rank)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, hit)
+	}
+
+	return results, rows.Err()
+}
+
+// CountSearch возвращает число постов, подходящих под термы и фильтр
+func (r *postRepository) CountSearch(terms []string, filter PostFilter) (int, error) {
+	hits, hitArgs := r.searchHits(terms)
+	where, args := filter.where(r.db.Dialect)
+
+	query := hits + `SELECT COUNT(*) FROM hits h JOIN posts p ON p.id = h.post_id ` + where
+
+	var count int
+	err := r.db.QueryRow(query, append(hitArgs, args...)...).Scan(&count)
+	return count, err
+}
+
+// searchHits возвращает CTE hits(post_id, snippet, rank) для диалекта и его аргументы
+func (r *postRepository) searchHits(terms []string) (string, []any) {
+	if r.db.Dialect == database.DialectPostgres {
+		// слово:* & слово2:*
+		parts := make([]string, len(terms))
+		for i, term := range terms {
+			parts[i] = term + ":*"
+		}
+		options := "StartSel=" + SnippetStart + ", StopSel=" + SnippetEnd +
+			", MaxWords=" + strconv.Itoa(snippetWords) + ", MinWords=8, MaxFragments=2, FragmentDelimiter=…"
+		return postgresSearchHits, []any{options, strings.Join(parts, " & ")}
+	}
+
+	// "слово"* "слово2"* - FTS5 объединяет термы через AND
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `"` + term + `"*`
+	}
+	return sqliteSearchHits, []any{SnippetStart, SnippetEnd, snippetWords, strings.Join(parts, " ")}
+}
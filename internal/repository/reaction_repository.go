@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+type reactionRepository struct {
+	db *database.DB
+}
+
+func NewReactionRepository(db *database.DB) ReactionRepository {
+	return &reactionRepository{db: db}
+}
+
+// targetColumn - колонка reactions и reaction_counts, в которой хранится цель
+func targetColumn(target models.ReactionTarget) (string, error) {
+	switch target {
+	case models.ReactionOnPost:
+		return "post_id", nil
+	case models.ReactionOnComment:
+		return "comment_id", nil
+	}
+	return "", fmt.Errorf("неизвестная цель реакции: %q", target)
+}
+
+func (r *reactionRepository) Add(target models.ReactionTarget, id int, emoji string, liker Liker) (bool, error) {
+	column, err := targetColumn(target)
+	if err != nil {
+		return false, err
+	}
+
+	var userID *int
+	var visitorID *string
+	if liker.UserID != 0 {
+		userID = &liker.UserID
+	} else {
+		visitorID = &liker.VisitorID
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Повторная реакция упирается в уникальный индекс и ничего не вставляет
+	var reactionID int64
+	err = tx.QueryRow(`
+		INSERT INTO reactions (`+column+`, emoji, user_id, visitor_id, created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`, id, emoji, userID, visitorID).Scan(&reactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO reaction_counts (`+column+`, emoji, count) VALUES (?, ?, 1)
+		ON CONFLICT (`+column+`, emoji) DO UPDATE SET count = reaction_counts.count + 1
+	`, id, emoji)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *reactionRepository) Remove(target models.ReactionTarget, id int, emoji string, liker Liker) (bool, error) {
+	column, err := targetColumn(target)
+	if err != nil {
+		return false, err
+	}
+	cond, arg := liker.owner()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM reactions WHERE `+column+` = ? AND emoji = ? AND `+cond, id, emoji, arg)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`UPDATE reaction_counts SET count = count - 1 WHERE `+column+` = ? AND emoji = ?`, id, emoji); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM reaction_counts WHERE `+column+` = ? AND emoji = ? AND count <= 0`, id, emoji); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *reactionRepository) Counts(target models.ReactionTarget, ids []int) (map[int]map[string]int, error) {
+	counts := make(map[int]map[string]int)
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	column, err := targetColumn(target)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	query := `SELECT ` + column + `, emoji, count FROM reaction_counts WHERE ` + column + ` IN (` + placeholders + `)`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, count int
+			emoji     string
+		)
+		if err := rows.Scan(&id, &emoji, &count); err != nil {
+			return nil, err
+		}
+		if counts[id] == nil {
+			counts[id] = make(map[string]int)
+		}
+		counts[id][emoji] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *reactionRepository) Reacted(target models.ReactionTarget, ids []int, liker Liker) (map[int]map[string]bool, error) {
+	reacted := make(map[int]map[string]bool)
+	if len(ids) == 0 || liker.Anonymous() {
+		return reacted, nil
+	}
+	column, err := targetColumn(target)
+	if err != nil {
+		return nil, err
+	}
+
+	cond, arg := liker.owner()
+	args := []any{arg}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	query := `SELECT ` + column + `, emoji FROM reactions WHERE ` + cond + ` AND ` + column + ` IN (` + placeholders + `)`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id    int
+			emoji string
+		)
+		if err := rows.Scan(&id, &emoji); err != nil {
+			return nil, err
+		}
+		if reacted[id] == nil {
+			reacted[id] = make(map[string]bool)
+		}
+		reacted[id][emoji] = true
+	}
+
+	return reacted, rows.Err()
+}
+
+func (r *reactionRepository) List(target models.ReactionTarget, id int, emoji string, limit, offset int) ([]models.Reaction, error) {
+	column, err := targetColumn(target)
+	if err != nil {
+		return nil, err
+	}
+
+	where := `r.` + column + ` = ?`
+	args := []any{id}
+	if emoji != "" {
+		where += ` AND r.emoji = ?`
+		args = append(args, emoji)
+	}
+
+	query := `
+		SELECT r.emoji, r.user_id, COALESCE(u.username, ''), r.created_at
+		FROM reactions r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE ` + where + `
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reactions []models.Reaction
+	for rows.Next() {
+		var reaction models.Reaction
+		if err := rows.Scan(&reaction.Emoji, &reaction.UserID, &reaction.Username, &reaction.CreatedAt); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+
+	return reactions, rows.Err()
+}
+
+func (r *reactionRepository) Count(target models.ReactionTarget, id int, emoji string) (int, error) {
+	column, err := targetColumn(target)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `SELECT COALESCE(SUM(count), 0) FROM reaction_counts WHERE ` + column + ` = ?`
+	args := []any{id}
+	if emoji != "" {
+		query += ` AND emoji = ?`
+		args = append(args, emoji)
+	}
+
+	var count int
+	err = r.db.QueryRow(query, args...).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+// Интерфейсы хранилищ, от которых зависит service слой.
+// Реализации в этом пакете пишут SQL с плейсхолдерами `?` и работают
+// поверх database.DB, который приводит запросы к диалекту SQLite или PostgreSQL.
+
+// PostRepository - хранилище постов
+type PostRepository interface {
+	GetByID(id int) (*models.Post, error)
+	Create(userID int, title, content string, categoryID int, status models.PostStatus, publishedAt *time.Time) (int64, error)
+	// Update сохраняет изменения поста одной транзакцией и возвращает false,
+	// если пост успели изменить (или удалить) с момента открытия формы
+	Update(id int, update PostUpdate) (bool, error)
+	// PublishDue переводит в published запланированные посты со временем не позже now
+	// и возвращает их ID
+	PublishDue(now time.Time) ([]int, error)
+	NextScheduled() (*time.Time, error)
+	Delete(id int) error
+	List(filter PostFilter) ([]models.Post, error)
+	Count(filter PostFilter) (int, error)
+	Search(terms []string, filter PostFilter) ([]SearchHit, error)
+	CountSearch(terms []string, filter PostFilter) (int, error)
+}
+
+// PostUpdate - изменения поста для PostRepository.Update. Новые заголовок,
+// текст и категория (Revise) создают ревизию и сохраняются, только если
+// текущая ревизия поста равна BaseRevision. Теги и состояние публикации
+// в ревизиях не хранятся: nil Tags и пустой Status оставляют их как есть.
+type PostUpdate struct {
+	BaseRevision int
+	EditorID     int
+	Revise       bool
+	Title        string
+	Content      string
+	CategoryID   int
+
+	Tags        []models.Tag
+	Status      models.PostStatus
+	PublishedAt *time.Time
+}
+
+// PostFilter - условия выборки постов; нулевые поля не фильтруют, кроме Status:
+// без него выбираются только опубликованные посты.
+// Before задаёт keyset пагинацию ленты: выбираются посты старше курсора.
+type PostFilter struct {
+	Status models.PostStatus
+	// CategoryID - категория вместе со всеми подкатегориями
+	CategoryID int
+	TagID      int
+	UserID     int
+	Before     *Cursor
+	Limit      int
+	Offset     int
+}
+
+// SearchHit - пост, найденный полнотекстовым поиском. В Snippet совпадения
+// обрамлены SnippetStart и SnippetEnd; Rank меньше у более релевантных.
+type SearchHit struct {
+	Post    models.Post
+	Snippet string
+	Rank    float64
+}
+
+// Маркеры совпадений в SearchHit.Snippet. Управляющие символы не встречаются
+// в тексте постов, поэтому после экранирования их можно заменить на теги.
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+// RevisionRepository - история версий постов
+type RevisionRepository interface {
+	GetByPostID(postID int) ([]models.PostRevision, error)
+	Get(postID, revision int) (*models.PostRevision, error)
+}
+
+// CommentRepository - хранилище комментариев
+type CommentRepository interface {
+	// ListByPost возвращает до limit одобренных комментариев верхнего уровня,
+	// начиная с новых; before - курсор последнего показанного комментария или nil
+	ListByPost(postID int, before *Cursor, limit int) ([]models.Comment, error)
+	// ListLatestByPosts - ListByPost без курсора для нескольких постов одним
+	// запросом: id поста -> до limit его комментариев, начиная с новых
+	ListLatestByPosts(postIDs []int, limit int) (map[int][]models.Comment, error)
+	// ListReplies возвращает все одобренные ответы в ветках комментариев parentIDs
+	// (на любой глубине) в порядке создания
+	ListReplies(parentIDs []int) ([]models.Comment, error)
+	// Depth возвращает уровень вложенности комментария: 0 у верхнего уровня
+	Depth(id int) (int, error)
+	GetByID(id int) (*models.Comment, error)
+	// Create сохраняет новый комментарий: PostID, ParentID, UserID, Author, Content,
+	// Status и поля спам фильтра (SpamScore, SpamReasons, IP)
+	Create(comment *models.Comment) (int64, error)
+	// ListByStatus возвращает комментарии в состоянии status для очереди модерации,
+	// начиная со старых
+	ListByStatus(status models.CommentStatus, limit, offset int) ([]models.Comment, error)
+	CountByStatus(status models.CommentStatus) (int, error)
+	// Moderate одной транзакцией переводит комментарии ids в status и переобучает
+	// ими спам фильтр по training: если что-то не сохранилось, не меняется ничего
+	Moderate(ids []int, status models.CommentStatus, training []SpamTraining) error
+	// MarkNotified отмечает, что автору поста сообщили о комментарии; false -
+	// отметка уже стояла и сообщать не нужно
+	MarkNotified(id int) (bool, error)
+	// HasApproved - есть ли у пользователя одобренные комментарии
+	HasApproved(userID int) (bool, error)
+	// Delete удаляет комментарий. Комментарий с ответами становится надгробием,
+	// без ответов - удаляется вместе с опустевшими удалёнными предками.
+	Delete(id int) (*CommentDeletion, error)
+}
+
+// CommentDeletion - итог удаления комментария. Tombstoned - комментарий оставлен
+// надгробием; иначе RemovedID - верхний из физически удалённых комментариев
+// (сам комментарий или его удалённый ранее предок, у которого не осталось ответов).
+type CommentDeletion struct {
+	Tombstoned bool
+	RemovedID  int
+}
+
+// CategoryRepository - хранилище категорий
+type CategoryRepository interface {
+	GetAll() ([]models.Category, error)
+	GetByID(id int) (*models.Category, error)
+	GetBySlug(slug string) (*models.Category, error)
+	GetByName(name string) (*models.Category, error)
+	Create(name, slug string, parentID *int) (int64, error)
+	Update(id int, name, slug string) error
+	// SetParent переносит категорию со всеми подкатегориями под parentID;
+	// nil - на верхний уровень. Циклы проверяет service слой.
+	SetParent(id int, parentID *int) error
+	// Delete удаляет категорию, переносит её посты и их ревизии в reassignTo,
+	// а подкатегории - к её родителю, и возвращает число перенесённых постов
+	Delete(id, reassignTo int) (int64, error)
+	// SetCommentModeration задаёт режим премодерации категории; nil - глобальный
+	SetCommentModeration(id int, mode *models.ModerationMode) error
+}
+
+// TagRepository - теги постов. Тег определяется адресом (slug): теги, названия
+// которых дают один адрес, считаются одним тегом.
+type TagRepository interface {
+	// GetAll возвращает все теги, включая теги без постов, по названию
+	GetAll() ([]models.Tag, error)
+	// List возвращает до limit тегов, у которых есть посты, начиная с самых
+	// популярных; prefix - начало адреса тега, пустой - любые теги
+	List(prefix string, limit int) ([]models.Tag, error)
+	GetByID(id int) (*models.Tag, error)
+	GetBySlug(slug string) (*models.Tag, error)
+	// ListByPosts возвращает теги постов postIDs по названию: id поста -> теги
+	ListByPosts(postIDs []int) (map[int][]models.Tag, error)
+	// SetPostTags заменяет теги поста на tags. Теги ищутся по Slug,
+	// ненайденные создаются с Name и Slug.
+	SetPostTags(postID int, tags []models.Tag) error
+	Rename(id int, name, slug string) error
+	// Merge переносит тег from на посты тега into и удаляет from
+	Merge(from, into int) error
+	// Delete удаляет тег и снимает его со всех постов
+	Delete(id int) error
+}
+
+// AttachmentRepository - записи о вложениях постов; сами файлы лежат в storage.Blob
+type AttachmentRepository interface {
+	GetByID(id int) (*models.Attachment, error)
+	// ListByPosts возвращает вложения постов postIDs в порядке загрузки:
+	// id поста -> вложения
+	ListByPosts(postIDs []int) (map[int][]models.Attachment, error)
+	CountByPost(postID int) (int, error)
+	// Create сохраняет PostID, UserID, Filename, ContentType, Size, StorageKey,
+	// ThumbKey, Width и Height
+	Create(attachment *models.Attachment) (int64, error)
+	Delete(id int) error
+}
+
+// SettingsRepository - настройки блога в виде пар ключ-значение
+type SettingsRepository interface {
+	// Get возвращает sql.ErrNoRows, если настройка не задана
+	Get(key string) (string, error)
+	Set(key, value string) error
+	// SetDefault сохраняет value, если настройка ещё не задана,
+	// и возвращает действующее значение
+	SetDefault(key, value string) (string, error)
+}
+
+// NotificationRepository - уведомления пользователей
+type NotificationRepository interface {
+	Create(userID int, postID, commentID *int, message string) (int64, error)
+	// ListByUser возвращает до limit последних уведомлений пользователя
+	ListByUser(userID, limit int) ([]models.Notification, error)
+	CountUnread(userID int) (int, error)
+	MarkAllRead(userID int) error
+}
+
+// SpamRepository - статистика байесовского спам фильтра
+type SpamRepository interface {
+	// TokenCounts возвращает для известных слов из tokens, в скольких спамных
+	// и нормальных комментариях они встречались
+	TokenCounts(tokens []string) (map[string]TokenCount, error)
+	// Documents - сколько спамных и нормальных комментариев в обучении
+	Documents() (spam, ham int, err error)
+}
+
+// SpamTraining - переобучение спам фильтра комментарием CommentID: обучение
+// меткой Previous отменяется, словами Tokens обучается метка Label, и она
+// запоминается в комментарии. Пустая метка - обучения нет.
+type SpamTraining struct {
+	CommentID int
+	Tokens    []string
+	Previous  models.SpamLabel
+	Label     models.SpamLabel
+}
+
+// TokenCount - в скольких спамных и нормальных комментариях встречалось слово
+type TokenCount struct {
+	Spam int
+	Ham  int
+}
+
+// LikeRepository - хранилище лайков. У поста не больше одного лайка
+// от каждого пользователя и каждого анонимного посетителя.
+type LikeRepository interface {
+	// Add ставит лайк; false - лайк от liker уже стоит
+	Add(postID int, liker Liker) (bool, error)
+	// Remove снимает лайк; false - лайка от liker не было
+	Remove(postID int, liker Liker) (bool, error)
+	// Liked возвращает, какие из постов postIDs лайкнул liker
+	Liked(liker Liker, postIDs []int) (map[int]bool, error)
+	GetByID(id int) (*models.Like, error)
+}
+
+// Liker - владелец лайка или реакции: вошедший пользователь (UserID) или, если
+// UserID нулевой, анонимный посетитель с id из подписанной cookie (VisitorID)
+type Liker struct {
+	UserID    int
+	VisitorID string
+}
+
+// Anonymous - владелец не известен: гость без cookie посетителя
+func (l Liker) Anonymous() bool {
+	return l.UserID == 0 && l.VisitorID == ""
+}
+
+// ReactionRepository - реакции на посты и комментарии. Счётчики хранятся
+// готовыми и обновляются вместе с реакциями.
+type ReactionRepository interface {
+	// Add ставит реакцию emoji; false - такая реакция от liker уже стоит
+	Add(target models.ReactionTarget, id int, emoji string, liker Liker) (bool, error)
+	// Remove снимает реакцию; false - её не было
+	Remove(target models.ReactionTarget, id int, emoji string, liker Liker) (bool, error)
+	// Counts возвращает счётчики реакций для целей ids: id -> emoji -> число
+	Counts(target models.ReactionTarget, ids []int) (map[int]map[string]int, error)
+	// Reacted возвращает, какие реакции на цели ids поставил liker
+	Reacted(target models.ReactionTarget, ids []int, liker Liker) (map[int]map[string]bool, error)
+	// List возвращает, кто поставил реакции на цель, начиная с последних;
+	// пустой emoji - реакции всех видов
+	List(target models.ReactionTarget, id int, emoji string, limit, offset int) ([]models.Reaction, error)
+	Count(target models.ReactionTarget, id int, emoji string) (int, error)
+}
+
+// UserRepository - хранилище пользователей
+type UserRepository interface {
+	Create(username, passwordHash string, role models.Role) (int64, error)
+	GetByID(id int) (*models.User, error)
+	GetByUsername(username string) (*models.User, error)
+	GetAll() ([]models.User, error)
+	Count() (int, error)
+	SetRole(id int, role models.Role) error
+}
+
+// SessionRepository - серверное хранилище сессий.
+// Хранится только хеш токена, сам токен есть лишь в cookie пользователя.
+type SessionRepository interface {
+	Create(tokenHash string, userID int, expiresAt time.Time) error
+	GetUser(tokenHash string) (*models.User, time.Time, error)
+	Delete(tokenHash string) error
+	DeleteExpired(now time.Time) (int64, error)
+}
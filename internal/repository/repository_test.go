@@ -0,0 +1,1056 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// Набор тестов, общий для SQLite и PostgreSQL: каждый тест выполняется
+// на обеих базах (PostgreSQL - с TEST_POSTGRES_DSN, см. пакет dbtest)
+
+// fixture - репозитории над тестовой базой и готовые автор и категория
+type fixture struct {
+	t          *testing.T
+	db         *database.DB
+	users      repository.UserRepository
+	posts      repository.PostRepository
+	revisions  repository.RevisionRepository
+	comments   repository.CommentRepository
+	categories repository.CategoryRepository
+	tags       repository.TagRepository
+	likes      repository.LikeRepository
+	reactions  repository.ReactionRepository
+
+	author   int
+	category int
+}
+
+func newFixture(t *testing.T, db *database.DB) *fixture {
+	t.Helper()
+	f := &fixture{
+		t:          t,
+		db:         db,
+		users:      repository.NewUserRepository(db),
+		posts:      repository.NewPostRepository(db),
+		revisions:  repository.NewRevisionRepository(db),
+		comments:   repository.NewCommentRepository(db),
+		categories: repository.NewCategoryRepository(db),
+		tags:       repository.NewTagRepository(db),
+		likes:      repository.NewLikeRepository(db),
+		reactions:  repository.NewReactionRepository(db),
+	}
+	f.author = f.user("author", models.RoleAuthor)
+	f.category = f.newCategory("Общие", "general", nil)
+	return f
+}
+
+func (f *fixture) user(name string, role models.Role) int {
+	f.t.Helper()
+	id, err := f.users.Create(name, "hash", role)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return int(id)
+}
+
+func (f *fixture) newCategory(name, slug string, parentID *int) int {
+	f.t.Helper()
+	id, err := f.categories.Create(name, slug, parentID)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return int(id)
+}
+
+func (f *fixture) post(title string, status models.PostStatus, publishedAt *time.Time) int {
+	f.t.Helper()
+	return f.postIn(f.category, title, status, publishedAt)
+}
+
+func (f *fixture) postIn(categoryID int, title string, status models.PostStatus, publishedAt *time.Time) int {
+	f.t.Helper()
+	if status == models.PostPublished && publishedAt == nil {
+		now := time.Now()
+		publishedAt = &now
+	}
+	id, err := f.posts.Create(f.author, title, "Текст поста "+title, categoryID, status, publishedAt)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return int(id)
+}
+
+func (f *fixture) comment(postID int, parentID *int, content string) int {
+	f.t.Helper()
+	id, err := f.comments.Create(&models.Comment{
+		PostID: postID, ParentID: parentID, Author: "гость", Content: content,
+		Status: models.CommentApproved,
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return int(id)
+}
+
+func (f *fixture) getPost(id int) *models.Post {
+	f.t.Helper()
+	post, err := f.posts.GetByID(id)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return post
+}
+
+func (f *fixture) getComment(id int) *models.Comment {
+	f.t.Helper()
+	comment, err := f.comments.GetByID(id)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return comment
+}
+
+func postIDs(posts []models.Post) []int {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+func commentIDs(comments []models.Comment) []int {
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	return ids
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUsers(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+
+		user, err := f.users.GetByUsername("author")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.ID != f.author || user.Role != models.RoleAuthor {
+			t.Errorf("GetByUsername: %+v", user)
+		}
+
+		if _, err := f.users.Create("author", "hash", models.RoleReader); err == nil {
+			t.Error("Create: повторное имя должно давать ошибку")
+		}
+
+		if err := f.users.SetRole(f.author, models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		if user, err = f.users.GetByID(f.author); err != nil || user.Role != models.RoleEditor {
+			t.Errorf("GetByID после SetRole: %+v, %v", user, err)
+		}
+
+		f.user("reader", models.RoleReader)
+		if n, err := f.users.Count(); err != nil || n != 2 {
+			t.Errorf("Count = %d, %v; want 2", n, err)
+		}
+		if all, err := f.users.GetAll(); err != nil || len(all) != 2 {
+			t.Errorf("GetAll: %d пользователей, %v", len(all), err)
+		}
+
+		if _, err := f.users.GetByUsername("nobody"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetByUsername(nobody) = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestPosts(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+
+		published := f.post("Опубликован", models.PostPublished, nil)
+		draft := f.post("Черновик", models.PostDraft, nil)
+
+		post := f.getPost(published)
+		if post.Title != "Опубликован" || post.Revision != 1 || post.AuthorName != "author" ||
+			post.Status != models.PostPublished || post.PublishedAt == nil || post.CreatedAt.IsZero() {
+			t.Errorf("GetByID: %+v", post)
+		}
+
+		// Без Status выбираются только опубликованные
+		list, err := f.posts.List(repository.PostFilter{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalInts(postIDs(list), []int{published}) {
+			t.Errorf("List = %v, want [%d]", postIDs(list), published)
+		}
+		list, err = f.posts.List(repository.PostFilter{Status: models.PostDraft, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalInts(postIDs(list), []int{draft}) {
+			t.Errorf("List(draft) = %v, want [%d]", postIDs(list), draft)
+		}
+		if n, err := f.posts.Count(repository.PostFilter{}); err != nil || n != 1 {
+			t.Errorf("Count = %d, %v; want 1", n, err)
+		}
+
+		// Правка с устаревшей ревизией не проходит
+		edit := func(title string) repository.PostUpdate {
+			return repository.PostUpdate{BaseRevision: 1, EditorID: f.author, Revise: true,
+				Title: title, Content: "Новый текст", CategoryID: f.category}
+		}
+		ok, err := f.posts.Update(published, edit("Новый заголовок"))
+		if err != nil || !ok {
+			t.Fatalf("Update = %v, %v", ok, err)
+		}
+		if ok, err = f.posts.Update(published, edit("Конфликт")); err != nil || ok {
+			t.Errorf("Update со старой ревизией = %v, %v; want false", ok, err)
+		}
+		post = f.getPost(published)
+		if post.Title != "Новый заголовок" || post.Revision != 2 {
+			t.Errorf("после Update: %q ревизия %d", post.Title, post.Revision)
+		}
+		revisions, err := f.revisions.GetByPostID(published)
+		if err != nil || len(revisions) != 2 {
+			t.Fatalf("GetByPostID: %d ревизий, %v", len(revisions), err)
+		}
+		first, err := f.revisions.Get(published, 1)
+		if err != nil || first.Title != "Опубликован" {
+			t.Errorf("Get(1): %+v, %v", first, err)
+		}
+
+		if err := f.posts.Delete(draft); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.posts.GetByID(draft); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetByID удалённого = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+// Правка, теги и состояние сохраняются вместе: ошибка на тегах откатывает всё
+func TestPostsUpdateAtomic(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		id := f.post("Черновик", models.PostDraft, nil)
+		if err := f.tags.SetPostTags(id, []models.Tag{{Name: "Go", Slug: "go"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Now()
+		update := repository.PostUpdate{
+			BaseRevision: 1, EditorID: f.author, Revise: true,
+			Title: "Новый заголовок", Content: "Новый текст", CategoryID: f.category,
+			// Один тег дважды нарушает первичный ключ post_tags
+			Tags:   []models.Tag{{Name: "SQL", Slug: "sql"}, {Name: "SQL", Slug: "sql"}},
+			Status: models.PostPublished, PublishedAt: &now,
+		}
+		if ok, err := f.posts.Update(id, update); err == nil {
+			t.Fatalf("Update с повторным тегом = %v, want ошибку", ok)
+		}
+		post := f.getPost(id)
+		if post.Title != "Черновик" || post.Revision != 1 || post.Status != models.PostDraft {
+			t.Errorf("после отката: %q ревизия %d, %s", post.Title, post.Revision, post.Status)
+		}
+		if revisions, err := f.revisions.GetByPostID(id); err != nil || len(revisions) != 1 {
+			t.Errorf("после отката %d ревизий, %v", len(revisions), err)
+		}
+		if tags, err := f.tags.ListByPosts([]int{id}); err != nil || len(tags[id]) != 1 || tags[id][0].Slug != "go" {
+			t.Errorf("теги после отката: %v, %v", tags[id], err)
+		}
+
+		update.Tags = []models.Tag{{Name: "SQL", Slug: "sql"}}
+		if ok, err := f.posts.Update(id, update); err != nil || !ok {
+			t.Fatalf("Update = %v, %v", ok, err)
+		}
+		post = f.getPost(id)
+		if post.Title != "Новый заголовок" || post.Revision != 2 || post.Status != models.PostPublished || post.PublishedAt == nil {
+			t.Errorf("после Update: %q ревизия %d, %s", post.Title, post.Revision, post.Status)
+		}
+		if tags, err := f.tags.ListByPosts([]int{id}); err != nil || len(tags[id]) != 1 || tags[id][0].Slug != "sql" {
+			t.Errorf("теги после Update: %v, %v", tags[id], err)
+		}
+	})
+}
+
+func TestPostsCounts(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		postID := f.post("Пост", models.PostPublished, nil)
+		other := f.post("Другой", models.PostPublished, nil)
+
+		root := f.comment(postID, nil, "первый")
+		f.comment(postID, &root, "ответ")
+		f.comment(other, nil, "к другому посту")
+		if _, err := f.comments.Create(&models.Comment{
+			PostID: postID, Author: "бот", Content: "спам", Status: models.CommentSpam,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		reader := f.user("reader", models.RoleReader)
+		for _, liker := range []repository.Liker{{UserID: f.author}, {UserID: reader}, {VisitorID: "v1"}} {
+			if _, err := f.likes.Add(postID, liker); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Лайки, поставленные до появления владельцев, ничьи и не мешают друг другу
+		for i := 0; i < 2; i++ {
+			if _, err := db.Exec(`INSERT INTO likes (post_id) VALUES (?)`, postID); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		post := f.getPost(postID)
+		if post.CommentsCount != 2 || post.LikesCount != 5 {
+			t.Errorf("счётчики: комментариев %d (want 2), лайков %d (want 5)", post.CommentsCount, post.LikesCount)
+		}
+
+		list, err := f.posts.List(repository.PostFilter{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range list {
+			if p.ID == postID && (p.CommentsCount != 2 || p.LikesCount != 5) {
+				t.Errorf("List: комментариев %d, лайков %d", p.CommentsCount, p.LikesCount)
+			}
+			if p.ID == other && (p.CommentsCount != 1 || p.LikesCount != 0) {
+				t.Errorf("List другого поста: комментариев %d, лайков %d", p.CommentsCount, p.LikesCount)
+			}
+		}
+	})
+}
+
+func TestPostsKeysetPagination(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+
+		// Два поста с одним временем публикации: порядок решает id
+		base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		var want []int
+		for i, offset := range []time.Duration{0, time.Hour, time.Hour, 2 * time.Hour, 3 * time.Hour} {
+			at := base.Add(offset)
+			want = append([]int{f.post(string(rune('A'+i)), models.PostPublished, &at)}, want...)
+		}
+
+		var got []int
+		filter := repository.PostFilter{Limit: 2}
+		for page := 0; page < 5; page++ {
+			list, err := f.posts.List(filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, postIDs(list)...)
+			if len(list) < filter.Limit {
+				break
+			}
+			last := list[len(list)-1]
+			filter.Before = repository.CursorOf(last.Date(), last.ID)
+		}
+		if !equalInts(got, want) {
+			t.Errorf("страницы = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestPostsSchedule(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		soon, later := now.Add(time.Hour), now.Add(2*time.Hour)
+		first := f.post("Скоро", models.PostScheduled, &soon)
+		f.post("Позже", models.PostScheduled, &later)
+
+		next, err := f.posts.NextScheduled()
+		if err != nil || next == nil || !next.Equal(soon) {
+			t.Fatalf("NextScheduled = %v, %v; want %v", next, err, soon)
+		}
+
+		if ids, err := f.posts.PublishDue(now); err != nil || len(ids) != 0 {
+			t.Errorf("PublishDue(now) = %v, %v; want пусто", ids, err)
+		}
+		ids, err := f.posts.PublishDue(soon.Add(time.Minute))
+		if err != nil || !equalInts(ids, []int{first}) {
+			t.Fatalf("PublishDue = %v, %v; want [%d]", ids, err, first)
+		}
+		if post := f.getPost(first); post.Status != models.PostPublished {
+			t.Errorf("статус после PublishDue: %s", post.Status)
+		}
+	})
+}
+
+func TestPostsCategoryFilter(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		tech := f.newCategory("Технологии", "tech", nil)
+		golang := f.newCategory("Go", "go", &tech)
+
+		inParent := f.postIn(tech, "Про технологии", models.PostPublished, nil)
+		inChild := f.postIn(golang, "Про Go", models.PostPublished, nil)
+		f.post("Общий", models.PostPublished, nil)
+
+		list, err := f.posts.List(repository.PostFilter{CategoryID: tech, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalInts(postIDs(list), []int{inChild, inParent}) {
+			t.Errorf("List(категория с подкатегорией) = %v, want [%d %d]", postIDs(list), inChild, inParent)
+		}
+	})
+}
+
+func TestPostsSearch(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		match := f.post("Горные маршруты", models.PostPublished, nil)
+		f.post("Рецепт борща", models.PostPublished, nil)
+		f.post("Горный черновик", models.PostDraft, nil)
+
+		hits, err := f.posts.Search([]string{"горн"}, repository.PostFilter{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) != 1 || hits[0].Post.ID != match || hits[0].Snippet == "" {
+			t.Fatalf("Search = %+v, want пост %d", hits, match)
+		}
+		if n, err := f.posts.CountSearch([]string{"горн"}, repository.PostFilter{}); err != nil || n != 1 {
+			t.Errorf("CountSearch = %d, %v; want 1", n, err)
+		}
+
+		// Комментарии тоже ищутся; счётчики - как у List, без размножения строк
+		f.comment(match, nil, "отличная тропа")
+		f.comment(match, nil, "и виды")
+		for _, liker := range []repository.Liker{{UserID: f.author}, {VisitorID: "v1"}, {VisitorID: "v2"}} {
+			if _, err := f.likes.Add(match, liker); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hits, err = f.posts.Search([]string{"троп"}, repository.PostFilter{Limit: 10})
+		if err != nil || len(hits) != 1 {
+			t.Fatalf("Search по комментарию: %d совпадений, %v", len(hits), err)
+		}
+		if post := hits[0].Post; post.CommentsCount != 2 || post.LikesCount != 3 || post.AuthorName != "author" {
+			t.Errorf("Search: комментариев %d (want 2), лайков %d (want 3), автор %q",
+				post.CommentsCount, post.LikesCount, post.AuthorName)
+		}
+	})
+}
+
+func TestComments(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		postID := f.post("Пост", models.PostPublished, nil)
+
+		root := f.comment(postID, nil, "корень")
+		reply := f.comment(postID, &root, "ответ")
+		deep := f.comment(postID, &reply, "ответ на ответ")
+		other := f.comment(postID, nil, "второй корень")
+
+		if depth, err := f.comments.Depth(deep); err != nil || depth != 2 {
+			t.Errorf("Depth = %d, %v; want 2", depth, err)
+		}
+
+		roots, err := f.comments.ListByPost(postID, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalInts(commentIDs(roots), []int{other, root}) {
+			t.Errorf("ListByPost = %v, want [%d %d]", commentIDs(roots), other, root)
+		}
+		replies, err := f.comments.ListReplies([]int{root})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalInts(commentIDs(replies), []int{reply, deep}) {
+			t.Errorf("ListReplies = %v, want [%d %d]", commentIDs(replies), reply, deep)
+		}
+
+		// Комментарий с ответами остаётся надгробием, без ответов - удаляется
+		// вместе с опустевшими надгробиями предков
+		deletion, err := f.comments.Delete(reply)
+		if err != nil || !deletion.Tombstoned {
+			t.Fatalf("Delete(с ответом) = %+v, %v; want надгробие", deletion, err)
+		}
+		if comment, err := f.comments.GetByID(reply); err != nil || !comment.Deleted() {
+			t.Errorf("надгробие: %+v, %v", comment, err)
+		}
+		deletion, err = f.comments.Delete(deep)
+		if err != nil || deletion.Tombstoned || deletion.RemovedID != reply {
+			t.Fatalf("Delete(последний ответ) = %+v, %v; want удалён %d", deletion, err, reply)
+		}
+		if _, err := f.comments.GetByID(reply); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("опустевшее надгробие осталось: %v", err)
+		}
+	})
+}
+
+// Последние комментарии нескольких постов - по limit на пост, без ответов
+// и неодобренных
+func TestCommentsLatestByPosts(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		first := f.post("Первый", models.PostPublished, nil)
+		second := f.post("Второй", models.PostPublished, nil)
+		empty := f.post("Без комментариев", models.PostPublished, nil)
+
+		var want []int
+		for i := 0; i < 4; i++ {
+			want = append([]int{f.comment(first, nil, "комментарий")}, want...)
+		}
+		root := f.comment(second, nil, "корень")
+		f.comment(second, &root, "ответ")
+		if _, err := f.comments.Create(&models.Comment{PostID: second, Author: "гость", Content: "спам", Status: models.CommentSpam}); err != nil {
+			t.Fatal(err)
+		}
+
+		latest, err := f.comments.ListLatestByPosts([]int{first, second, empty}, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalInts(commentIDs(latest[first]), want[:3]) {
+			t.Errorf("первый пост: %v, want %v", commentIDs(latest[first]), want[:3])
+		}
+		if !equalInts(commentIDs(latest[second]), []int{root}) {
+			t.Errorf("второй пост: %v, want [%d]", commentIDs(latest[second]), root)
+		}
+		if _, ok := latest[empty]; ok || len(latest) != 2 {
+			t.Errorf("ListLatestByPosts вернул посты %v", latest)
+		}
+
+		if latest, err := f.comments.ListLatestByPosts(nil, 3); err != nil || len(latest) != 0 {
+			t.Errorf("ListLatestByPosts(nil) = %v, %v", latest, err)
+		}
+	})
+}
+
+func TestCommentsPagination(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		postID := f.post("Пост", models.PostPublished, nil)
+
+		var want []int
+		for i := 0; i < 5; i++ {
+			want = append([]int{f.comment(postID, nil, "комментарий")}, want...)
+		}
+
+		var got []int
+		var before *repository.Cursor
+		for page := 0; page < 5; page++ {
+			comments, err := f.comments.ListByPost(postID, before, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, commentIDs(comments)...)
+			if len(comments) < 2 {
+				break
+			}
+			last := comments[len(comments)-1]
+			if before, err = repository.ParseCursor(repository.CursorOf(last.CreatedAt, last.ID).String()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !equalInts(got, want) {
+			t.Errorf("страницы = %v, want %v", got, want)
+		}
+	})
+}
+
+// PostgreSQL хранит время с микросекундами: комментарии одной секунды
+// не должны теряться или повторяться на границе страниц
+func TestCommentsPaginationSubsecond(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		if db.Dialect != database.DialectPostgres {
+			t.Skip("SQLite хранит CURRENT_TIMESTAMP с точностью до секунды")
+		}
+		f := newFixture(t, db)
+		postID := f.post("Пост", models.PostPublished, nil)
+
+		// id растут, а время - нет: порядок по времени обратный порядку id
+		base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		var want []int
+		for i := 0; i < 5; i++ {
+			var id int
+			err := db.QueryRow(`
+				INSERT INTO comments (post_id, author, content, status, created_at)
+				VALUES (?, 'гость', 'комментарий', 'approved', ?)
+				RETURNING id
+			`, postID, base.Add(time.Duration(900-i*100)*time.Millisecond)).Scan(&id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, id)
+		}
+
+		var got []int
+		var before *repository.Cursor
+		for page := 0; page < 5; page++ {
+			comments, err := f.comments.ListByPost(postID, before, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, commentIDs(comments)...)
+			if len(comments) < 2 {
+				break
+			}
+			last := comments[len(comments)-1]
+			if before, err = repository.ParseCursor(repository.CursorOf(last.CreatedAt, last.ID).String()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !equalInts(got, want) {
+			t.Errorf("страницы = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestCommentsModeration(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		postID := f.post("Пост", models.PostPublished, nil)
+		reader := f.user("reader", models.RoleReader)
+
+		id, err := f.comments.Create(&models.Comment{
+			PostID: postID, UserID: &reader, Author: "reader", Content: "жду проверки",
+			Status: models.CommentPending, SpamScore: 0.5, SpamReasons: "ссылки", IP: "10.0.0.1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pending, err := f.comments.ListByStatus(models.CommentPending, 10, 0)
+		if err != nil || len(pending) != 1 || pending[0].IP != "10.0.0.1" || pending[0].SpamScore != 0.5 {
+			t.Fatalf("ListByStatus(pending) = %+v, %v", pending, err)
+		}
+		if ok, err := f.comments.HasApproved(reader); err != nil || ok {
+			t.Errorf("HasApproved до одобрения = %v, %v", ok, err)
+		}
+
+		training := []repository.SpamTraining{{CommentID: int(id), Tokens: []string{"жду", "проверки"}, Label: models.SpamLabelHam}}
+		if err := f.comments.Moderate([]int{int(id)}, models.CommentApproved, training); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := f.comments.CountByStatus(models.CommentPending); err != nil || n != 0 {
+			t.Errorf("CountByStatus(pending) = %d, %v; want 0", n, err)
+		}
+		if ok, err := f.comments.HasApproved(reader); err != nil || !ok {
+			t.Errorf("HasApproved после одобрения = %v, %v", ok, err)
+		}
+		if comment, err := f.comments.GetByID(int(id)); err != nil || comment.SpamLabel == nil || *comment.SpamLabel != models.SpamLabelHam {
+			t.Errorf("метка после Moderate: %+v, %v", comment, err)
+		}
+
+		// Об одобрении сообщают один раз, даже если комментарий одобрят снова
+		for i, want := range []bool{true, false} {
+			if first, err := f.comments.MarkNotified(int(id)); err != nil || first != want {
+				t.Errorf("MarkNotified #%d = %v, %v; want %v", i+1, first, err, want)
+			}
+		}
+	})
+}
+
+func TestLikes(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		postID := f.post("Пост", models.PostPublished, nil)
+		other := f.post("Другой", models.PostPublished, nil)
+
+		for _, liker := range []repository.Liker{{UserID: f.author}, {VisitorID: "visitor"}} {
+			if added, err := f.likes.Add(postID, liker); err != nil || !added {
+				t.Fatalf("Add(%+v) = %v, %v", liker, added, err)
+			}
+			if added, err := f.likes.Add(postID, liker); err != nil || added {
+				t.Errorf("повторный Add(%+v) = %v, %v; want false", liker, added, err)
+			}
+
+			liked, err := f.likes.Liked(liker, []int{postID, other})
+			if err != nil || !liked[postID] || liked[other] {
+				t.Errorf("Liked(%+v) = %v, %v", liker, liked, err)
+			}
+
+			if removed, err := f.likes.Remove(postID, liker); err != nil || !removed {
+				t.Errorf("Remove(%+v) = %v, %v", liker, removed, err)
+			}
+			if removed, err := f.likes.Remove(postID, liker); err != nil || removed {
+				t.Errorf("повторный Remove(%+v) = %v, %v; want false", liker, removed, err)
+			}
+		}
+
+		if liked, err := f.likes.Liked(repository.Liker{}, []int{postID}); err != nil || len(liked) != 0 {
+			t.Errorf("Liked(аноним) = %v, %v; want пусто", liked, err)
+		}
+	})
+}
+
+func TestReactions(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		postID := f.post("Пост", models.PostPublished, nil)
+		visitor := repository.Liker{VisitorID: "visitor"}
+		user := repository.Liker{UserID: f.author}
+
+		for _, r := range []struct {
+			liker repository.Liker
+			emoji string
+		}{{user, "👍"}, {visitor, "👍"}, {visitor, "❤️"}} {
+			if added, err := f.reactions.Add(models.ReactionOnPost, postID, r.emoji, r.liker); err != nil || !added {
+				t.Fatalf("Add(%s) = %v, %v", r.emoji, added, err)
+			}
+		}
+		if added, err := f.reactions.Add(models.ReactionOnPost, postID, "👍", user); err != nil || added {
+			t.Errorf("повторный Add = %v, %v; want false", added, err)
+		}
+
+		counts, err := f.reactions.Counts(models.ReactionOnPost, []int{postID})
+		if err != nil || counts[postID]["👍"] != 2 || counts[postID]["❤️"] != 1 {
+			t.Errorf("Counts = %v, %v", counts, err)
+		}
+		reacted, err := f.reactions.Reacted(models.ReactionOnPost, []int{postID}, visitor)
+		if err != nil || !reacted[postID]["❤️"] || !reacted[postID]["👍"] {
+			t.Errorf("Reacted = %v, %v", reacted, err)
+		}
+
+		list, err := f.reactions.List(models.ReactionOnPost, postID, "👍", 10, 0)
+		if err != nil || len(list) != 2 {
+			t.Errorf("List(👍): %d реакций, %v", len(list), err)
+		}
+		if n, err := f.reactions.Count(models.ReactionOnPost, postID, ""); err != nil || n != 3 {
+			t.Errorf("Count = %d, %v; want 3", n, err)
+		}
+
+		if removed, err := f.reactions.Remove(models.ReactionOnPost, postID, "👍", user); err != nil || !removed {
+			t.Errorf("Remove = %v, %v", removed, err)
+		}
+		counts, err = f.reactions.Counts(models.ReactionOnPost, []int{postID})
+		if err != nil || counts[postID]["👍"] != 1 {
+			t.Errorf("Counts после Remove = %v, %v", counts, err)
+		}
+
+		// Реакции на комментарии считаются отдельно от реакций на посты
+		commentID := f.comment(postID, nil, "комментарий")
+		if _, err := f.reactions.Add(models.ReactionOnComment, commentID, "😂", visitor); err != nil {
+			t.Fatal(err)
+		}
+		counts, err = f.reactions.Counts(models.ReactionOnComment, []int{commentID})
+		if err != nil || counts[commentID]["😂"] != 1 || counts[commentID]["❤️"] != 0 {
+			t.Errorf("Counts(comment) = %v, %v", counts, err)
+		}
+	})
+}
+
+func TestCategories(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		parent := f.newCategory("Путешествия", "travel", nil)
+		child := f.newCategory("Горы", "mountains", &parent)
+		grandchild := f.newCategory("Кавказ", "caucasus", &child)
+
+		category, err := f.categories.GetBySlug("mountains")
+		if err != nil || category.ID != child || category.ParentID == nil || *category.ParentID != parent {
+			t.Fatalf("GetBySlug = %+v, %v", category, err)
+		}
+		if _, err := f.categories.GetByName("Горы"); err != nil {
+			t.Errorf("GetByName: %v", err)
+		}
+
+		if err := f.categories.Update(child, "Высокие горы", "high-mountains"); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.categories.SetParent(child, nil); err != nil {
+			t.Fatal(err)
+		}
+		if category, err = f.categories.GetByID(child); err != nil || category.ParentID != nil || category.Slug != "high-mountains" {
+			t.Errorf("после Update и SetParent: %+v, %v", category, err)
+		}
+
+		mode := models.ModerationAll
+		if err := f.categories.SetCommentModeration(child, &mode); err != nil {
+			t.Fatal(err)
+		}
+		if category, err = f.categories.GetByID(child); err != nil || category.CommentModeration == nil || *category.CommentModeration != mode {
+			t.Errorf("SetCommentModeration: %+v, %v", category, err)
+		}
+
+		// Посты удалённой категории переходят в другую, подкатегории - к её родителю
+		postID := f.postIn(child, "Пост", models.PostPublished, nil)
+		moved, err := f.categories.Delete(child, f.category)
+		if err != nil || moved != 1 {
+			t.Fatalf("Delete = %d, %v; want 1", moved, err)
+		}
+		if post := f.getPost(postID); post.CategoryID != f.category {
+			t.Errorf("пост в категории %d, want %d", post.CategoryID, f.category)
+		}
+		if category, err = f.categories.GetByID(grandchild); err != nil || category.ParentID != nil {
+			t.Errorf("подкатегория после удаления родителя: %+v, %v", category, err)
+		}
+
+		all, err := f.categories.GetAll()
+		if err != nil || len(all) != 3 {
+			t.Errorf("GetAll: %d категорий, %v; want 3", len(all), err)
+		}
+	})
+}
+
+func TestTags(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		first := f.post("Первый", models.PostPublished, nil)
+		second := f.post("Второй", models.PostPublished, nil)
+
+		if err := f.tags.SetPostTags(first, []models.Tag{{Name: "Go", Slug: "go"}, {Name: "SQL", Slug: "sql"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.tags.SetPostTags(second, []models.Tag{{Name: "go", Slug: "go"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		byPost, err := f.tags.ListByPosts([]int{first, second})
+		if err != nil || len(byPost[first]) != 2 || len(byPost[second]) != 1 {
+			t.Fatalf("ListByPosts = %v, %v", byPost, err)
+		}
+		popular, err := f.tags.List("", 10)
+		if err != nil || len(popular) != 2 || popular[0].Slug != "go" || popular[0].PostsCount != 2 {
+			t.Errorf("List = %+v, %v", popular, err)
+		}
+		if prefixed, err := f.tags.List("s", 10); err != nil || len(prefixed) != 1 || prefixed[0].Slug != "sql" {
+			t.Errorf("List(s) = %+v, %v", prefixed, err)
+		}
+
+		golang, err := f.tags.GetBySlug("go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlTag, err := f.tags.GetBySlug("sql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.tags.Rename(sqlTag.ID, "Базы данных", "databases"); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.tags.Merge(golang.ID, sqlTag.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.tags.GetByID(golang.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("тег после Merge: %v", err)
+		}
+		if byPost, err = f.tags.ListByPosts([]int{first, second}); err != nil || len(byPost[first]) != 1 || len(byPost[second]) != 1 {
+			t.Errorf("ListByPosts после Merge = %v, %v", byPost, err)
+		}
+
+		if err := f.tags.Delete(sqlTag.ID); err != nil {
+			t.Fatal(err)
+		}
+		if all, err := f.tags.GetAll(); err != nil || len(all) != 0 {
+			t.Errorf("GetAll после Delete = %+v, %v", all, err)
+		}
+	})
+}
+
+func TestAttachments(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		attachments := repository.NewAttachmentRepository(db)
+		postID := f.post("Пост", models.PostPublished, nil)
+
+		id, err := attachments.Create(&models.Attachment{
+			PostID: postID, UserID: &f.author, Filename: "photo.jpg", ContentType: "image/jpeg",
+			Size: 1024, StorageKey: "posts/1/photo.jpg", ThumbKey: "posts/1/thumb.jpg", Width: 640, Height: 480,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		attachment, err := attachments.GetByID(int(id))
+		if err != nil || attachment.Filename != "photo.jpg" || attachment.Size != 1024 || attachment.Width != 640 {
+			t.Fatalf("GetByID = %+v, %v", attachment, err)
+		}
+		byPost, err := attachments.ListByPosts([]int{postID})
+		if err != nil || len(byPost[postID]) != 1 {
+			t.Errorf("ListByPosts = %v, %v", byPost, err)
+		}
+		if n, err := attachments.CountByPost(postID); err != nil || n != 1 {
+			t.Errorf("CountByPost = %d, %v; want 1", n, err)
+		}
+
+		if err := attachments.Delete(int(id)); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := attachments.CountByPost(postID); err != nil || n != 0 {
+			t.Errorf("CountByPost после Delete = %d, %v; want 0", n, err)
+		}
+	})
+}
+
+func TestSettings(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		settings := repository.NewSettingsRepository(db)
+
+		if _, err := settings.Get("moderation"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Get незаданной = %v, want sql.ErrNoRows", err)
+		}
+		if err := settings.Set("moderation", "all"); err != nil {
+			t.Fatal(err)
+		}
+		if err := settings.Set("moderation", "off"); err != nil {
+			t.Fatal(err)
+		}
+		if value, err := settings.Get("moderation"); err != nil || value != "off" {
+			t.Errorf("Get = %q, %v; want off", value, err)
+		}
+
+		if value, err := settings.SetDefault("secret", "first"); err != nil || value != "first" {
+			t.Errorf("SetDefault = %q, %v; want first", value, err)
+		}
+		if value, err := settings.SetDefault("secret", "second"); err != nil || value != "first" {
+			t.Errorf("повторный SetDefault = %q, %v; want first", value, err)
+		}
+	})
+}
+
+func TestSessions(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		sessions := repository.NewSessionRepository(db)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		if err := sessions.Create("active", f.author, now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := sessions.Create("expired", f.author, now.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+
+		user, expiresAt, err := sessions.GetUser("active")
+		if err != nil || user.ID != f.author || !expiresAt.Equal(now.Add(time.Hour)) {
+			t.Fatalf("GetUser = %+v, %v, %v", user, expiresAt, err)
+		}
+
+		if n, err := sessions.DeleteExpired(now); err != nil || n != 1 {
+			t.Errorf("DeleteExpired = %d, %v; want 1", n, err)
+		}
+		if err := sessions.Delete("active"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := sessions.GetUser("active"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetUser удалённой = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestNotifications(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		notifications := repository.NewNotificationRepository(db)
+		postID := f.post("Пост", models.PostPublished, nil)
+		commentID := f.comment(postID, nil, "комментарий")
+
+		if _, err := notifications.Create(f.author, &postID, &commentID, "Новый комментарий"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := notifications.Create(f.author, nil, nil, "Комментарий одобрен"); err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := notifications.ListByUser(f.author, 10)
+		if err != nil || len(list) != 2 {
+			t.Fatalf("ListByUser: %d уведомлений, %v", len(list), err)
+		}
+		if n, err := notifications.CountUnread(f.author); err != nil || n != 2 {
+			t.Errorf("CountUnread = %d, %v; want 2", n, err)
+		}
+		if err := notifications.MarkAllRead(f.author); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := notifications.CountUnread(f.author); err != nil || n != 0 {
+			t.Errorf("CountUnread после MarkAllRead = %d, %v; want 0", n, err)
+		}
+	})
+}
+
+func TestSpam(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		spam := repository.NewSpamRepository(db)
+		postID := f.post("Пост", models.PostPublished, nil)
+		first := f.comment(postID, nil, "casino bonus")
+		second := f.comment(postID, nil, "casino статья")
+		third := f.comment(postID, nil, "статья")
+
+		moderate := func(ids []int, status models.CommentStatus, training ...repository.SpamTraining) error {
+			t.Helper()
+			return f.comments.Moderate(ids, status, training)
+		}
+		if err := moderate([]int{first, second}, models.CommentSpam,
+			repository.SpamTraining{CommentID: first, Tokens: []string{"casino", "bonus"}, Label: models.SpamLabelSpam},
+			repository.SpamTraining{CommentID: second, Tokens: []string{"casino", "статья"}, Label: models.SpamLabelSpam},
+		); err != nil {
+			t.Fatal(err)
+		}
+		if err := moderate([]int{third}, models.CommentApproved,
+			repository.SpamTraining{CommentID: third, Tokens: []string{"статья"}, Label: models.SpamLabelHam},
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		counts, err := spam.TokenCounts([]string{"casino", "статья", "unknown"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts["casino"] != (repository.TokenCount{Spam: 2}) || counts["статья"] != (repository.TokenCount{Spam: 1, Ham: 1}) {
+			t.Errorf("TokenCounts = %v", counts)
+		}
+		if _, ok := counts["unknown"]; ok {
+			t.Error("TokenCounts вернул незнакомое слово")
+		}
+
+		// Отклонённый комментарий больше ничему не учит; слова, которые
+		// нигде не встречаются, удаляются
+		if err := moderate([]int{second}, models.CommentRejected,
+			repository.SpamTraining{CommentID: second, Tokens: []string{"casino", "статья"}, Previous: models.SpamLabelSpam},
+		); err != nil {
+			t.Fatal(err)
+		}
+		spamDocs, hamDocs, err := spam.Documents()
+		if err != nil || spamDocs != 1 || hamDocs != 1 {
+			t.Errorf("Documents = %d, %d, %v; want 1, 1", spamDocs, hamDocs, err)
+		}
+		if comment := f.getComment(second); comment.SpamLabel != nil || comment.Status != models.CommentRejected {
+			t.Errorf("после отклонения: состояние %s, метка %v", comment.Status, comment.SpamLabel)
+		}
+
+		// Ошибка обучения откатывает и смену состояния
+		err = moderate([]int{first, third}, models.CommentRejected,
+			repository.SpamTraining{CommentID: first, Tokens: []string{"casino", "bonus"}, Previous: models.SpamLabelSpam},
+			repository.SpamTraining{CommentID: third, Tokens: []string{"статья"}, Label: "неизвестная"},
+		)
+		if err == nil {
+			t.Fatal("Moderate с неизвестной меткой не вернул ошибку")
+		}
+		if comment := f.getComment(first); comment.Status != models.CommentSpam || comment.SpamLabel == nil {
+			t.Errorf("после отката: состояние %s, метка %v", comment.Status, comment.SpamLabel)
+		}
+		if counts, err := spam.TokenCounts([]string{"casino"}); err != nil || counts["casino"] != (repository.TokenCount{Spam: 1}) {
+			t.Errorf("после отката TokenCounts = %v, %v", counts, err)
+		}
+	})
+}
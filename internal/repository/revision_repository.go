@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+// Ревизии только читаются: новые версии записывает postRepository
+// в той же транзакции, что и изменение поста.
+type revisionRepository struct {
+	db *database.DB
+}
+
+func NewRevisionRepository(db *database.DB) RevisionRepository {
+	return &revisionRepository{db: db}
+}
+
+const revisionSelect = `
+	SELECT r.id, r.post_id, r.revision, r.title, r.content, r.category_id, r.editor_id, r.created_at,
+	       COALESCE(u.username, '') as editor_name
+	FROM post_revisions r
+	LEFT JOIN users u ON u.id = r.editor_id
+`
+
+func scanRevision(row rowScanner) (models.PostRevision, error) {
+	var rev models.PostRevision
+	err := row.Scan(&rev.ID, &rev.PostID, &rev.Revision, &rev.Title, &rev.Content,
+		&rev.CategoryID, &rev.EditorID, &rev.CreatedAt, &rev.EditorName)
+	return rev, err
+}
+
+// GetByPostID возвращает ревизии поста, начиная с последней
+func (r *revisionRepository) GetByPostID(postID int) ([]models.PostRevision, error) {
+	query := revisionSelect + `
+		WHERE r.post_id = ?
+		ORDER BY r.revision DESC
+	`
+
+	rows, err := r.db.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.PostRevision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (r *revisionRepository) Get(postID, revision int) (*models.PostRevision, error) {
+	query := revisionSelect + `WHERE r.post_id = ? AND r.revision = ?`
+
+	rev, err := scanRevision(r.db.QueryRow(query, postID, revision))
+	if err != nil {
+		return nil, err
+	}
+
+	return &rev, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+type sessionRepository struct {
+	db *database.DB
+}
+
+func NewSessionRepository(db *database.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(tokenHash string, userID int, expiresAt time.Time) error {
+	query := `
+		INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP, ?)
+	`
+
+	_, err := r.db.Exec(query, tokenHash, userID, expiresAt.UTC())
+	return err
+}
+
+// GetUser возвращает владельца сессии и срок её действия
+func (r *sessionRepository) GetUser(tokenHash string) (*models.User, time.Time, error) {
+	query := `
+		SELECT u.id, u.username, u.password_hash, u.role, u.created_at, s.expires_at
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = ?
+	`
+
+	var user models.User
+	var expiresAt time.Time
+	err := r.db.QueryRow(query, tokenHash).Scan(&user.ID, &user.Username,
+		&user.PasswordHash, &user.Role, &user.CreatedAt, &expiresAt)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return &user, expiresAt, nil
+}
+
+func (r *sessionRepository) Delete(tokenHash string) error {
+	_, err := r.db.Exec(`DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	return err
+}
+
+func (r *sessionRepository) DeleteExpired(now time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM sessions WHERE expires_at < ?`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"github.com/s.usynin/testing/go-server/internal/database"
+)
+
+type settingsRepository struct {
+	db *database.DB
+}
+
+func NewSettingsRepository(db *database.DB) SettingsRepository {
+	return &settingsRepository{db: db}
+}
+
+func (r *settingsRepository) Get(key string) (string, error) {
+	query := `SELECT value FROM settings WHERE key = ?`
+
+	var value string
+	err := r.db.QueryRow(query, key).Scan(&value)
+	return value, err
+}
+
+func (r *settingsRepository) Set(key, value string) error {
+	query := `
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value
+	`
+
+	_, err := r.db.Exec(query, key, value)
+	return err
+}
+
+func (r *settingsRepository) SetDefault(key, value string) (string, error) {
+	query := `
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(query, key, value); err != nil {
+		return "", err
+	}
+	return r.Get(key)
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+type spamRepository struct {
+	db *database.DB
+}
+
+func NewSpamRepository(db *database.DB) SpamRepository {
+	return &spamRepository{db: db}
+}
+
+func (r *spamRepository) TokenCounts(tokens []string) (map[string]TokenCount, error) {
+	counts := make(map[string]TokenCount)
+	if len(tokens) == 0 {
+		return counts, nil
+	}
+
+	args := make([]any, len(tokens))
+	for i, token := range tokens {
+		args[i] = token
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tokens)), ", ")
+
+	query := `SELECT token, spam_count, ham_count FROM spam_tokens WHERE token IN (` + placeholders + `)`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			token string
+			count TokenCount
+		)
+		if err := rows.Scan(&token, &count.Spam, &count.Ham); err != nil {
+			return nil, err
+		}
+		counts[token] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *spamRepository) Documents() (int, int, error) {
+	rows, err := r.db.Query(`SELECT label, documents FROM spam_corpus`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var spam, ham int
+	for rows.Next() {
+		var (
+			label     models.SpamLabel
+			documents int
+		)
+		if err := rows.Scan(&label, &documents); err != nil {
+			return 0, 0, err
+		}
+		switch label {
+		case models.SpamLabelSpam:
+			spam = documents
+		case models.SpamLabelHam:
+			ham = documents
+		}
+	}
+
+	return spam, ham, rows.Err()
+}
+
+// learnTokens прибавляет delta (1 - обучить, -1 - отменить обучение)
+// к счётчикам слов tokens и документов метки label
+func learnTokens(tx *database.Tx, tokens []string, label models.SpamLabel, delta int) error {
+	// Имя колонки нельзя передать плейсхолдером, поэтому метка проверяется здесь
+	var column string
+	switch label {
+	case models.SpamLabelSpam:
+		column = "spam_count"
+	case models.SpamLabelHam:
+		column = "ham_count"
+	default:
+		return fmt.Errorf("неизвестная метка спам фильтра: %q", label)
+	}
+
+	tokenQuery := `
+		INSERT INTO spam_tokens (token, ` + column + `) VALUES (?, ?)
+		ON CONFLICT (token) DO UPDATE SET ` + column + ` = spam_tokens.` + column + ` + excluded.` + column
+	for _, token := range tokens {
+		if _, err := tx.Exec(tokenQuery, token, delta); err != nil {
+			return err
+		}
+	}
+
+	var documents int
+	err := tx.QueryRow(`SELECT documents FROM spam_corpus WHERE label = ?`, label).Scan(&documents)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO spam_corpus (label, documents) VALUES (?, ?)
+		ON CONFLICT (label) DO UPDATE SET documents = excluded.documents
+	`, label, max(documents+delta, 0))
+	if err != nil {
+		return err
+	}
+
+	// Слова, которые больше не встречаются ни в одном обученном комментарии
+	if delta < 0 {
+		if _, err := tx.Exec(`DELETE FROM spam_tokens WHERE spam_count <= 0 AND ham_count <= 0`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+type tagRepository struct {
+	db *database.DB
+}
+
+func NewTagRepository(db *database.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+// tagPostsCount - число опубликованных постов с тегом tags.id
+const tagPostsCount = `(SELECT COUNT(*) FROM post_tags JOIN posts ON posts.id = post_tags.post_id
+	WHERE post_tags.tag_id = tags.id AND posts.status = 'published')`
+
+const tagColumns = `id, name, slug, created_at, ` + tagPostsCount + ` AS posts_count`
+
+func scanTag(row rowScanner) (*models.Tag, error) {
+	var tag models.Tag
+	err := row.Scan(&tag.ID, &tag.Name, &tag.Slug, &tag.CreatedAt, &tag.PostsCount)
+	return &tag, err
+}
+
+func (r *tagRepository) GetAll() ([]models.Tag, error) {
+	return r.queryTags(`SELECT ` + tagColumns + ` FROM tags ORDER BY name ASC`)
+}
+
+func (r *tagRepository) List(prefix string, limit int) ([]models.Tag, error) {
+	query := `
+		SELECT ` + tagColumns + ` FROM tags
+		WHERE slug LIKE ? AND ` + tagPostsCount + ` > 0
+		ORDER BY posts_count DESC, name ASC
+		LIMIT ?
+	`
+	// В адресе только латиница, цифры и дефисы, экранировать % и _ не нужно
+	return r.queryTags(query, prefix+"%", limit)
+}
+
+func (r *tagRepository) GetByID(id int) (*models.Tag, error) {
+	tag, err := scanTag(r.db.QueryRow(`SELECT `+tagColumns+` FROM tags WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (r *tagRepository) GetBySlug(slug string) (*models.Tag, error) {
+	tag, err := scanTag(r.db.QueryRow(`SELECT `+tagColumns+` FROM tags WHERE slug = ?`, slug))
+	if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (r *tagRepository) ListByPosts(postIDs []int) (map[int][]models.Tag, error) {
+	tags := make(map[int][]models.Tag)
+	if len(postIDs) == 0 {
+		return tags, nil
+	}
+
+	args := make([]any, len(postIDs))
+	for i, id := range postIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(postIDs)), ", ")
+
+	query := `
+		SELECT pt.post_id, tags.id, tags.name, tags.slug, tags.created_at, ` + tagPostsCount + ` AS posts_count
+		FROM post_tags pt
+		JOIN tags ON tags.id = pt.tag_id
+		WHERE pt.post_id IN (` + placeholders + `)
+		ORDER BY tags.name ASC
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var tag models.Tag
+		if err := rows.Scan(&postID, &tag.ID, &tag.Name, &tag.Slug, &tag.CreatedAt, &tag.PostsCount); err != nil {
+			return nil, err
+		}
+		tags[postID] = append(tags[postID], tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (r *tagRepository) SetPostTags(postID int, tags []models.Tag) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setPostTags(tx, postID, tags); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setPostTags заменяет теги поста внутри транзакции tx, см. SetPostTags
+func setPostTags(tx *database.Tx, postID int, tags []models.Tag) error {
+	if _, err := tx.Exec(`DELETE FROM post_tags WHERE post_id = ?`, postID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		var id int64
+		err := tx.QueryRow(`SELECT id FROM tags WHERE slug = ?`, tag.Slug).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			query := `INSERT INTO tags (name, slug, created_at) VALUES (?, ?, CURRENT_TIMESTAMP) RETURNING id`
+			err = tx.QueryRow(query, tag.Name, tag.Slug).Scan(&id)
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`INSERT INTO post_tags (post_id, tag_id) VALUES (?, ?)`, postID, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *tagRepository) Rename(id int, name, slug string) error {
+	_, err := r.db.Exec(`UPDATE tags SET name = ?, slug = ? WHERE id = ?`, name, slug, id)
+	return err
+}
+
+func (r *tagRepository) Merge(from, into int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Посты, у которых уже есть оба тега, просто теряют тег from
+	query := `
+		INSERT INTO post_tags (post_id, tag_id)
+		SELECT post_id, ? FROM post_tags
+		WHERE tag_id = ? AND post_id NOT IN (SELECT post_id FROM post_tags WHERE tag_id = ?)
+	`
+	if _, err := tx.Exec(query, into, from, into); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM tags WHERE id = ?`, from); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *tagRepository) Delete(id int) error {
+	_, err := r.db.Exec(`DELETE FROM tags WHERE id = ?`, id)
+	return err
+}
+
+func (r *tagRepository) queryTags(query string, args ...any) ([]models.Tag, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, *tag)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+type userRepository struct {
+	db *database.DB
+}
+
+func NewUserRepository(db *database.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) Create(username, passwordHash string, role models.Role) (int64, error) {
+	query := `
+		INSERT INTO users (username, password_hash, role, created_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(query, username, passwordHash, role).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *userRepository) GetByID(id int) (*models.User, error) {
+	query := `SELECT id, username, password_hash, role, created_at FROM users WHERE id = ?`
+	return r.getOne(query, id)
+}
+
+func (r *userRepository) GetByUsername(username string) (*models.User, error) {
+	query := `SELECT id, username, password_hash, role, created_at FROM users WHERE username = ?`
+	return r.getOne(query, username)
+}
+
+func (r *userRepository) getOne(query string, arg any) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(query, arg).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) GetAll() ([]models.User, error) {
+	query := `SELECT id, username, password_hash, role, created_at FROM users ORDER BY username ASC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *userRepository) Count() (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	return count, err
+}
+
+func (r *userRepository) SetRole(id int, role models.Role) error {
+	_, err := r.db.Exec(`UPDATE users SET role = ? WHERE id = ?`, role, id)
+	return err
+}
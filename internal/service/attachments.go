@@ -0,0 +1,420 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/storage"
+	"github.com/s.usynin/testing/go-server/internal/thumbnail"
+)
+
+const (
+	// DefaultMaxAttachmentSize - предельный размер одного файла по умолчанию
+	DefaultMaxAttachmentSize = 10 << 20
+	// MaxAttachmentsPerPost - файлов у одного поста
+	MaxAttachmentsPerPost = 20
+	// thumbnailSize - сторона квадрата, в который вписываются превью
+	thumbnailSize = 320
+	// maxFilenameLength - символов в имени файла, остальное обрезается
+	maxFilenameLength = 120
+)
+
+// attachmentTypes - типы файлов, которые можно прикрепить, и расширения
+// для ключей в хранилище. Тип определяется по содержимому, а не по имени
+// файла: HTML и SVG не принимаются, чтобы вложения не исполняли скрипты
+// на домене блога.
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// Upload - файл из формы или запроса API. Content читается по частям
+// и целиком в память не загружается.
+type Upload struct {
+	Filename string
+	Size     int64
+	Content  io.ReaderAt
+}
+
+// reader - содержимое файла с начала, не длиннее Size
+func (u Upload) reader() *io.SectionReader {
+	return io.NewSectionReader(u.Content, 0, u.Size)
+}
+
+// head - начало файла, по которому http.DetectContentType определяет тип
+func (u Upload) head() ([]byte, error) {
+	head := make([]byte, min(u.Size, 512))
+	n, err := u.Content.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// AttachmentFile - открытое для чтения вложение или его превью.
+// Public - пост виден всем, файл можно кэшировать в общих кэшах.
+type AttachmentFile struct {
+	io.ReadCloser
+	Attachment  *models.Attachment
+	ContentType string
+	Public      bool
+}
+
+// AttachmentService прикрепляет файлы к постам: проверяет их, делает превью
+// изображений и хранит содержимое в storage.Blob, а записи - в БД
+type AttachmentService struct {
+	attachmentRepo repository.AttachmentRepository
+	postRepo       repository.PostRepository
+	blob           storage.Blob
+	maxSize        int64
+}
+
+func NewAttachmentService(
+	attachmentRepo repository.AttachmentRepository,
+	postRepo repository.PostRepository,
+	blob storage.Blob,
+	maxSize int64,
+) *AttachmentService {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+	return &AttachmentService{
+		attachmentRepo: attachmentRepo,
+		postRepo:       postRepo,
+		blob:           blob,
+		maxSize:        maxSize,
+	}
+}
+
+// MaxSize - предельный размер одного файла в байтах
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Limits - ограничения для подсказки в формах
+func (s *AttachmentService) Limits() string {
+	return fmt.Sprintf("до %d файлов по %s: изображения, PDF, текст, ZIP", MaxAttachmentsPerPost, formatSize(s.maxSize))
+}
+
+// Check проверяет файлы до сохранения поста, чтобы неподходящий файл не
+// оставил пост сохранённым наполовину: число файлов у поста postID (0 - новый
+// пост), размер и тип каждого
+func (s *AttachmentService) Check(postID int, uploads []Upload) error {
+	if len(uploads) == 0 {
+		return nil
+	}
+
+	count := 0
+	if postID != 0 {
+		var err error
+		if count, err = s.attachmentRepo.CountByPost(postID); err != nil {
+			return err
+		}
+	}
+	if count+len(uploads) > MaxAttachmentsPerPost {
+		return invalid("files", fmt.Sprintf("У поста может быть не больше %d файлов", MaxAttachmentsPerPost))
+	}
+
+	for _, upload := range uploads {
+		name := cleanFilename(upload.Filename)
+		switch {
+		case upload.Size == 0:
+			return invalid("files", fmt.Sprintf("Файл %s пустой", name))
+		case upload.Size > s.maxSize:
+			return invalid("files", fmt.Sprintf("Файл %s больше %s", name, formatSize(s.maxSize)))
+		}
+		head, err := upload.head()
+		if err != nil {
+			return err
+		}
+		if _, ok := attachmentTypes[mediaType(head)]; !ok {
+			return invalid("files", fmt.Sprintf("Файлы такого типа, как %s, прикреплять нельзя", name))
+		}
+	}
+	return nil
+}
+
+// Attach прикрепляет файлы к посту; нужно право на правку поста.
+// Файлы прикрепляются все или ни одного.
+func (s *AttachmentService) Attach(actor *models.User, postID int, uploads []Upload) ([]models.Attachment, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := authorizePost(actor, post, CanEditPost); err != nil {
+		return nil, err
+	}
+	if len(uploads) == 0 {
+		return nil, invalid("files", "Не выбрано ни одного файла")
+	}
+	if err := s.Check(postID, uploads); err != nil {
+		return nil, err
+	}
+
+	attachments := make([]models.Attachment, 0, len(uploads))
+	for _, upload := range uploads {
+		attachment, err := s.store(actor, postID, upload)
+		if err != nil {
+			s.discard(attachments...)
+			return nil, err
+		}
+		attachments = append(attachments, *attachment)
+	}
+	return attachments, nil
+}
+
+// discard откатывает уже сохранённые вложения, если остальные файлы
+// прикрепить не удалось: пост остаётся таким, каким был до Attach
+func (s *AttachmentService) discard(attachments ...models.Attachment) {
+	for _, attachment := range attachments {
+		if err := s.attachmentRepo.Delete(attachment.ID); err != nil {
+			log.Printf("Ошибка отката вложения %d поста %d: %v", attachment.ID, attachment.PostID, err)
+			continue
+		}
+		s.removeFiles(attachment)
+	}
+}
+
+// store кладёт файл и превью в хранилище и создаёт запись. Если запись
+// создать не удалось, файлы удаляются, чтобы не остаться без владельца.
+func (s *AttachmentService) store(actor *models.User, postID int, upload Upload) (*models.Attachment, error) {
+	head, err := upload.head()
+	if err != nil {
+		return nil, err
+	}
+	contentType := http.DetectContentType(head)
+	ext := attachmentTypes[mediaType(head)]
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		PostID:      postID,
+		UserID:      &actor.ID,
+		Filename:    cleanFilename(upload.Filename),
+		ContentType: contentType,
+		Size:        upload.Size,
+		StorageKey:  "posts/" + strconv.Itoa(postID) + "/" + name + ext,
+	}
+	if attachment.Filename == "" {
+		attachment.Filename = "file" + ext
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		thumb, err := thumbnail.Make(upload.reader(), thumbnailSize)
+		if err != nil {
+			// WebP и слишком большие изображения остаются без превью
+			log.Printf("Превью для %s не создано: %v", attachment.Filename, err)
+		} else {
+			thumbExt := ".png"
+			if thumb.ContentType == "image/jpeg" {
+				thumbExt = ".jpg"
+			}
+			thumbKey := "posts/" + strconv.Itoa(postID) + "/" + name + "_thumb" + thumbExt
+			if err := s.blob.Put(thumbKey, bytes.NewReader(thumb.Data), int64(len(thumb.Data)), thumb.ContentType); err != nil {
+				return nil, err
+			}
+			attachment.ThumbKey = thumbKey
+			attachment.Width, attachment.Height = thumb.Width, thumb.Height
+		}
+	}
+
+	if err := s.blob.Put(attachment.StorageKey, upload.reader(), attachment.Size, contentType); err != nil {
+		s.removeFiles(*attachment)
+		return nil, err
+	}
+
+	id, err := s.attachmentRepo.Create(attachment)
+	if err != nil {
+		s.removeFiles(*attachment)
+		return nil, err
+	}
+	attachment.ID = int(id)
+	attachment.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	attachment.SetURLs()
+	return attachment, nil
+}
+
+// List возвращает вложения поста в порядке загрузки, если viewer может видеть пост
+func (s *AttachmentService) List(viewer *models.User, postID int) ([]models.Attachment, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if !CanViewPost(viewer, post) {
+		return nil, ErrNotFound
+	}
+	return s.list(postID)
+}
+
+// Open открывает вложение (или его превью, если thumb) для отдачи. Вложения
+// неопубликованных постов видны только тем, кто может их видеть.
+func (s *AttachmentService) Open(viewer *models.User, id int, thumb bool) (*AttachmentFile, error) {
+	attachment, err := s.attachmentRepo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	post, err := s.postRepo.GetByID(attachment.PostID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if !CanViewPost(viewer, post) {
+		return nil, ErrNotFound
+	}
+
+	key, contentType := attachment.StorageKey, attachment.ContentType
+	if thumb {
+		if attachment.ThumbKey == "" {
+			return nil, ErrNotFound
+		}
+		key, contentType = attachment.ThumbKey, mime.TypeByExtension(path.Ext(attachment.ThumbKey))
+	}
+
+	body, err := s.blob.Get(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Файл вложения %d отсутствует в хранилище: %s", id, key)
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &AttachmentFile{
+		ReadCloser:  body,
+		Attachment:  attachment,
+		ContentType: contentType,
+		Public:      CanViewPost(nil, post),
+	}, nil
+}
+
+// Delete открепляет файл от поста и удаляет его из хранилища;
+// нужно право на правку поста
+func (s *AttachmentService) Delete(actor *models.User, id int) error {
+	attachment, err := s.attachmentRepo.GetByID(id)
+	if err != nil {
+		return notFound(err)
+	}
+	post, err := s.postRepo.GetByID(attachment.PostID)
+	if err != nil {
+		return notFound(err)
+	}
+	if err := authorizePost(actor, post, CanEditPost); err != nil {
+		return err
+	}
+
+	if err := s.attachmentRepo.Delete(id); err != nil {
+		return err
+	}
+	s.removeFiles(*attachment)
+	return nil
+}
+
+// attach загружает вложения постов
+func (s *AttachmentService) attach(posts ...*models.Post) {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	attachments, err := s.attachmentRepo.ListByPosts(ids)
+	if err != nil {
+		log.Printf("Ошибка загрузки вложений: %v", err)
+		return
+	}
+	for _, post := range posts {
+		post.Attachments = attachments[post.ID]
+	}
+}
+
+// list возвращает вложения поста перед его удалением: записи удалятся
+// вместе с постом, а файлы нужно удалить из хранилища после этого
+func (s *AttachmentService) list(postID int) ([]models.Attachment, error) {
+	attachments, err := s.attachmentRepo.ListByPosts([]int{postID})
+	if err != nil {
+		return nil, err
+	}
+	return attachments[postID], nil
+}
+
+// removeFiles удаляет файлы вложений и их превью из хранилища. Ошибки только
+// пишутся в лог: запись уже удалена, и повторить удаление будет не по чему,
+// поэтому в логе ключ файла, оставшегося в хранилище без владельца, и его пост.
+func (s *AttachmentService) removeFiles(attachments ...models.Attachment) {
+	for _, attachment := range attachments {
+		for _, key := range []string{attachment.StorageKey, attachment.ThumbKey} {
+			if key == "" {
+				continue
+			}
+			if err := s.blob.Delete(key); err != nil {
+				log.Printf("Файл %s поста %d остался в хранилище без вложения: %v", key, attachment.PostID, err)
+			}
+		}
+	}
+}
+
+// mediaType - тип содержимого файла без параметров (charset и т.п.)
+func mediaType(data []byte) string {
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return contentType
+}
+
+// cleanFilename оставляет от имени файла из формы только последний сегмент
+// пути без управляющих символов, не длиннее maxFilenameLength символов
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	if utf8.RuneCountInString(name) > maxFilenameLength {
+		runes := []rune(name)
+		ext := path.Ext(name)
+		if utf8.RuneCountInString(ext) > 10 {
+			ext = ""
+		}
+		name = string(runes[:maxFilenameLength-utf8.RuneCountInString(ext)]) + ext
+	}
+	return strings.TrimSpace(name)
+}
+
+// formatSize - размер в мегабайтах или килобайтах для сообщений об ошибках
+func formatSize(size int64) string {
+	if size >= 1<<20 && size%(1<<20) == 0 {
+		return strconv.FormatInt(size>>20, 10) + " МБ"
+	}
+	return strconv.FormatInt(size>>10, 10) + " КБ"
+}
+
+// randomName - случайное имя файла в хранилище: файлы с одинаковыми
+// именами не конфликтуют, а ключ не раскрывает имя, данное автором
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
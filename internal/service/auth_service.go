@@ -0,0 +1,165 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// SessionTTL - время жизни сессии после входа
+const SessionTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidCredentials = errors.New("неверное имя пользователя или пароль")
+	ErrUsernameTaken      = errors.New("имя пользователя уже занято")
+	ErrInvalidUsername    = errors.New("имя пользователя: 3-32 символа, латиница, цифры, _ и -")
+	ErrWeakPassword       = errors.New("пароль должен быть не короче 8 символов")
+	ErrSessionNotFound    = errors.New("сессия не найдена или истекла")
+)
+
+var usernameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
+
+type AuthService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+}
+
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository) *AuthService {
+	return &AuthService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+	}
+}
+
+// Register регистрирует нового пользователя с ролью reader; писать посты
+// он сможет, когда администратор повысит его роль
+func (s *AuthService) Register(username, password string) (*models.User, error) {
+	return s.CreateUser(username, password, models.RoleReader)
+}
+
+// CreateUser создаёт пользователя с ролью role и bcrypt-хешем пароля.
+// Так создаётся первый администратор: команда `server user create`.
+func (s *AuthService) CreateUser(username, password string, role models.Role) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if !usernameRe.MatchString(username) {
+		return nil, ErrInvalidUsername
+	}
+	if len(password) < 8 {
+		return nil, ErrWeakPassword
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	if _, err := s.userRepo.GetByUsername(username); err == nil {
+		return nil, ErrUsernameTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.userRepo.Create(username, string(hash), role)
+	if err != nil {
+		// Имя могли занять между проверкой и вставкой
+		if _, getErr := s.userRepo.GetByUsername(username); getErr == nil {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	return s.userRepo.GetByID(int(id))
+}
+
+// Login проверяет пароль и открывает новую сессию.
+// Возвращает токен для cookie; в базе хранится только его хеш.
+func (s *AuthService) Login(username, password string) (*models.User, string, error) {
+	user, err := s.userRepo.GetByUsername(strings.TrimSpace(username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, "", ErrInvalidCredentials
+	}
+
+	token, err := s.CreateSession(user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, token, nil
+}
+
+// CreateSession открывает сессию для пользователя и возвращает её токен
+func (s *AuthService) CreateSession(userID int) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	err := s.sessionRepo.Create(hashToken(token), userID, time.Now().Add(SessionTTL))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// UserBySession возвращает пользователя по токену из cookie
+func (s *AuthService) UserBySession(token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	user, expiresAt, err := s.sessionRepo.GetUser(hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(expiresAt) {
+		s.sessionRepo.Delete(hashToken(token))
+		return nil, ErrSessionNotFound
+	}
+
+	return user, nil
+}
+
+// Logout удаляет сессию на сервере
+func (s *AuthService) Logout(token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.sessionRepo.Delete(hashToken(token))
+}
+
+// CleanupSessions удаляет истёкшие сессии
+func (s *AuthService) CleanupSessions() (int64, error) {
+	return s.sessionRepo.DeleteExpired(time.Now())
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/slug"
+)
+
+// maxCategoryName - длина названия категории в символах
+const maxCategoryName = 64
+
+// CategoryService - управление категориями, доступно администраторам
+type CategoryService struct {
+	categoryRepo repository.CategoryRepository
+	sitemap      *Sitemap
+}
+
+func NewCategoryService(categoryRepo repository.CategoryRepository, sitemap *Sitemap) *CategoryService {
+	return &CategoryService{categoryRepo: categoryRepo, sitemap: sitemap}
+}
+
+// ListCategories возвращает все категории деревом (см. CategoryTree.Flatten)
+// с числом постов в каждой
+func (s *CategoryService) ListCategories(actor *models.User) ([]models.Category, error) {
+	if err := authorize(actor, PermManageCategories); err != nil {
+		return nil, err
+	}
+	categories, err := s.categoryRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return NewCategoryTree(categories).Flatten(), nil
+}
+
+// CreateCategory создаёт категорию внутри parentID (nil - на верхнем уровне).
+// Пустой slug строится из названия транслитерацией.
+func (s *CategoryService) CreateCategory(actor *models.User, name, slugValue string, parentID *int) (*models.Category, error) {
+	if err := authorize(actor, PermManageCategories); err != nil {
+		return nil, err
+	}
+
+	name, slugValue, err := s.validate(0, name, slugValue)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		if _, err := s.categoryRepo.GetByID(*parentID); errors.Is(err, sql.ErrNoRows) {
+			return nil, invalid("parent_id", "Родительская категория не найдена")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := s.categoryRepo.Create(name, slugValue, parentID)
+	if err != nil {
+		return nil, err
+	}
+	s.sitemap.Invalidate()
+	return s.categoryRepo.GetByID(int(id))
+}
+
+// UpdateCategory переименовывает категорию и меняет её адрес. Старый адрес
+// /category/{slug} после этого не открывается.
+func (s *CategoryService) UpdateCategory(actor *models.User, id int, name, slugValue string) (*models.Category, error) {
+	if err := authorize(actor, PermManageCategories); err != nil {
+		return nil, err
+	}
+	if _, err := s.categoryRepo.GetByID(id); err != nil {
+		return nil, notFound(err)
+	}
+
+	name, slugValue, err := s.validate(id, name, slugValue)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryRepo.Update(id, name, slugValue); err != nil {
+		return nil, err
+	}
+	s.sitemap.Invalidate()
+	return s.categoryRepo.GetByID(id)
+}
+
+// MoveCategory переносит категорию вместе с подкатегориями внутрь parentID;
+// nil - на верхний уровень. Категорию нельзя перенести в неё саму или в её
+// подкатегорию: дерево превратилось бы в цикл.
+func (s *CategoryService) MoveCategory(actor *models.User, id int, parentID *int) (*models.Category, error) {
+	if err := authorize(actor, PermManageCategories); err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categoryRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	tree := NewCategoryTree(categories)
+	if _, ok := tree.Get(id); !ok {
+		return nil, ErrNotFound
+	}
+	if parentID != nil {
+		if _, ok := tree.Get(*parentID); !ok {
+			return nil, invalid("parent_id", "Родительская категория не найдена")
+		}
+		if tree.InSubtree(*parentID, id) {
+			return nil, invalid("parent_id", "Категорию нельзя перенести в неё саму или в её подкатегорию")
+		}
+	}
+
+	if err := s.categoryRepo.SetParent(id, parentID); err != nil {
+		return nil, err
+	}
+	s.sitemap.Invalidate()
+	return s.categoryRepo.GetByID(id)
+}
+
+// DeleteCategory удаляет категорию, переносит её посты в reassignTo,
+// а подкатегории - к её родителю, и возвращает число перенесённых постов
+func (s *CategoryService) DeleteCategory(actor *models.User, id, reassignTo int) (int64, error) {
+	if err := authorize(actor, PermManageCategories); err != nil {
+		return 0, err
+	}
+	if _, err := s.categoryRepo.GetByID(id); err != nil {
+		return 0, notFound(err)
+	}
+
+	if reassignTo == id {
+		return 0, invalid("reassign_to", "Посты нужно перенести в другую категорию")
+	}
+	if _, err := s.categoryRepo.GetByID(reassignTo); errors.Is(err, sql.ErrNoRows) {
+		return 0, invalid("reassign_to", "Категория для постов не найдена")
+	} else if err != nil {
+		return 0, err
+	}
+
+	moved, err := s.categoryRepo.Delete(id, reassignTo)
+	if err != nil {
+		return 0, err
+	}
+	// Посты категории переехали: их записи в карте сайта устарели
+	s.sitemap.Reset()
+	return moved, nil
+}
+
+// validate приводит название и адрес категории id (0 - новой) к сохраняемому
+// виду и проверяет, что они не заняты другой категорией
+func (s *CategoryService) validate(id int, name, slugValue string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", invalid("name", "Название категории обязательно")
+	}
+	if utf8.RuneCountInString(name) > maxCategoryName {
+		return "", "", invalid("name", "Название категории слишком длинное")
+	}
+
+	slugValue = strings.TrimSpace(slugValue)
+	if slugValue == "" {
+		slugValue = slug.Make(name)
+		if slugValue == "" {
+			return "", "", invalid("slug", "Не удалось построить адрес из названия, задайте его латиницей")
+		}
+	} else if !slug.Valid(slugValue) {
+		return "", "", invalid("slug", "Адрес: латиница в нижнем регистре, цифры и дефисы")
+	}
+
+	if other, err := s.categoryRepo.GetByName(name); err == nil && other.ID != id {
+		return "", "", invalid("name", "Категория с таким названием уже есть")
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", "", err
+	}
+	if other, err := s.categoryRepo.GetBySlug(slugValue); err == nil && other.ID != id {
+		return "", "", invalid("slug", "Адрес уже занят категорией «"+other.Name+"»")
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", "", err
+	}
+
+	return name, slugValue, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import "github.com/s.usynin/testing/go-server/internal/models"
+
+// CategoryTree - категории, собранные в дерево по ParentID. Категорий в блоге
+// немного, поэтому дерево строится в памяти из полного списка.
+type CategoryTree struct {
+	byID map[int]*models.Category
+	// children - подкатегории в порядке исходного списка; ключ 0 - верхний уровень
+	children map[int][]*models.Category
+}
+
+// NewCategoryTree собирает дерево. Категория с несуществующим родителем
+// считается категорией верхнего уровня.
+func NewCategoryTree(categories []models.Category) *CategoryTree {
+	t := &CategoryTree{
+		byID:     make(map[int]*models.Category, len(categories)),
+		children: make(map[int][]*models.Category),
+	}
+	for i := range categories {
+		category := categories[i]
+		t.byID[category.ID] = &category
+	}
+	for _, category := range categories {
+		parent := 0
+		if category.ParentID != nil && t.byID[*category.ParentID] != nil {
+			parent = *category.ParentID
+		}
+		t.children[parent] = append(t.children[parent], t.byID[category.ID])
+	}
+	return t
+}
+
+// Get возвращает категорию с заполненными Ancestors и Depth
+func (t *CategoryTree) Get(id int) (*models.Category, bool) {
+	category, ok := t.byID[id]
+	if !ok {
+		return nil, false
+	}
+	result := *category
+	result.Ancestors = t.Ancestors(id)
+	result.Depth = len(result.Ancestors)
+	return &result, true
+}
+
+// Children - подкатегории id; для 0 - категории верхнего уровня
+func (t *CategoryTree) Children(id int) []models.Category {
+	var children []models.Category
+	for _, child := range t.children[id] {
+		children = append(children, *child)
+	}
+	return children
+}
+
+// Ancestors - родители категории от верхнего уровня к ближайшему
+func (t *CategoryTree) Ancestors(id int) []models.Category {
+	var ancestors []models.Category
+	category := t.byID[id]
+	for category != nil && category.ParentID != nil && len(ancestors) < len(t.byID) {
+		parent := t.byID[*category.ParentID]
+		if parent == nil {
+			break
+		}
+		ancestors = append([]models.Category{*parent}, ancestors...)
+		category = parent
+	}
+	return ancestors
+}
+
+// Root - категория верхнего уровня, в которую входит id (сама id, если это она)
+func (t *CategoryTree) Root(id int) int {
+	if ancestors := t.Ancestors(id); len(ancestors) > 0 {
+		return ancestors[0].ID
+	}
+	return id
+}
+
+// InSubtree проверяет, что id - это root или одна из его подкатегорий
+// на любой глубине
+func (t *CategoryTree) InSubtree(id, root int) bool {
+	if id == root {
+		return true
+	}
+	for _, ancestor := range t.Ancestors(id) {
+		if ancestor.ID == root {
+			return true
+		}
+	}
+	return false
+}
+
+// Flatten возвращает все категории обходом в глубину: каждая категория идёт
+// сразу за родителем, Depth задаёт отступ
+func (t *CategoryTree) Flatten() []models.Category {
+	list := make([]models.Category, 0, len(t.byID))
+	var walk func(parent, depth int)
+	walk = func(parent, depth int) {
+		for _, category := range t.children[parent] {
+			item := *category
+			item.Depth = depth
+			list = append(list, item)
+			walk(category.ID, depth+1)
+		}
+	}
+	walk(0, 0)
+	return list
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/spam"
+)
+
+// DefaultMaxCommentDepth - глубина веток ответов по умолчанию
+const DefaultMaxCommentDepth = 5
+
+// AddComment добавляет комментарий или, если задан parentID, ответ на комментарий
+// того же поста. Для вошедшего пользователя комментарий привязывается к нему,
+// а имя автора берётся из аккаунта; гости указывают имя сами. По итогам спам
+// фильтра и режима премодерации комментарий публикуется сразу, получает статус
+// pending или попадает в спам. source - адрес отправителя и скрытые поля формы.
+func (s *PostService) AddComment(postID int, parentID *int, user *models.User, author, content string, source spam.Source) (*models.Comment, error) {
+	var userID *int
+	if user != nil {
+		userID = &user.ID
+		author = user.Username
+	}
+	if strings.TrimSpace(author) == "" {
+		return nil, invalid("author", "Укажите имя")
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, invalid("content", "Комментарий не может быть пустым")
+	}
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := checkOpen(post); err != nil {
+		return nil, err
+	}
+
+	var parent *models.Comment
+	depth := 0
+	if parentID != nil {
+		parent, err = s.commentRepo.GetByID(*parentID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && (parent.PostID != postID || parent.Status != models.CommentApproved)) {
+			return nil, invalid("parent_id", "Комментарий, на который вы отвечаете, не найден")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parent.Deleted() {
+			return nil, invalid("parent_id", "Нельзя ответить на удалённый комментарий")
+		}
+		if depth, err = s.commentRepo.Depth(parent.ID); err != nil {
+			return nil, err
+		}
+		depth++
+	}
+
+	comment := &models.Comment{
+		PostID:   postID,
+		ParentID: parentID,
+		UserID:   userID,
+		Author:   author,
+		Content:  content,
+		IP:       source.IP,
+	}
+	if err := s.moderation.screen(post, user, comment, source); err != nil {
+		return nil, err
+	}
+
+	id, err := s.commentRepo.Create(comment)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err = s.commentRepo.GetByID(int(id))
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status == models.CommentApproved {
+		s.moderation.commentPublished(post, comment)
+	}
+
+	// Ответ глубже предела показывается рядом с родителем, с пометкой, кому он
+	comment.Depth = depth
+	if depth > s.maxCommentDepth {
+		comment.Depth = s.maxCommentDepth
+		comment.ReplyTo = parent.Author
+	}
+	comment.AtMaxDepth = comment.Depth >= s.maxCommentDepth
+	comment.ContentHTML = s.render(fmt.Sprintf("comment:%d", comment.ID), comment.Content)
+	return comment, nil
+}
+
+// CommentFormToken - токен для новой формы комментария к посту postID, по
+// которому спам фильтр узнаёт, сколько форма заполнялась. Токен одноразовый.
+func (s *PostService) CommentFormToken(postID int) string {
+	return s.moderation.spamFilter.FormToken(postID)
+}
+
+// GetCommentThread возвращает опубликованный комментарий со всей веткой ответов,
+// если viewer может видеть его пост
+func (s *PostService) GetCommentThread(viewer *models.User, id int) (*models.Comment, error) {
+	comment, err := s.commentRepo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if comment.Status != models.CommentApproved {
+		return nil, ErrNotFound
+	}
+	if _, err := s.getVisiblePost(viewer, comment.PostID); err != nil {
+		return nil, err
+	}
+	depth, err := s.commentRepo.Depth(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	// На пределе глубины ответы показываются рядом с комментарием, а не в его ветке
+	thread := []models.Comment{*comment}
+	if depth >= s.maxCommentDepth {
+		thread[0].Depth, thread[0].AtMaxDepth = s.maxCommentDepth, true
+	} else if thread, err = s.loadReplies(thread, depth); err != nil {
+		return nil, err
+	}
+
+	s.renderComments(thread)
+	return &thread[0], nil
+}
+
+// DeleteComment удаляет комментарий: свой - автор, любой - редактор и администратор.
+// Если на комментарий есть ответы, от него остаётся надгробие "комментарий удалён",
+// чтобы ветка не рассыпалась; иначе он удаляется полностью вместе с надгробиями
+// предков, у которых не осталось ответов.
+func (s *PostService) DeleteComment(actor *models.User, id int) (*repository.CommentDeletion, error) {
+	comment, err := s.commentRepo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if actor == nil {
+		return nil, ErrUnauthenticated
+	}
+	if comment.Deleted() {
+		return nil, ErrNotFound
+	}
+	if !CanDeleteComment(actor, comment) {
+		return nil, ErrForbidden
+	}
+
+	return s.commentRepo.Delete(id)
+}
+
+// loadReplies загружает ответы на комментарии roots и раскладывает их в дерево.
+// depth - уровень, на котором показываются сами roots.
+func (s *PostService) loadReplies(roots []models.Comment, depth int) ([]models.Comment, error) {
+	if len(roots) == 0 {
+		return roots, nil
+	}
+
+	ids := make([]int, len(roots))
+	for i, root := range roots {
+		ids[i] = root.ID
+	}
+	replies, err := s.commentRepo.ListReplies(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCommentTree(roots, replies, depth, s.maxCommentDepth), nil
+}
+
+// commentNode - узел дерева при сборке; parent - узел, под которым комментарий
+// показывается (не обязательно тот, на который он отвечает)
+type commentNode struct {
+	comment  models.Comment
+	parent   *commentNode
+	children []*commentNode
+}
+
+// buildCommentTree собирает дерево из корней и их ответов. replies должны идти
+// в порядке создания, чтобы родитель встречался раньше ответа. Ответы глубже
+// maxDepth поднимаются на уровень maxDepth: они показываются после родителя,
+// а в ReplyTo записывается, кому адресован ответ.
+func buildCommentTree(roots, replies []models.Comment, depth, maxDepth int) []models.Comment {
+	nodes := make(map[int]*commentNode, len(roots)+len(replies))
+	top := make([]*commentNode, len(roots))
+	for i, root := range roots {
+		root.Depth = depth
+		top[i] = &commentNode{comment: root}
+		nodes[root.ID] = top[i]
+	}
+
+	for _, reply := range replies {
+		if reply.ParentID == nil {
+			continue
+		}
+		parent, ok := nodes[*reply.ParentID]
+		if !ok {
+			continue
+		}
+
+		holder := parent
+		if parent.comment.Depth >= maxDepth && parent.parent != nil {
+			holder = parent.parent
+			reply.ReplyTo = parent.comment.Author
+		}
+		reply.Depth = holder.comment.Depth + 1
+
+		node := &commentNode{comment: reply, parent: holder}
+		holder.children = append(holder.children, node)
+		nodes[reply.ID] = node
+	}
+
+	comments := make([]models.Comment, len(top))
+	for i, node := range top {
+		comments[i] = node.flatten(maxDepth)
+	}
+	return comments
+}
+
+// flatten переводит узел в models.Comment с заполненными Replies и RepliesCount
+func (n *commentNode) flatten(maxDepth int) models.Comment {
+	comment := n.comment
+	comment.AtMaxDepth = comment.Depth >= maxDepth
+	comment.Replies = nil
+	comment.RepliesCount = 0
+	for _, child := range n.children {
+		reply := child.flatten(maxDepth)
+		comment.RepliesCount += 1 + reply.RepliesCount
+		comment.Replies = append(comment.Replies, reply)
+	}
+	return comment
+}
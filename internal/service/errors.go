@@ -0,0 +1,33 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("не найдено")
+	ErrConflict = errors.New("пост уже изменили, обновите страницу и повторите правку")
+)
+
+// ValidationError - ошибка во входных данных, Field указывает на поле формы или JSON
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(field, message string) error {
+	return &ValidationError{Field: field, Message: message}
+}
+
+// notFound заменяет sql.ErrNoRows на ErrNotFound, остальные ошибки не трогает
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/spam"
+)
+
+// moderationSetting - ключ глобального режима премодерации в settings
+const moderationSetting = "comment_moderation"
+
+// ModerationQueueStatuses - состояния, которые показывает очередь модерации
+var ModerationQueueStatuses = []models.CommentStatus{models.CommentPending, models.CommentSpam, models.CommentRejected}
+
+// ModerationService - премодерация комментариев: режимы для блога и категорий,
+// спам фильтр, очередь на проверку и уведомления авторам постов о новых комментариях
+type ModerationService struct {
+	commentRepo      repository.CommentRepository
+	postRepo         repository.PostRepository
+	categoryRepo     repository.CategoryRepository
+	settingsRepo     repository.SettingsRepository
+	notificationRepo repository.NotificationRepository
+	spamFilter       *spam.Filter
+}
+
+func NewModerationService(
+	commentRepo repository.CommentRepository,
+	postRepo repository.PostRepository,
+	categoryRepo repository.CategoryRepository,
+	settingsRepo repository.SettingsRepository,
+	notificationRepo repository.NotificationRepository,
+	spamFilter *spam.Filter,
+) *ModerationService {
+	return &ModerationService{
+		commentRepo:      commentRepo,
+		postRepo:         postRepo,
+		categoryRepo:     categoryRepo,
+		settingsRepo:     settingsRepo,
+		notificationRepo: notificationRepo,
+		spamFilter:       spamFilter,
+	}
+}
+
+// ModerationSettings - глобальный режим премодерации, режимы категорий
+// и списки блокировки спам фильтра (по элементу на строку)
+type ModerationSettings struct {
+	Global       models.ModerationMode
+	Categories   []CategoryModeration
+	BlockedWords string
+	BlockedIPs   string
+}
+
+// CategoryModeration - режим категории; пустой Mode - как у всего блога
+type CategoryModeration struct {
+	models.Category
+	Mode models.ModerationMode
+}
+
+// Settings возвращает режимы премодерации для страницы настроек
+func (s *ModerationService) Settings(actor *models.User) (*ModerationSettings, error) {
+	if err := authorize(actor, PermManageModeration); err != nil {
+		return nil, err
+	}
+
+	global, err := s.globalMode()
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categoryRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &ModerationSettings{Global: global}
+	if settings.BlockedWords, err = s.setting(spam.BlockedWordsSetting); err != nil {
+		return nil, err
+	}
+	if settings.BlockedIPs, err = s.setting(spam.BlockedIPsSetting); err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		item := CategoryModeration{Category: category}
+		if category.CommentModeration != nil {
+			item.Mode = *category.CommentModeration
+		}
+		settings.Categories = append(settings.Categories, item)
+	}
+	return settings, nil
+}
+
+// UpdateSettings сохраняет глобальный режим и режимы категорий.
+// В categories nil означает, что категория следует глобальному режиму.
+func (s *ModerationService) UpdateSettings(actor *models.User, global models.ModerationMode, categories map[int]*models.ModerationMode) error {
+	if err := authorize(actor, PermManageModeration); err != nil {
+		return err
+	}
+	if !global.Valid() {
+		return invalid("global", "Неизвестный режим премодерации")
+	}
+	for _, mode := range categories {
+		if mode != nil && !mode.Valid() {
+			return invalid("category", "Неизвестный режим премодерации")
+		}
+	}
+
+	if err := s.settingsRepo.Set(moderationSetting, string(global)); err != nil {
+		return err
+	}
+	for id, mode := range categories {
+		if err := s.categoryRepo.SetCommentModeration(id, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateBlocklist сохраняет списки запрещённых слов и адресов спам фильтра
+func (s *ModerationService) UpdateBlocklist(actor *models.User, words, ips string) error {
+	if err := authorize(actor, PermManageModeration); err != nil {
+		return err
+	}
+	for _, ip := range spam.ParseList(ips) {
+		if !spam.ValidIP(ip) {
+			return invalid("blocked_ips", fmt.Sprintf("%q - не IP адрес и не подсеть", ip))
+		}
+	}
+
+	if err := s.settingsRepo.Set(spam.BlockedWordsSetting, strings.Join(spam.ParseList(words), "\n")); err != nil {
+		return err
+	}
+	return s.settingsRepo.Set(spam.BlockedIPsSetting, strings.Join(spam.ParseList(ips), "\n"))
+}
+
+// setting возвращает настройку или пустую строку, если она не задана
+func (s *ModerationService) setting(key string) (string, error) {
+	value, err := s.settingsRepo.Get(key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *ModerationService) globalMode() (models.ModerationMode, error) {
+	value, err := s.setting(moderationSetting)
+	if err != nil {
+		return "", err
+	}
+
+	mode := models.ModerationMode(value)
+	if !mode.Valid() {
+		return models.ModerationOff, nil
+	}
+	return mode, nil
+}
+
+// modeFor возвращает режим категории поста, а если он не задан - глобальный
+func (s *ModerationService) modeFor(post *models.Post) (models.ModerationMode, error) {
+	category, err := s.categoryRepo.GetByID(post.CategoryID)
+	if err == nil && category.CommentModeration != nil && category.CommentModeration.Valid() {
+		return *category.CommentModeration, nil
+	}
+	return s.globalMode()
+}
+
+// screen проверяет спам фильтром новый комментарий, пришедший из source,
+// и заполняет его Status, SpamScore и SpamReasons. Комментарии модераторов
+// не проверяются. Явный спам сразу уходит в спам, подозрительный ждёт
+// модератора, остальное решает режим премодерации.
+func (s *ModerationService) screen(post *models.Post, user *models.User, comment *models.Comment, source spam.Source) error {
+	if Can(user, PermModerateComments) {
+		comment.Status = models.CommentApproved
+		return nil
+	}
+
+	verdict := s.spamFilter.Check(&spam.Submission{
+		Source:  source,
+		PostID:  post.ID,
+		UserID:  comment.UserID,
+		Author:  comment.Author,
+		Content: comment.Content,
+	})
+	comment.SpamScore, comment.SpamReasons = verdict.Score, verdict.Reason()
+
+	status, err := s.initialStatus(post, user)
+	if err != nil {
+		return err
+	}
+	switch {
+	case verdict.Spam():
+		status = models.CommentSpam
+	case verdict.Suspicious() && status == models.CommentApproved:
+		status = models.CommentPending
+	}
+	comment.Status = status
+	return nil
+}
+
+// initialStatus решает по режиму премодерации, публикуется ли новый комментарий
+// сразу или ждёт модератора. В режиме first_time проверяются гости
+// и пользователи, у которых ещё нет одобренных комментариев.
+func (s *ModerationService) initialStatus(post *models.Post, user *models.User) (models.CommentStatus, error) {
+	mode, err := s.modeFor(post)
+	if err != nil {
+		return "", err
+	}
+
+	switch mode {
+	case models.ModerationAll:
+		return models.CommentPending, nil
+	case models.ModerationFirstTime:
+		if user == nil {
+			return models.CommentPending, nil
+		}
+		trusted, err := s.commentRepo.HasApproved(user.ID)
+		if err != nil {
+			return "", err
+		}
+		if !trusted {
+			return models.CommentPending, nil
+		}
+	}
+	return models.CommentApproved, nil
+}
+
+// commentPublished сообщает автору поста о новом опубликованном комментарии,
+// только при первой публикации: комментарий, одобренный снова после отклонения,
+// уже отмечен как разосланный. Ошибка уведомления не отменяет публикацию,
+// поэтому только логируется.
+func (s *ModerationService) commentPublished(post *models.Post, comment *models.Comment) {
+	if post.UserID == nil || (comment.UserID != nil && *comment.UserID == *post.UserID) {
+		return
+	}
+	first, err := s.commentRepo.MarkNotified(comment.ID)
+	if err != nil {
+		log.Printf("Ошибка уведомления о комментарии %d: %v", comment.ID, err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	message := fmt.Sprintf("Новый комментарий от %s к посту «%s»", comment.Author, post.Title)
+	if _, err := s.notificationRepo.Create(*post.UserID, &post.ID, &comment.ID, message); err != nil {
+		log.Printf("Ошибка уведомления о комментарии %d: %v", comment.ID, err)
+	}
+}
+
+// ModerationItem - комментарий в очереди вместе с постом, к которому он написан
+type ModerationItem struct {
+	models.Comment
+	Post *models.Post
+}
+
+// ModerationQueue - страница очереди модерации и число комментариев в каждом состоянии
+type ModerationQueue struct {
+	Status  models.CommentStatus
+	Items   []ModerationItem
+	Counts  map[models.CommentStatus]int
+	Page    int
+	PerPage int
+}
+
+// Total - число комментариев в показанном состоянии
+func (q *ModerationQueue) Total() int {
+	return q.Counts[q.Status]
+}
+
+// Count - число комментариев в состоянии status (для вкладок в шаблоне)
+func (q *ModerationQueue) Count(status string) int {
+	return q.Counts[models.CommentStatus(status)]
+}
+
+// PrevPage - номер предыдущей страницы или 0 на первой
+func (q *ModerationQueue) PrevPage() int {
+	return q.Page - 1
+}
+
+// NextPage - номер следующей страницы или 0 на последней
+func (q *ModerationQueue) NextPage() int {
+	if q.Page*q.PerPage >= q.Total() {
+		return 0
+	}
+	return q.Page + 1
+}
+
+// Queue возвращает страницу комментариев в состоянии status, начиная со старых
+func (s *ModerationService) Queue(actor *models.User, status models.CommentStatus, page, perPage int) (*ModerationQueue, error) {
+	if err := authorize(actor, PermModerateComments); err != nil {
+		return nil, err
+	}
+	if !isQueueStatus(status) {
+		return nil, invalid("status", "В очереди только ожидающие, спам и отклонённые комментарии")
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	queue := &ModerationQueue{Status: status, Page: page, PerPage: perPage, Counts: make(map[models.CommentStatus]int)}
+	for _, st := range ModerationQueueStatuses {
+		count, err := s.commentRepo.CountByStatus(st)
+		if err != nil {
+			return nil, err
+		}
+		queue.Counts[st] = count
+	}
+
+	comments, err := s.commentRepo.ListByStatus(status, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make(map[int]*models.Post)
+	for _, comment := range comments {
+		post, ok := posts[comment.PostID]
+		if !ok {
+			if post, err = s.postRepo.GetByID(comment.PostID); err != nil {
+				return nil, err
+			}
+			posts[comment.PostID] = post
+		}
+		queue.Items = append(queue.Items, ModerationItem{Comment: comment, Post: post})
+	}
+
+	return queue, nil
+}
+
+// Moderate переводит комментарии ids в состояние status (approved, rejected или spam)
+// и возвращает, сколько комментариев изменено. Решение обучает спам фильтр в той же
+// транзакции, что и смена состояния; авторы постов получают уведомления о впервые
+// одобренных комментариях (см. commentPublished).
+func (s *ModerationService) Moderate(actor *models.User, ids []int, status models.CommentStatus) (int, error) {
+	if err := authorize(actor, PermModerateComments); err != nil {
+		return 0, err
+	}
+	if status != models.CommentApproved && status != models.CommentRejected && status != models.CommentSpam {
+		return 0, invalid("action", "Неизвестное действие модерации")
+	}
+	if len(ids) == 0 {
+		return 0, invalid("ids", "Выберите комментарии")
+	}
+
+	var changed []models.Comment
+	for _, id := range ids {
+		comment, err := s.commentRepo.GetByID(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if comment.Status != status && !comment.Deleted() {
+			changed = append(changed, *comment)
+		}
+	}
+
+	changedIDs := make([]int, len(changed))
+	var training []repository.SpamTraining
+	for i, comment := range changed {
+		changedIDs[i] = comment.ID
+		if tr, ok := spamTraining(&comment, status); ok {
+			training = append(training, tr)
+		}
+	}
+	if err := s.commentRepo.Moderate(changedIDs, status, training); err != nil {
+		return 0, err
+	}
+
+	if status == models.CommentApproved {
+		for i := range changed {
+			post, err := s.postRepo.GetByID(changed[i].PostID)
+			if err != nil {
+				continue
+			}
+			s.commentPublished(post, &changed[i])
+		}
+	}
+
+	return len(changed), nil
+}
+
+// spamTraining - как решение модератора обучает спам фильтр: спам - пример
+// спама, одобренный комментарий - пример нормального, отклонённый не учит
+// ничему. Прежнее обучение этим комментарием отменяется, чтобы смена решения
+// не считалась дважды; false - обучение не меняется.
+func spamTraining(comment *models.Comment, status models.CommentStatus) (repository.SpamTraining, bool) {
+	var label, previous models.SpamLabel
+	switch status {
+	case models.CommentSpam:
+		label = models.SpamLabelSpam
+	case models.CommentApproved:
+		label = models.SpamLabelHam
+	}
+	if comment.SpamLabel != nil {
+		previous = *comment.SpamLabel
+	}
+	if label == previous {
+		return repository.SpamTraining{}, false
+	}
+
+	return repository.SpamTraining{
+		CommentID: comment.ID,
+		Tokens:    spam.Tokenize(&spam.Submission{Author: comment.Author, Content: comment.Content}),
+		Previous:  previous,
+		Label:     label,
+	}, true
+}
+
+func isQueueStatus(status models.CommentStatus) bool {
+	for _, st := range ModerationQueueStatuses {
+		if st == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/spam"
+)
+
+// В режиме first_time ждут модератора гости и пользователи без одобренных
+// комментариев; режим категории важнее глобального
+func TestFirstTimeModeration(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		posts := f.postService(newMemBlob())
+		moderation := f.moderationService()
+		admin := actor(f.user("admin", models.RoleAdmin), models.RoleAdmin)
+		reader := actor(f.user("reader", models.RoleReader), models.RoleReader)
+		newcomer := actor(f.user("newcomer", models.RoleReader), models.RoleReader)
+		postID := f.post("Пост", models.PostPublished)
+
+		if err := moderation.UpdateSettings(admin, models.ModerationFirstTime, nil); err != nil {
+			t.Fatal(err)
+		}
+		add := func(user *models.User, want models.CommentStatus) int {
+			t.Helper()
+			comment, err := posts.AddComment(postID, nil, user, "Гость", "Комментарий", spam.Source{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if comment.Status != want {
+				name := "гость"
+				if user != nil {
+					name = user.Username
+				}
+				t.Errorf("комментарий %s: %s, ожидался %s", name, comment.Status, want)
+			}
+			return comment.ID
+		}
+
+		first := add(reader, models.CommentPending)
+		add(nil, models.CommentPending)
+		add(admin, models.CommentApproved)
+
+		if n, err := moderation.Moderate(admin, []int{first}, models.CommentApproved); err != nil || n != 1 {
+			t.Fatalf("Moderate = %d, %v", n, err)
+		}
+		add(reader, models.CommentApproved)
+		add(newcomer, models.CommentPending)
+		add(nil, models.CommentPending)
+
+		off, all := models.ModerationOff, models.ModerationAll
+		if err := moderation.UpdateSettings(admin, models.ModerationFirstTime, map[int]*models.ModerationMode{f.category: &off}); err != nil {
+			t.Fatal(err)
+		}
+		add(newcomer, models.CommentApproved)
+		add(nil, models.CommentApproved)
+
+		if err := moderation.UpdateSettings(admin, models.ModerationOff, map[int]*models.ModerationMode{f.category: &all}); err != nil {
+			t.Fatal(err)
+		}
+		add(reader, models.CommentPending)
+	})
+}
+
+// Автор поста узнаёт о комментарии один раз, сколько бы его ни одобряли;
+// о своих комментариях - никогда. Решение модератора обучает спам фильтр.
+func TestModerateNotifiesOnce(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		moderation := f.moderationService()
+		notifications := repository.NewNotificationRepository(db)
+		spamRepo := repository.NewSpamRepository(db)
+		editor := actor(f.user("editor", models.RoleEditor), models.RoleEditor)
+		postID := f.post("Пост", models.PostPublished)
+
+		guest := f.comment(postID, models.CommentPending)
+		own, err := f.comments.Create(&models.Comment{PostID: postID, UserID: &f.author, Author: "author", Content: "Ответ", Status: models.CommentPending})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		moderate := func(status models.CommentStatus, want int, ids ...int) {
+			t.Helper()
+			if n, err := moderation.Moderate(editor, ids, status); err != nil || n != want {
+				t.Fatalf("Moderate(%s) = %d, %v; want %d", status, n, err, want)
+			}
+		}
+		check := func(step string, wantNotes, wantSpam, wantHam int) {
+			t.Helper()
+			notes, err := notifications.ListByUser(f.author, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(notes) != wantNotes {
+				t.Errorf("%s: уведомлений %d, ожидалось %d", step, len(notes), wantNotes)
+			}
+			spamDocs, hamDocs, err := spamRepo.Documents()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if spamDocs != wantSpam || hamDocs != wantHam {
+				t.Errorf("%s: обучено спам %d, нормальных %d; ожидалось %d, %d", step, spamDocs, hamDocs, wantSpam, wantHam)
+			}
+		}
+
+		moderate(models.CommentApproved, 2, guest, int(own))
+		check("одобрение", 1, 0, 2)
+		notes, err := notifications.ListByUser(f.author, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "Новый комментарий от Гость к посту «Пост»"; len(notes) == 1 && notes[0].Message != want {
+			t.Errorf("уведомление %q, ожидалось %q", notes[0].Message, want)
+		}
+
+		// Повторное решение ничего не меняет
+		moderate(models.CommentApproved, 0, guest)
+
+		moderate(models.CommentSpam, 1, guest)
+		check("спам", 1, 1, 1)
+		moderate(models.CommentRejected, 1, guest)
+		check("отклонение", 1, 0, 1)
+		moderate(models.CommentApproved, 1, guest)
+		check("повторное одобрение", 1, 0, 2)
+	})
+}
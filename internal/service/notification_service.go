@@ -0,0 +1,42 @@
+package service
+
+import (
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// NotificationsPerPage - уведомлений на странице /notifications
+const NotificationsPerPage = 50
+
+type NotificationService struct {
+	notificationRepo repository.NotificationRepository
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository) *NotificationService {
+	return &NotificationService{notificationRepo: notificationRepo}
+}
+
+// List возвращает последние уведомления пользователя и отмечает их прочитанными.
+// У возвращённых уведомлений ReadAt остаётся прежним, чтобы новые можно было выделить.
+func (s *NotificationService) List(actor *models.User) ([]models.Notification, error) {
+	if actor == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	notifications, err := s.notificationRepo.ListByUser(actor.ID, NotificationsPerPage)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.notificationRepo.MarkAllRead(actor.ID); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// UnreadCount - число непрочитанных уведомлений; 0 для гостя
+func (s *NotificationService) UnreadCount(user *models.User) (int, error) {
+	if user == nil {
+		return 0, nil
+	}
+	return s.notificationRepo.CountUnread(user.ID)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+)
+
+var (
+	ErrUnauthenticated = errors.New("необходимо войти в аккаунт")
+	ErrForbidden       = errors.New("недостаточно прав для этого действия")
+)
+
+// Permission - отдельное право, которое проверяет service слой
+type Permission string
+
+const (
+	PermCreatePost       Permission = "post:create"
+	PermEditOwnPost      Permission = "post:edit:own"
+	PermEditAnyPost      Permission = "post:edit:any"
+	PermDeleteOwnPost    Permission = "post:delete:own"
+	PermDeleteAnyPost    Permission = "post:delete:any"
+	PermModerateComments Permission = "comments:moderate"
+	PermManageModeration Permission = "comments:settings"
+	PermManageCategories Permission = "categories:manage"
+	PermManageTags       Permission = "tags:manage"
+	PermManageUsers      Permission = "users:manage"
+)
+
+// rolePermissions - политика доступа: какие права есть у каждой роли.
+// Роли не наследуют права автоматически, список задаётся явно.
+var rolePermissions = map[models.Role][]Permission{
+	models.RoleReader: {},
+	models.RoleAuthor: {
+		PermCreatePost, PermEditOwnPost, PermDeleteOwnPost,
+	},
+	models.RoleEditor: {
+		PermCreatePost, PermEditOwnPost, PermDeleteOwnPost,
+		PermEditAnyPost, PermDeleteAnyPost, PermModerateComments,
+	},
+	models.RoleAdmin: {
+		PermCreatePost, PermEditOwnPost, PermDeleteOwnPost,
+		PermEditAnyPost, PermDeleteAnyPost, PermModerateComments,
+		PermManageModeration, PermManageCategories, PermManageTags, PermManageUsers,
+	},
+}
+
+// Can проверяет, есть ли у пользователя право. Гость (nil) не имеет прав.
+func Can(user *models.User, perm Permission) bool {
+	if user == nil {
+		return false
+	}
+	for _, p := range rolePermissions[user.Role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize возвращает ErrUnauthenticated для гостя и ErrForbidden при нехватке прав
+func authorize(user *models.User, perm Permission) error {
+	if user == nil {
+		return ErrUnauthenticated
+	}
+	if !Can(user, perm) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// CanEditPost - может ли пользователь редактировать пост
+func CanEditPost(user *models.User, post *models.Post) bool {
+	return Can(user, PermEditAnyPost) || (isOwner(user, post) && Can(user, PermEditOwnPost))
+}
+
+// CanViewPost - может ли пользователь открыть пост: опубликованные и архивные
+// видны всем, черновики и запланированные - только тем, кто может их править
+func CanViewPost(user *models.User, post *models.Post) bool {
+	switch post.Status {
+	case models.PostPublished, models.PostArchived:
+		return true
+	}
+	return CanEditPost(user, post)
+}
+
+// CanDeletePost - может ли пользователь удалить пост
+func CanDeletePost(user *models.User, post *models.Post) bool {
+	return Can(user, PermDeleteAnyPost) || (isOwner(user, post) && Can(user, PermDeleteOwnPost))
+}
+
+// CanDeleteComment - может ли пользователь удалить комментарий: свой может
+// удалить любой вошедший пользователь, чужой - редактор и администратор
+func CanDeleteComment(user *models.User, comment *models.Comment) bool {
+	if user == nil || comment.Deleted() {
+		return false
+	}
+	return Can(user, PermModerateComments) || (comment.UserID != nil && *comment.UserID == user.ID)
+}
+
+func isOwner(user *models.User, post *models.Post) bool {
+	return user != nil && post.UserID != nil && *post.UserID == user.ID
+}
+
+// authorizePost проверяет право на действие с конкретным постом
+func authorizePost(user *models.User, post *models.Post, allowed func(*models.User, *models.Post) bool) error {
+	if user == nil {
+		return ErrUnauthenticated
+	}
+	if !allowed(user, post) {
+		return ErrForbidden
+	}
+	return nil
+}
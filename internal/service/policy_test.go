@@ -0,0 +1,158 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+var (
+	roles       = []models.Role{models.RoleReader, models.RoleAuthor, models.RoleEditor, models.RoleAdmin}
+	permissions = []service.Permission{
+		service.PermCreatePost, service.PermEditOwnPost, service.PermEditAnyPost,
+		service.PermDeleteOwnPost, service.PermDeleteAnyPost, service.PermModerateComments,
+		service.PermManageModeration, service.PermManageCategories, service.PermManageTags,
+		service.PermManageUsers,
+	}
+)
+
+// Политика целиком: у каждой роли ровно перечисленные права
+func TestRolePermissions(t *testing.T) {
+	own := []service.Permission{service.PermCreatePost, service.PermEditOwnPost, service.PermDeleteOwnPost}
+	editor := append(own[:len(own):len(own)], service.PermEditAnyPost, service.PermDeleteAnyPost, service.PermModerateComments)
+	want := map[models.Role][]service.Permission{
+		models.RoleReader: nil,
+		models.RoleAuthor: own,
+		models.RoleEditor: editor,
+		models.RoleAdmin:  permissions,
+	}
+
+	for _, role := range roles {
+		granted := map[service.Permission]bool{}
+		for _, perm := range want[role] {
+			granted[perm] = true
+		}
+		for _, perm := range permissions {
+			if got := service.Can(actor(1, role), perm); got != granted[perm] {
+				t.Errorf("Can(%s, %s) = %v, want %v", role, perm, got, granted[perm])
+			}
+		}
+	}
+	for _, perm := range permissions {
+		if service.Can(nil, perm) {
+			t.Errorf("Can(гость, %s) = true", perm)
+		}
+		if service.Can(actor(1, "superuser"), perm) {
+			t.Errorf("Can(неизвестная роль, %s) = true", perm)
+		}
+	}
+}
+
+// Свой и чужой пост: правка, удаление и просмотр черновика
+func TestPostPolicy(t *testing.T) {
+	ownerID, otherID := 1, 2
+	draft := &models.Post{ID: 1, Status: models.PostDraft, UserID: &ownerID}
+
+	// Что роль может сделать со своим и с чужим черновиком
+	type rights struct{ own, any bool }
+	tests := []struct {
+		role         models.Role
+		edit, delete rights
+	}{
+		{models.RoleReader, rights{false, false}, rights{false, false}},
+		{models.RoleAuthor, rights{true, false}, rights{true, false}},
+		{models.RoleEditor, rights{true, true}, rights{true, true}},
+		{models.RoleAdmin, rights{true, true}, rights{true, true}},
+	}
+	for _, tt := range tests {
+		for _, owner := range []bool{true, false} {
+			user := actor(otherID, tt.role)
+			edit, del := tt.edit.any, tt.delete.any
+			if owner {
+				user.ID = ownerID
+				edit, del = tt.edit.own, tt.delete.own
+			}
+			if got := service.CanEditPost(user, draft); got != edit {
+				t.Errorf("CanEditPost(%s, владелец %v) = %v, want %v", tt.role, owner, got, edit)
+			}
+			if got := service.CanDeletePost(user, draft); got != del {
+				t.Errorf("CanDeletePost(%s, владелец %v) = %v, want %v", tt.role, owner, got, del)
+			}
+			// Черновик видят те, кто может его править
+			if got := service.CanViewPost(user, draft); got != edit {
+				t.Errorf("CanViewPost(%s, владелец %v) = %v, want %v", tt.role, owner, got, edit)
+			}
+		}
+	}
+
+	published := *draft
+	published.Status = models.PostPublished
+	if !service.CanViewPost(nil, &published) || service.CanEditPost(nil, &published) {
+		t.Error("гость должен видеть опубликованный пост и не может его править")
+	}
+}
+
+// Сервис постов отказывает по той же политике: ErrForbidden - это 403 в API
+// и на сайте, ErrUnauthenticated - 401
+func TestPostServiceForbidden(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		posts := f.postService(newMemBlob())
+		reader := actor(f.user("reader", models.RoleReader), models.RoleReader)
+		other := actor(f.user("other", models.RoleAuthor), models.RoleAuthor)
+		author := actor(f.author, models.RoleAuthor)
+		editor := actor(f.user("editor", models.RoleEditor), models.RoleEditor)
+		id := f.post("Пост автора", models.PostPublished)
+
+		tests := []struct {
+			name  string
+			actor *models.User
+			call  func(*models.User) error
+			want  error
+		}{
+			{"гость создаёт пост", nil, createPost(posts, f.category), service.ErrUnauthenticated},
+			{"читатель создаёт пост", reader, createPost(posts, f.category), service.ErrForbidden},
+			{"читатель правит пост", reader, updatePost(posts, id, f.category), service.ErrForbidden},
+			{"автор правит чужой пост", other, updatePost(posts, id, f.category), service.ErrForbidden},
+			{"автор удаляет чужой пост", other, deletePost(posts, id), service.ErrForbidden},
+			{"читатель удаляет пост", reader, deletePost(posts, id), service.ErrForbidden},
+			{"автор создаёт пост", author, createPost(posts, f.category), nil},
+			{"автор правит свой пост", author, updatePost(posts, id, f.category), nil},
+			{"редактор правит чужой пост", editor, updatePost(posts, id, f.category), nil},
+			{"редактор удаляет чужой пост", editor, deletePost(posts, id), nil},
+		}
+		for _, tt := range tests {
+			if err := tt.call(tt.actor); !errors.Is(err, tt.want) {
+				t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+			}
+		}
+	})
+}
+
+func createPost(posts *service.PostService, categoryID int) func(*models.User) error {
+	return func(user *models.User) error {
+		_, err := posts.CreatePost(user, "Новый пост", "Текст", categoryID, nil, service.Publication{}, nil)
+		return err
+	}
+}
+
+func updatePost(posts *service.PostService, id, categoryID int) func(*models.User) error {
+	return func(user *models.User) error {
+		post, err := posts.GetPostByID(user, id)
+		if err != nil {
+			return err
+		}
+		_, err = posts.UpdatePost(user, id, post.Revision, "Правка "+string(user.Role), "Текст", categoryID, nil, nil)
+		return err
+	}
+}
+
+func deletePost(posts *service.PostService, id int) func(*models.User) error {
+	return func(user *models.User) error {
+		return posts.DeletePost(user, id)
+	}
+}
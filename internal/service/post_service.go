@@ -1,99 +1,699 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/diff"
+	"github.com/s.usynin/testing/go-server/internal/markdown"
 	"github.com/s.usynin/testing/go-server/internal/models"
 	"github.com/s.usynin/testing/go-server/internal/repository"
 )
 
 type PostService struct {
-	postRepo     *repository.PostRepository
-	commentRepo  *repository.CommentRepository
-	categoryRepo *repository.CategoryRepository
-	likeRepo     *repository.LikeRepository
+	postRepo     repository.PostRepository
+	commentRepo  repository.CommentRepository
+	categoryRepo repository.CategoryRepository
+	tagRepo      repository.TagRepository
+	likeRepo     repository.LikeRepository
+	revisionRepo repository.RevisionRepository
+	markdown     *markdown.Cache
+	moderation   *ModerationService
+	attachments  *AttachmentService
+	sitemap      *Sitemap
+	// maxCommentDepth - глубже этого уровня ответы показываются на нём же
+	maxCommentDepth int
 }
 
 func NewPostService(
-	postRepo *repository.PostRepository,
-	commentRepo *repository.CommentRepository,
-	categoryRepo *repository.CategoryRepository,
-	likeRepo *repository.LikeRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	categoryRepo repository.CategoryRepository,
+	tagRepo repository.TagRepository,
+	likeRepo repository.LikeRepository,
+	revisionRepo repository.RevisionRepository,
+	markdownCache *markdown.Cache,
+	moderation *ModerationService,
+	attachments *AttachmentService,
+	sitemap *Sitemap,
+	maxCommentDepth int,
 ) *PostService {
+	if maxCommentDepth < 1 {
+		maxCommentDepth = DefaultMaxCommentDepth
+	}
 	return &PostService{
-		postRepo:     postRepo,
-		commentRepo:  commentRepo,
-		categoryRepo: categoryRepo,
-		likeRepo:     likeRepo,
+		postRepo:        postRepo,
+		commentRepo:     commentRepo,
+		categoryRepo:    categoryRepo,
+		tagRepo:         tagRepo,
+		likeRepo:        likeRepo,
+		revisionRepo:    revisionRepo,
+		markdown:        markdownCache,
+		moderation:      moderation,
+		attachments:     attachments,
+		sitemap:         sitemap,
+		maxCommentDepth: maxCommentDepth,
 	}
 }
 
-func (s *PostService) GetAllPosts() ([]models.Post, error) {
-	posts, err := s.postRepo.GetAll()
+// PostPage - страница ленты постов. Next - курсор следующей страницы, nil на последней.
+type PostPage struct {
+	Posts []models.Post
+	Total int
+	Next  *repository.Cursor
+}
+
+// ListPosts возвращает страницу постов по фильтру, начиная с новых, и общее число
+// подходящих постов. Следующая страница запрашивается с filter.Before = page.Next.
+func (s *PostService) ListPosts(filter repository.PostFilter) (*PostPage, error) {
+	countFilter := filter
+	countFilter.Before = nil
+	total, err := s.postRepo.Count(countFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Лишний пост показывает, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	posts, err := s.postRepo.List(filter)
 	if err != nil {
 		return nil, err
 	}
 
-	// Загружаем категории для постов
+	page := &PostPage{Posts: posts, Total: total}
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+		last := page.Posts[limit-1]
+		page.Next = repository.CursorOf(last.Date(), last.ID)
+	}
+
+	s.attachCategories(page.Posts)
+	s.renderPosts(page.Posts)
+	return page, nil
+}
+
+// attachCategories загружает категории, теги и вложения для списка постов
+func (s *PostService) attachCategories(posts []models.Post) {
+	pointers := make([]*models.Post, len(posts))
 	for i := range posts {
-		category, err := s.categoryRepo.GetByID(posts[i].CategoryID)
-		if err == nil {
-			posts[i].Category = category
+		pointers[i] = &posts[i]
+	}
+	s.attachCategory(pointers...)
+	s.attachTags(pointers...)
+	s.attachments.attach(pointers...)
+}
+
+// attachCategory загружает категории постов вместе с цепочкой родителей
+func (s *PostService) attachCategory(posts ...*models.Post) {
+	tree, err := s.CategoryTree()
+	if err != nil {
+		log.Printf("Ошибка загрузки категорий: %v", err)
+		return
+	}
+	for _, post := range posts {
+		post.Category, _ = tree.Get(post.CategoryID)
+	}
+}
+
+// attachTags загружает теги постов
+func (s *PostService) attachTags(posts ...*models.Post) {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	tags, err := s.tagRepo.ListByPosts(ids)
+	if err != nil {
+		log.Printf("Ошибка загрузки тегов: %v", err)
+		return
+	}
+	for _, post := range posts {
+		post.Tags = tags[post.ID]
+	}
+}
+
+// GetPostByID возвращает пост с последними комментариями. Черновик или
+// запланированный пост для того, кто не может его править, не найден.
+func (s *PostService) GetPostByID(viewer *models.User, id int) (*models.Post, error) {
+	post, err := s.getVisiblePost(viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Загружаем категорию, теги и вложения
+	s.attachCategory(post)
+	s.attachTags(post)
+	s.attachments.attach(post)
+
+	// Загружаем последние комментарии
+	if err := s.LoadLatestComments(CommentsPerPage, post); err != nil {
+		return nil, err
+	}
+
+	s.renderPost(post)
+	return post, nil
+}
+
+// GetPostWithAllComments возвращает пост для его отдельной страницы:
+// в отличие от карточки в ленте, со всеми комментариями
+func (s *PostService) GetPostWithAllComments(viewer *models.User, id int) (*models.Post, error) {
+	post, err := s.getVisiblePost(viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachCategory(post)
+	s.attachTags(post)
+	s.attachments.attach(post)
+
+	var comments []models.Comment
+	var before *repository.Cursor
+	for {
+		page, err := s.listComments(post, before, allCommentsBatch)
+		if err != nil {
+			return nil, err
 		}
+		comments = append(comments, page.Comments...)
+		if page.Next == nil {
+			break
+		}
+		before = page.Next
 	}
+	post.Comments = (&CommentPage{Comments: comments}).Chronological()
 
-	return posts, nil
+	s.renderPost(post)
+	return post, nil
 }
 
-func (s *PostService) GetPostByID(id int) (*models.Post, error) {
+// getVisiblePost загружает пост, если viewer может его видеть
+func (s *PostService) getVisiblePost(viewer *models.User, id int) (*models.Post, error) {
 	post, err := s.postRepo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if !CanViewPost(viewer, post) {
+		return nil, ErrNotFound
+	}
+	return post, nil
+}
+
+const (
+	// CommentsPerPage - комментариев на странице поста и в одной догрузке
+	CommentsPerPage = 20
+	// allCommentsBatch - комментариев верхнего уровня за один запрос для страницы поста
+	allCommentsBatch = 100
+)
+
+// CommentPage - страница комментариев верхнего уровня от новых к старым,
+// каждый с деревом ответов. Next - курсор для более ранних, nil если их нет.
+type CommentPage struct {
+	Comments []models.Comment
+	Total    int
+	Next     *repository.Cursor
+}
+
+// ListComments возвращает до limit комментариев верхнего уровня, более ранних
+// чем before, вместе с ответами на них. Комментарии поста, который viewer
+// не может видеть, не найдены.
+func (s *PostService) ListComments(viewer *models.User, postID int, before *repository.Cursor, limit int) (*CommentPage, error) {
+	post, err := s.getVisiblePost(viewer, postID)
 	if err != nil {
 		return nil, err
 	}
+	return s.listComments(post, before, limit)
+}
 
-	// Загружаем категорию
-	category, err := s.categoryRepo.GetByID(post.CategoryID)
-	if err == nil {
-		post.Category = category
+// listComments - ListComments для уже загруженного и проверенного поста
+func (s *PostService) listComments(post *models.Post, before *repository.Cursor, limit int) (*CommentPage, error) {
+	comments, err := s.commentRepo.ListByPost(post.ID, before, limit+1)
+	if err != nil {
+		return nil, err
 	}
 
-	// Загружаем комментарии
-	comments, err := s.commentRepo.GetByPostID(id)
-	if err == nil {
-		post.Comments = comments
+	page := &CommentPage{Comments: comments, Total: post.CommentsCount}
+	if len(comments) > limit {
+		page.Comments = comments[:limit]
+		last := page.Comments[limit-1]
+		page.Next = repository.CursorOf(last.CreatedAt, last.ID)
 	}
 
-	return post, nil
+	if page.Comments, err = s.loadReplies(page.Comments, 0); err != nil {
+		return nil, err
+	}
+
+	s.renderComments(page.Comments)
+	return page, nil
 }
 
-func (s *PostService) CreatePost(title, content string, categoryID int) (*models.Post, error) {
-	id, err := s.postRepo.Create(title, content, categoryID)
+// LoadLatestComments загружает каждому посту в post.Comments последние limit
+// комментариев в хронологическом порядке и курсор для более ранних
+// в post.CommentsCursor. Комментарии и ответы на них всех постов читаются
+// двумя запросами, сколько бы постов ни было в ленте.
+func (s *PostService) LoadLatestComments(limit int, posts ...*models.Post) error {
+	var ids []int
+	for _, post := range posts {
+		post.Comments, post.CommentsCursor = nil, ""
+		if post.CommentsCount > 0 {
+			ids = append(ids, post.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	latest, err := s.commentRepo.ListLatestByPosts(ids, limit+1)
+	if err != nil {
+		return err
+	}
+
+	var roots []models.Comment
+	for _, post := range posts {
+		comments := latest[post.ID]
+		if len(comments) > limit {
+			last := comments[limit-1]
+			post.CommentsCursor = repository.CursorOf(last.CreatedAt, last.ID).String()
+			comments = comments[:limit]
+		}
+		roots = append(roots, comments...)
+	}
+
+	comments, err := s.loadReplies(roots, 0)
+	if err != nil {
+		return err
+	}
+	s.renderComments(comments)
+
+	byPost := make(map[int][]models.Comment)
+	for _, comment := range comments {
+		byPost[comment.PostID] = append(byPost[comment.PostID], comment)
+	}
+	for _, post := range posts {
+		if page := byPost[post.ID]; page != nil {
+			post.Comments = (&CommentPage{Comments: page}).Chronological()
+		}
+	}
+	return nil
+}
+
+// Chronological возвращает комментарии страницы от старых к новым - в порядке показа
+func (p *CommentPage) Chronological() []models.Comment {
+	reversed := make([]models.Comment, len(p.Comments))
+	for i, comment := range p.Comments {
+		reversed[len(p.Comments)-1-i] = comment
+	}
+	return reversed
+}
+
+// renderPosts заполняет ContentHTML у списка постов
+func (s *PostService) renderPosts(posts []models.Post) {
+	for i := range posts {
+		s.renderPost(&posts[i])
+	}
+}
+
+// renderPost заполняет ContentHTML поста и его комментариев.
+// HTML поста кэшируется по номеру ревизии, поэтому правка сразу даёт новый ключ.
+func (s *PostService) renderPost(post *models.Post) {
+	post.ContentHTML = s.render(fmt.Sprintf("post:%d:%d", post.ID, post.Revision), post.Content)
+	s.renderComments(post.Comments)
+}
+
+// renderComments заполняет ContentHTML комментариев и всех ответов на них
+func (s *PostService) renderComments(comments []models.Comment) {
+	for i := range comments {
+		if !comments[i].Deleted() {
+			comments[i].ContentHTML = s.render(fmt.Sprintf("comment:%d", comments[i].ID), comments[i].Content)
+		}
+		s.renderComments(comments[i].Replies)
+	}
+}
+
+// render возвращает HTML из кэша; при ошибке рендера показывается экранированный исходный текст
+func (s *PostService) render(key, source string) template.HTML {
+	html, err := s.markdown.Render(key, source)
+	if err != nil {
+		log.Printf("Ошибка рендера Markdown %s: %v", key, err)
+		return template.HTML(template.HTMLEscapeString(source))
+	}
+	return html
+}
+
+// PreviewMarkdown рендерит текст из формы для предпросмотра, не сохраняя его
+func (s *PostService) PreviewMarkdown(content string) (template.HTML, error) {
+	return s.markdown.Preview(content)
+}
+
+// CreatePost создаёт пост с тегами tagNames и вложениями uploads; новые теги
+// создаются. pub - сразу опубликовать, сохранить черновик или запланировать
+// публикацию. Если теги или файлы сохранить не удалось, пост удаляется:
+// наполовину сохранённым он не остаётся.
+func (s *PostService) CreatePost(author *models.User, title, content string, categoryID int, tagNames []string, pub Publication, uploads []Upload) (*models.Post, error) {
+	if err := authorize(author, PermCreatePost); err != nil {
+		return nil, err
+	}
+	if err := s.validatePost(title, content, categoryID); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(tagNames)
+	if err != nil {
+		return nil, err
+	}
+	status, publishedAt, err := pub.resolve(nil, time.Now())
 	if err != nil {
 		return nil, err
 	}
+	if err := s.attachments.Check(0, uploads); err != nil {
+		return nil, err
+	}
+
+	id, err := s.postRepo.Create(author.ID, title, content, categoryID, status, publishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tagRepo.SetPostTags(int(id), tags); err != nil {
+		s.discardPost(int(id))
+		return nil, err
+	}
+	if len(uploads) > 0 {
+		if _, err := s.attachments.Attach(author, int(id), uploads); err != nil {
+			s.discardPost(int(id))
+			return nil, err
+		}
+	}
+	s.sitemap.PostChanged(int(id))
 
-	return s.GetPostByID(int(id))
+	return s.GetPostByID(author, int(id))
 }
 
-func (s *PostService) DeletePost(id int) error {
-	return s.postRepo.Delete(id)
+// discardPost удаляет только что созданный пост, который не удалось
+// сохранить целиком. Его вложения Attach к этому времени уже откатил.
+func (s *PostService) discardPost(id int) {
+	if err := s.postRepo.Delete(id); err != nil {
+		log.Printf("Ошибка удаления недосохранённого поста %d: %v", id, err)
+	}
 }
 
-func (s *PostService) GetPostsByCategory(categoryID int) ([]models.Post, error) {
-	return s.postRepo.GetByCategoryID(categoryID)
+// DeletePost удаляет пост, если у пользователя есть на это право:
+// автор может удалить свой пост, редактор и администратор - любой.
+// Файлы вложений удаляются из хранилища только после того, как удалена
+// запись поста: иначе ошибка БД оставила бы пост с пропавшими файлами.
+func (s *PostService) DeletePost(actor *models.User, id int) error {
+	post, err := s.postRepo.GetByID(id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if err := authorizePost(actor, post, CanDeletePost); err != nil {
+		return err
+	}
+
+	attachments, err := s.attachments.list(id)
+	if err != nil {
+		return err
+	}
+	if err := s.postRepo.Delete(id); err != nil {
+		return err
+	}
+	s.attachments.removeFiles(attachments...)
+	s.sitemap.PostChanged(id)
+	return nil
+}
+
+// UpdatePost сохраняет новую версию поста. baseRevision - ревизия, с которой
+// открывали форму: если пост успели изменить, возвращается ErrConflict.
+// Теги и состояние публикации в ревизиях не хранятся: tagNames и pub
+// применяются сразу, nil оставляет их как есть.
+func (s *PostService) UpdatePost(actor *models.User, id, baseRevision int, title, content string, categoryID int, tagNames []string, pub *Publication) (*models.Post, error) {
+	post, err := s.GetPostForEdit(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validatePost(title, content, categoryID); err != nil {
+		return nil, err
+	}
+	var tags []models.Tag
+	if tagNames != nil {
+		if tags, err = normalizeTags(tagNames); err != nil {
+			return nil, err
+		}
+	}
+	status, publishedAt := post.Status, post.PublishedAt
+	if pub != nil {
+		if status, publishedAt, err = pub.resolve(post, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Ничего не изменилось - новую ревизию не создаём
+	update := repository.PostUpdate{
+		BaseRevision: baseRevision,
+		EditorID:     actor.ID,
+		Revise:       post.Title != title || post.Content != content || post.CategoryID != categoryID,
+		Title:        title,
+		Content:      content,
+		CategoryID:   categoryID,
+		Tags:         tags,
+	}
+	if status != post.Status || !sameTime(publishedAt, post.PublishedAt) {
+		update.Status, update.PublishedAt = status, publishedAt
+	}
+	ok, err := s.postRepo.Update(id, update)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrConflict
+	}
+	s.sitemap.PostChanged(id)
+	return s.GetPostByID(actor, id)
+}
+
+// GetRevisions возвращает пост и его ревизии, начиная с последней
+func (s *PostService) GetRevisions(actor *models.User, postID int) (*models.Post, []models.PostRevision, error) {
+	post, err := s.GetPostForEdit(actor, postID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revisions, err := s.revisionRepo.GetByPostID(postID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return post, revisions, nil
+}
+
+// RevisionDiff - построчное сравнение двух ревизий поста
+type RevisionDiff struct {
+	From    *models.PostRevision
+	To      *models.PostRevision
+	Title   []diff.Line
+	Content []diff.Line
+}
+
+func (s *PostService) DiffRevisions(actor *models.User, postID, from, to int) (*RevisionDiff, error) {
+	if _, err := s.GetPostForEdit(actor, postID); err != nil {
+		return nil, err
+	}
+
+	fromRev, err := s.getRevision(postID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := s.getRevision(postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	title, err := diff.Lines(fromRev.Title, toRev.Title)
+	if err != nil {
+		return nil, diffError(err)
+	}
+	content, err := diff.Lines(fromRev.Content, toRev.Content)
+	if err != nil {
+		return nil, diffError(err)
+	}
+
+	return &RevisionDiff{
+		From:    fromRev,
+		To:      toRev,
+		Title:   title,
+		Content: content,
+	}, nil
+}
+
+// diffError превращает слишком большое сравнение в ошибку проверки
+func diffError(err error) error {
+	if errors.Is(err, diff.ErrTooLarge) {
+		return invalid("to", "Ревизии слишком велики или слишком различаются для построчного сравнения")
+	}
+	return err
+}
+
+// RestoreRevision возвращает пост к старой версии. История не переписывается:
+// восстановленное содержимое сохраняется как новая ревизия.
+func (s *PostService) RestoreRevision(actor *models.User, postID, revision int) (*models.Post, error) {
+	post, err := s.GetPostForEdit(actor, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := s.getRevision(postID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.UpdatePost(actor, postID, post.Revision, rev.Title, rev.Content, rev.CategoryID, nil, nil)
+}
+
+// GetPostForEdit загружает пост с тегами и вложениями и проверяет право на его редактирование
+func (s *PostService) GetPostForEdit(actor *models.User, id int) (*models.Post, error) {
+	post, err := s.postRepo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if err := authorizePost(actor, post, CanEditPost); err != nil {
+		return nil, err
+	}
+
+	s.attachTags(post)
+	s.attachments.attach(post)
+	return post, nil
 }
 
+func (s *PostService) getRevision(postID, revision int) (*models.PostRevision, error) {
+	rev, err := s.revisionRepo.Get(postID, revision)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return rev, nil
+}
+
+// validatePost проверяет поля поста перед сохранением
+func (s *PostService) validatePost(title, content string, categoryID int) error {
+	if strings.TrimSpace(title) == "" {
+		return invalid("title", "Заголовок обязателен")
+	}
+	if strings.TrimSpace(content) == "" {
+		return invalid("content", "Содержание обязательно")
+	}
+	if _, err := s.categoryRepo.GetByID(categoryID); errors.Is(err, sql.ErrNoRows) {
+		return invalid("category_id", "Категория не найдена")
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetCategories возвращает все категории деревом: подкатегории идут сразу
+// за родителем, Depth задаёт отступ
 func (s *PostService) GetCategories() ([]models.Category, error) {
-	return s.categoryRepo.GetAll()
+	tree, err := s.CategoryTree()
+	if err != nil {
+		return nil, err
+	}
+	return tree.Flatten(), nil
 }
 
-func (s *PostService) AddComment(postID int, author, content string) (*models.Comment, error) {
-	id, err := s.commentRepo.Create(postID, author, content)
+// CategoryTree загружает все категории деревом
+func (s *PostService) CategoryTree() (*CategoryTree, error) {
+	categories, err := s.categoryRepo.GetAll()
 	if err != nil {
 		return nil, err
 	}
+	return NewCategoryTree(categories), nil
+}
 
-	return s.commentRepo.GetByID(int(id))
+// GetCategoryByID возвращает категорию с цепочкой родителей
+func (s *PostService) GetCategoryByID(id int) (*models.Category, error) {
+	category, err := s.categoryRepo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return s.withAncestors(category)
 }
 
-func (s *PostService) AddLike(postID int) (int64, error) {
-	return s.likeRepo.Add(postID)
+// GetCategoryBySlug возвращает категорию с цепочкой родителей
+func (s *PostService) GetCategoryBySlug(slug string) (*models.Category, error) {
+	category, err := s.categoryRepo.GetBySlug(slug)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return s.withAncestors(category)
+}
+
+func (s *PostService) withAncestors(category *models.Category) (*models.Category, error) {
+	tree, err := s.CategoryTree()
+	if err != nil {
+		return nil, err
+	}
+	category.Ancestors = tree.Ancestors(category.ID)
+	category.Depth = len(category.Ancestors)
+	return category, nil
+}
+
+// ToggleLike ставит лайк посту от liker или снимает уже поставленный
+// и возвращает, стоит ли лайк теперь
+func (s *PostService) ToggleLike(postID int, liker repository.Liker) (bool, error) {
+	if err := s.checkLike(postID, liker); err != nil {
+		return false, err
+	}
+
+	added, err := s.likeRepo.Add(postID, liker)
+	if err != nil || added {
+		return added, err
+	}
+	_, err = s.likeRepo.Remove(postID, liker)
+	return false, err
+}
+
+// Like ставит лайк; повторный лайк ничего не меняет
+func (s *PostService) Like(postID int, liker repository.Liker) error {
+	if err := s.checkLike(postID, liker); err != nil {
+		return err
+	}
+	_, err := s.likeRepo.Add(postID, liker)
+	return err
+}
+
+// Unlike снимает лайк, если он был
+func (s *PostService) Unlike(postID int, liker repository.Liker) error {
+	if err := s.checkLike(postID, liker); err != nil {
+		return err
+	}
+	_, err := s.likeRepo.Remove(postID, liker)
+	return err
+}
+
+func (s *PostService) checkLike(postID int, liker repository.Liker) error {
+	if liker.Anonymous() {
+		return ErrUnauthenticated
+	}
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return notFound(err)
+	}
+	return checkOpen(post)
+}
+
+// LoadLiked отмечает в Post.Liked посты, которые лайкнул liker
+func (s *PostService) LoadLiked(liker repository.Liker, posts ...*models.Post) error {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	liked, err := s.likeRepo.Liked(liker, ids)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.Liked = liked[post.ID]
+	}
+	return nil
 }
@@ -0,0 +1,188 @@
+package service_test
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+// memBlob - хранилище в памяти, которое может отказывать в записи и удалении
+type memBlob struct {
+	mu    sync.Mutex
+	files map[string]string
+	// putsLeft - сколько ещё Put пройдёт; отрицательное - без ограничений
+	putsLeft   int
+	failDelete bool
+}
+
+func newMemBlob() *memBlob {
+	return &memBlob{files: map[string]string{}, putsLeft: -1}
+}
+
+var errBlob = errors.New("хранилище недоступно")
+
+func (b *memBlob) Put(key string, r io.Reader, size int64, contentType string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.putsLeft == 0 {
+		return errBlob
+	}
+	b.putsLeft--
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.files[key] = string(data)
+	return nil
+}
+
+func (b *memBlob) Get(key string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.files[key]
+	if !ok {
+		return nil, errBlob
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func (b *memBlob) Delete(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failDelete {
+		return errBlob
+	}
+	delete(b.files, key)
+	return nil
+}
+
+func (b *memBlob) keys() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keys := make([]string, 0, len(b.files))
+	for key := range b.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func textUpload(name, text string) service.Upload {
+	return service.Upload{Filename: name, Size: int64(len(text)), Content: strings.NewReader(text)}
+}
+
+func TestCreatePostWithAttachments(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		blob := newMemBlob()
+		posts := f.postService(blob)
+		author := actor(f.author, models.RoleAuthor)
+		uploads := []service.Upload{textUpload("a.txt", "первый"), textUpload("b.txt", "второй")}
+
+		// Второй файл не записался: нет ни поста, ни первого файла, ни тегов
+		blob.putsLeft = 1
+		if _, err := posts.CreatePost(author, "Пост", "Текст", f.category, []string{"go"}, service.Publication{}, uploads); !errors.Is(err, errBlob) {
+			t.Fatalf("CreatePost при отказе хранилища: %v, want errBlob", err)
+		}
+		if n, err := f.posts.Count(repository.PostFilter{}); err != nil || n != 0 {
+			t.Errorf("после отказа постов: %d, %v; want 0", n, err)
+		}
+		if keys := blob.keys(); len(keys) != 0 {
+			t.Errorf("после отказа в хранилище остались %v", keys)
+		}
+
+		blob.putsLeft = -1
+		post, err := posts.CreatePost(author, "Пост", "Текст", f.category, []string{"go"}, service.Publication{}, uploads)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(post.Attachments) != 2 || len(post.Tags) != 1 {
+			t.Errorf("пост: %d вложений, %d тегов; want 2 и 1", len(post.Attachments), len(post.Tags))
+		}
+		if keys := blob.keys(); len(keys) != 2 {
+			t.Errorf("в хранилище %v, want 2 файла", keys)
+		}
+
+		// Файлы, которые не удалось удалить, не мешают удалить пост
+		blob.failDelete = true
+		if err := posts.DeletePost(author, post.ID); err != nil {
+			t.Fatalf("DeletePost при отказе хранилища: %v", err)
+		}
+		if _, err := f.posts.GetByID(post.ID); err == nil {
+			t.Error("пост не удалён")
+		}
+	})
+}
+
+// Лента загружает последние комментарии всех постов сразу: каждому посту -
+// свои, с ответами и курсором для более ранних
+func TestLoadLatestComments(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		posts := f.postService(newMemBlob())
+		first := f.post("Первый", models.PostPublished)
+		second := f.post("Второй", models.PostPublished)
+		empty := f.post("Без комментариев", models.PostPublished)
+
+		oldest := f.comment(first, models.CommentApproved)
+		middle := f.comment(first, models.CommentApproved)
+		newest := f.comment(first, models.CommentApproved)
+		reply, err := f.comments.Create(&models.Comment{PostID: first, ParentID: &newest, Author: "Гость", Content: "Ответ", Status: models.CommentApproved})
+		if err != nil {
+			t.Fatal(err)
+		}
+		only := f.comment(second, models.CommentApproved)
+
+		loaded := make([]*models.Post, 3)
+		for i, id := range []int{first, second, empty} {
+			if loaded[i], err = f.posts.GetByID(id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := posts.LoadLatestComments(2, loaded...); err != nil {
+			t.Fatal(err)
+		}
+
+		ids := func(comments []models.Comment) []int {
+			result := make([]int, len(comments))
+			for i, comment := range comments {
+				result[i] = comment.ID
+			}
+			return result
+		}
+		if got := ids(loaded[0].Comments); len(got) != 2 || got[0] != middle || got[1] != newest {
+			t.Errorf("первый пост: %v, want [%d %d]", got, middle, newest)
+		}
+		if got := loaded[0].Comments; len(got) == 2 && (len(got[1].Replies) != 1 || got[1].Replies[0].ID != int(reply) || got[1].ContentHTML == "") {
+			t.Errorf("ответы последнего комментария: %+v", got[1])
+		}
+		if got := ids(loaded[1].Comments); len(got) != 1 || got[0] != only || loaded[1].CommentsCursor != "" {
+			t.Errorf("второй пост: %v, курсор %q", got, loaded[1].CommentsCursor)
+		}
+		if loaded[2].Comments != nil || loaded[2].CommentsCursor != "" {
+			t.Errorf("пост без комментариев: %v, курсор %q", loaded[2].Comments, loaded[2].CommentsCursor)
+		}
+
+		// Курсор ведёт к более ранним комментариям
+		cursor, err := repository.ParseCursor(loaded[0].CommentsCursor)
+		if err != nil || cursor == nil {
+			t.Fatalf("курсор %q: %v", loaded[0].CommentsCursor, err)
+		}
+		page, err := posts.ListComments(nil, first, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(page.Comments); len(got) != 1 || got[0] != oldest || page.Next != nil {
+			t.Errorf("более ранние: %v, Next %v", got, page.Next)
+		}
+	})
+}
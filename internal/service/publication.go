@@ -0,0 +1,103 @@
+package service
+
+import (
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// Publication - как опубликовать пост: состояние и время публикации.
+// Пустое состояние значит "опубликовать"; At в будущем у опубликованного
+// поста делает его запланированным, At в прошлом задаёт дату публикации.
+type Publication struct {
+	Status models.PostStatus
+	At     *time.Time
+}
+
+// resolve проверяет публикацию и возвращает состояние и время публикации
+// для сохранения. post - текущая версия поста, nil при создании.
+func (p Publication) resolve(post *models.Post, now time.Time) (models.PostStatus, *time.Time, error) {
+	status := p.Status
+	if status == "" {
+		status = models.PostPublished
+	}
+	if !status.Valid() {
+		return "", nil, invalid("status", "Неизвестное состояние поста")
+	}
+	// Формы передают время с точностью до минуты: та же минута - та же дата
+	if p.At != nil && post != nil && post.PublishedAt != nil &&
+		p.At.Equal(post.PublishedAt.Truncate(time.Minute)) {
+		p.At = post.PublishedAt
+	}
+
+	switch status {
+	case models.PostDraft:
+		return status, nil, nil
+
+	case models.PostScheduled:
+		if p.At == nil {
+			return "", nil, invalid("published_at", "Укажите время публикации")
+		}
+		if !p.At.After(now) {
+			return "", nil, invalid("published_at", "Время публикации уже прошло")
+		}
+		return status, p.At, nil
+
+	case models.PostArchived:
+		if post == nil {
+			return "", nil, invalid("status", "Новый пост нельзя сразу отправить в архив")
+		}
+		return status, post.PublishedAt, nil
+	}
+
+	// Опубликовать: в будущем - по расписанию, иначе сейчас или в указанное время.
+	// Уже опубликованный пост сохраняет свою дату.
+	switch {
+	case p.At != nil && p.At.After(now):
+		return models.PostScheduled, p.At, nil
+	case p.At != nil:
+		return status, p.At, nil
+	case post != nil && post.PublishedAt != nil && (post.Published() || post.Status == models.PostArchived):
+		return status, post.PublishedAt, nil
+	}
+	return status, &now, nil
+}
+
+// checkOpen проверяет, что пост можно комментировать, лайкать и отмечать
+// реакциями: черновики и запланированные посты "не существуют" для читателей,
+// у архивных всё это закрыто
+func checkOpen(post *models.Post) error {
+	switch post.Status {
+	case models.PostPublished:
+		return nil
+	case models.PostArchived:
+		return invalid("post_id", "Пост в архиве: комментарии и оценки закрыты")
+	}
+	return ErrNotFound
+}
+
+// ListPostsByStatus возвращает посты в состоянии filter.Status. Неопубликованные
+// посты видят только вошедшие: автор - свои, редактор и администратор - все.
+func (s *PostService) ListPostsByStatus(actor *models.User, filter repository.PostFilter) (*PostPage, error) {
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, invalid("status", "Неизвестное состояние поста")
+	}
+	if filter.Status != "" && filter.Status != models.PostPublished {
+		if err := authorize(actor, PermCreatePost); err != nil {
+			return nil, err
+		}
+		if !Can(actor, PermEditAnyPost) {
+			filter.UserID = actor.ID
+		}
+	}
+	return s.ListPosts(filter)
+}
+
+// sameTime сравнивает необязательные времена публикации
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// DefaultReactions - набор реакций по умолчанию
+var DefaultReactions = []string{"👍", "❤️", "😂", "😮", "😢"}
+
+// ReactionService - реакции на посты и комментарии из настраиваемого набора
+type ReactionService struct {
+	reactionRepo repository.ReactionRepository
+	postRepo     repository.PostRepository
+	commentRepo  repository.CommentRepository
+	emojis       []string
+}
+
+// NewReactionService создаёт сервис с набором реакций emojis; пустой набор
+// заменяется DefaultReactions
+func NewReactionService(
+	reactionRepo repository.ReactionRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	emojis []string,
+) *ReactionService {
+	if len(emojis) == 0 {
+		emojis = DefaultReactions
+	}
+	return &ReactionService{
+		reactionRepo: reactionRepo,
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+		emojis:       emojis,
+	}
+}
+
+func (s *ReactionService) available(emoji string) bool {
+	for _, e := range s.emojis {
+		if e == emoji {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTarget проверяет, что опубликованный пост или комментарий существует.
+// Комментарий открыт, только если открыт его пост: иначе по реакциям можно
+// было бы узнать о черновиках и запланированных постах.
+func (s *ReactionService) checkTarget(target models.ReactionTarget, id int) error {
+	postID := id
+	switch target {
+	case models.ReactionOnPost:
+	case models.ReactionOnComment:
+		comment, err := s.commentRepo.GetByID(id)
+		if err != nil {
+			return notFound(err)
+		}
+		if comment.Status != models.CommentApproved || comment.Deleted() {
+			return ErrNotFound
+		}
+		postID = comment.PostID
+	default:
+		return invalid("target", "Реакцию можно поставить посту или комментарию")
+	}
+
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return notFound(err)
+	}
+	return checkOpen(post)
+}
+
+// Toggle ставит реакцию или снимает уже поставленную и возвращает
+// обновлённые счётчики цели
+func (s *ReactionService) Toggle(target models.ReactionTarget, id int, emoji string, liker repository.Liker) ([]models.ReactionCount, error) {
+	if liker.Anonymous() {
+		return nil, ErrUnauthenticated
+	}
+	if err := s.checkTarget(target, id); err != nil {
+		return nil, err
+	}
+
+	removed, err := s.reactionRepo.Remove(target, id, emoji, liker)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		if err := s.add(target, id, emoji, liker); err != nil {
+			return nil, err
+		}
+	}
+	return s.Counts(target, id, liker)
+}
+
+// React ставит реакцию; повторная ничего не меняет
+func (s *ReactionService) React(target models.ReactionTarget, id int, emoji string, liker repository.Liker) ([]models.ReactionCount, error) {
+	if liker.Anonymous() {
+		return nil, ErrUnauthenticated
+	}
+	if err := s.checkTarget(target, id); err != nil {
+		return nil, err
+	}
+	if err := s.add(target, id, emoji, liker); err != nil {
+		return nil, err
+	}
+	return s.Counts(target, id, liker)
+}
+
+// Unreact снимает реакцию, если она была. Реакцию, убранную из набора,
+// тоже можно снять.
+func (s *ReactionService) Unreact(target models.ReactionTarget, id int, emoji string, liker repository.Liker) ([]models.ReactionCount, error) {
+	if liker.Anonymous() {
+		return nil, ErrUnauthenticated
+	}
+	if err := s.checkTarget(target, id); err != nil {
+		return nil, err
+	}
+	if _, err := s.reactionRepo.Remove(target, id, emoji, liker); err != nil {
+		return nil, err
+	}
+	return s.Counts(target, id, liker)
+}
+
+func (s *ReactionService) add(target models.ReactionTarget, id int, emoji string, liker repository.Liker) error {
+	if !s.available(emoji) {
+		return invalid("emoji", "Такой реакции нет в наборе блога")
+	}
+	_, err := s.reactionRepo.Add(target, id, emoji, liker)
+	return err
+}
+
+// Counts возвращает счётчики реакций цели с отметками реакций liker
+func (s *ReactionService) Counts(target models.ReactionTarget, id int, liker repository.Liker) ([]models.ReactionCount, error) {
+	all, err := s.load(target, []int{id}, liker)
+	if err != nil {
+		return nil, err
+	}
+	return all[id], nil
+}
+
+// LoadPosts заполняет Reactions у постов и всех их загруженных комментариев
+func (s *ReactionService) LoadPosts(liker repository.Liker, posts ...*models.Post) error {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	all, err := s.load(models.ReactionOnPost, ids, liker)
+	if err != nil {
+		return err
+	}
+
+	var comments []*models.Comment
+	for _, post := range posts {
+		post.Reactions = all[post.ID]
+		comments = appendComments(comments, post.Comments)
+	}
+	return s.loadComments(liker, comments)
+}
+
+// LoadComments заполняет Reactions у комментариев и всех ответов в их ветках
+func (s *ReactionService) LoadComments(liker repository.Liker, comments ...*models.Comment) error {
+	var all []*models.Comment
+	for _, comment := range comments {
+		all = append(all, comment)
+		all = appendComments(all, comment.Replies)
+	}
+	return s.loadComments(liker, all)
+}
+
+func (s *ReactionService) loadComments(liker repository.Liker, comments []*models.Comment) error {
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	all, err := s.load(models.ReactionOnComment, ids, liker)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		comment.Reactions = all[comment.ID]
+	}
+	return nil
+}
+
+// appendComments добавляет к list комментарии и, рекурсивно, их ответы
+func appendComments(list []*models.Comment, comments []models.Comment) []*models.Comment {
+	for i := range comments {
+		list = append(list, &comments[i])
+		list = appendComments(list, comments[i].Replies)
+	}
+	return list
+}
+
+// load собирает счётчики для целей ids: сначала все реакции набора (в том числе
+// с нулём, чтобы их можно было выбрать), затем реакции, убранные из набора,
+// если их ещё кто-то не снял
+func (s *ReactionService) load(target models.ReactionTarget, ids []int, liker repository.Liker) (map[int][]models.ReactionCount, error) {
+	counts, err := s.reactionRepo.Counts(target, ids)
+	if err != nil {
+		return nil, err
+	}
+	reacted, err := s.reactionRepo.Reacted(target, ids, liker)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int][]models.ReactionCount, len(ids))
+	for _, id := range ids {
+		list := make([]models.ReactionCount, 0, len(s.emojis))
+		for _, emoji := range s.emojis {
+			list = append(list, models.ReactionCount{
+				Emoji:     emoji,
+				Count:     counts[id][emoji],
+				Reacted:   reacted[id][emoji],
+				Available: true,
+			})
+		}
+		var removed []string
+		for emoji := range counts[id] {
+			if !s.available(emoji) {
+				removed = append(removed, emoji)
+			}
+		}
+		sort.Strings(removed)
+		for _, emoji := range removed {
+			list = append(list, models.ReactionCount{Emoji: emoji, Count: counts[id][emoji], Reacted: reacted[id][emoji]})
+		}
+		result[id] = list
+	}
+	return result, nil
+}
+
+// List возвращает страницу реакций цели - кто и что поставил - и их общее число
+func (s *ReactionService) List(target models.ReactionTarget, id int, emoji string, page, perPage int) ([]models.Reaction, int, error) {
+	if err := s.checkTarget(target, id); err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	total, err := s.reactionRepo.Count(target, id, emoji)
+	if err != nil {
+		return nil, 0, err
+	}
+	reactions, err := s.reactionRepo.List(target, id, emoji, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	return reactions, total, nil
+}
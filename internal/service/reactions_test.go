@@ -0,0 +1,40 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/database/dbtest"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/service"
+)
+
+func TestReactionsOnCommentFollowPost(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *database.DB) {
+		f := newFixture(t, db)
+		reactions := service.NewReactionService(repository.NewReactionRepository(db), f.posts, f.comments, nil)
+		visitor := repository.Liker{VisitorID: "visitor"}
+
+		published := f.comment(f.post("Опубликован", models.PostPublished), models.CommentApproved)
+		if _, err := reactions.Toggle(models.ReactionOnComment, published, "👍", visitor); err != nil {
+			t.Fatalf("реакция на комментарий опубликованного поста: %v", err)
+		}
+
+		// Комментарий под черновиком не должен выдавать, что черновик существует
+		draft := f.comment(f.post("Черновик", models.PostDraft), models.CommentApproved)
+		if _, err := reactions.Toggle(models.ReactionOnComment, draft, "👍", visitor); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("Toggle под черновиком: %v, want ErrNotFound", err)
+		}
+		if _, _, err := reactions.List(models.ReactionOnComment, draft, "", 1, 10); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("List под черновиком: %v, want ErrNotFound", err)
+		}
+
+		archived := f.comment(f.post("В архиве", models.PostArchived), models.CommentApproved)
+		var verr *service.ValidationError
+		if _, err := reactions.React(models.ReactionOnComment, archived, "👍", visitor); !errors.As(err, &verr) {
+			t.Errorf("React под архивным постом: %v, want ValidationError", err)
+		}
+	})
+}
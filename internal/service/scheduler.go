@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+const (
+	// schedulerMaxWait - как долго планировщик спит, не проверяя, не появились ли
+	// новые запланированные посты; до уже известных он просыпается точно в срок
+	schedulerMaxWait = 30 * time.Second
+	// schedulerMinWait не даёт планировщику крутиться вхолостую, если БД недоступна
+	schedulerMinWait = time.Second
+)
+
+// Scheduler публикует запланированные посты, когда наступает их время
+type Scheduler struct {
+	postRepo repository.PostRepository
+	sitemap  *Sitemap
+}
+
+func NewScheduler(postRepo repository.PostRepository, sitemap *Sitemap) *Scheduler {
+	return &Scheduler{postRepo: postRepo, sitemap: sitemap}
+}
+
+// Run публикует наступившие посты и ждёт следующего, пока ctx не отменён.
+// Посты, время которых прошло, пока сервер был остановлен, публикуются сразу.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		timer := time.NewTimer(s.tick(time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// tick публикует посты со временем не позже now и возвращает, сколько ждать до следующего
+func (s *Scheduler) tick(now time.Time) time.Duration {
+	if ids, err := s.postRepo.PublishDue(now); err != nil {
+		log.Printf("Ошибка публикации запланированных постов: %v", err)
+	} else if len(ids) > 0 {
+		log.Printf("Опубликовано запланированных постов: %d", len(ids))
+		for _, id := range ids {
+			s.sitemap.PostChanged(id)
+		}
+	}
+
+	wait := schedulerMaxWait
+	next, err := s.postRepo.NextScheduled()
+	if err != nil {
+		log.Printf("Ошибка чтения расписания публикаций: %v", err)
+	} else if next != nil && next.Sub(now) < wait {
+		wait = next.Sub(now)
+	}
+	return max(wait, schedulerMinWait)
+}
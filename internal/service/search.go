@@ -0,0 +1,88 @@
+package service
+
+import (
+	"html"
+	"html/template"
+	"strings"
+	"unicode"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+const (
+	maxSearchTerms  = 8
+	maxSearchLength = 64
+)
+
+// Search ищет посты по заголовку, тексту и комментариям. Каждое слово запроса
+// ищется как префикс, пост должен содержать все слова. Возвращает страницу
+// результатов по релевантности и общее число найденных постов.
+func (s *PostService) Search(query string, filter repository.PostFilter) ([]models.SearchResult, int, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, 0, invalid("q", "Введите слова для поиска")
+	}
+
+	total, err := s.postRepo.CountSearch(terms, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits, err := s.postRepo.Search(terms, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]models.SearchResult, len(hits))
+	posts := make([]*models.Post, len(hits))
+	for i, hit := range hits {
+		results[i] = models.SearchResult{
+			Post:    hit.Post,
+			Snippet: highlightSnippet(hit.Snippet),
+			Rank:    hit.Rank,
+		}
+		posts[i] = &results[i].Post
+		s.renderPost(posts[i])
+	}
+	s.attachCategory(posts...)
+	s.attachTags(posts...)
+
+	return results, total, nil
+}
+
+// searchTerms разбивает запрос на слова из букв и цифр в нижнем регистре.
+// Операторы и кавычки отбрасываются, так что пользователь не может сломать
+// синтаксис FTS5 или tsquery.
+func searchTerms(query string) []string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range words {
+		if runes := []rune(word); len(runes) > maxSearchLength {
+			word = string(runes[:maxSearchLength])
+		}
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+
+	return terms
+}
+
+// highlightSnippet экранирует фрагмент и заменяет маркеры совпадений на <mark>
+func highlightSnippet(snippet string) template.HTML {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, repository.SnippetStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, repository.SnippetEnd, "</mark>")
+	return template.HTML(escaped)
+}
@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/database"
+	"github.com/s.usynin/testing/go-server/internal/markdown"
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/service"
+	"github.com/s.usynin/testing/go-server/internal/spam"
+	"github.com/s.usynin/testing/go-server/internal/storage"
+)
+
+// Сервисы проверяются на настоящих репозиториях над тестовой базой
+// (SQLite и, с TEST_POSTGRES_DSN, PostgreSQL - см. пакет dbtest)
+
+// fixture - репозитории над тестовой базой и готовые автор и категория
+type fixture struct {
+	t           *testing.T
+	db          *database.DB
+	users       repository.UserRepository
+	posts       repository.PostRepository
+	comments    repository.CommentRepository
+	categories  repository.CategoryRepository
+	tags        repository.TagRepository
+	attachments repository.AttachmentRepository
+	settings    repository.SettingsRepository
+
+	author   int
+	category int
+}
+
+func newFixture(t *testing.T, db *database.DB) *fixture {
+	t.Helper()
+	f := &fixture{
+		t:           t,
+		db:          db,
+		users:       repository.NewUserRepository(db),
+		posts:       repository.NewPostRepository(db),
+		comments:    repository.NewCommentRepository(db),
+		categories:  repository.NewCategoryRepository(db),
+		tags:        repository.NewTagRepository(db),
+		attachments: repository.NewAttachmentRepository(db),
+		settings:    repository.NewSettingsRepository(db),
+	}
+	f.author = f.user("author", models.RoleAuthor)
+	id, err := f.categories.Create("Общие", "general", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.category = int(id)
+	return f
+}
+
+func (f *fixture) user(name string, role models.Role) int {
+	f.t.Helper()
+	id, err := f.users.Create(name, "hash", role)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return int(id)
+}
+
+func (f *fixture) post(title string, status models.PostStatus) int {
+	f.t.Helper()
+	var publishedAt *time.Time
+	if status != models.PostDraft {
+		now := time.Now()
+		publishedAt = &now
+	}
+	id, err := f.posts.Create(f.author, title, "Текст поста", f.category, status, publishedAt)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return int(id)
+}
+
+func (f *fixture) comment(postID int, status models.CommentStatus) int {
+	f.t.Helper()
+	id, err := f.comments.Create(&models.Comment{PostID: postID, Author: "Гость", Content: "Комментарий", Status: status})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return int(id)
+}
+
+// moderationService - модерация со спам фильтром из checkers
+func (f *fixture) moderationService(checkers ...spam.Checker) *service.ModerationService {
+	return service.NewModerationService(f.comments, f.posts, f.categories, f.settings,
+		repository.NewNotificationRepository(f.db), spam.NewFilter(checkers...))
+}
+
+// postService - сервис постов с файлами вложений в blob
+func (f *fixture) postService(blob storage.Blob) *service.PostService {
+	sitemap := service.NewSitemap(f.posts, f.categories, f.tags)
+	attachments := service.NewAttachmentService(f.attachments, f.posts, blob, 0)
+	return service.NewPostService(f.posts, f.comments, f.categories, f.tags, repository.NewLikeRepository(f.db),
+		repository.NewRevisionRepository(f.db), markdown.NewCache(markdown.NewRenderer(), 16),
+		f.moderationService(), attachments, sitemap, 0)
+}
+
+// actor - пользователь id с ролью role, как его видят сервисы
+func actor(id int, role models.Role) *models.User {
+	return &models.User{ID: id, Username: string(role), Role: role}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// sitemapBatch - постов за один запрос при полной загрузке карты сайта
+const sitemapBatch = 5000
+
+// SitemapURL - страница для sitemap.xml: путь от корня сайта и время
+// последнего изменения; нулевое время - неизвестно
+type SitemapURL struct {
+	Path    string
+	LastMod time.Time
+}
+
+// sitemapPost - опубликованный пост в карте сайта. Категория и теги нужны,
+// чтобы считать время изменения их страниц.
+type sitemapPost struct {
+	url        SitemapURL
+	categoryID int
+	tagIDs     []int
+}
+
+// Sitemap - карта сайта: главная, категории, теги с опубликованными постами
+// и сами посты. Посты читаются из БД целиком один раз, дальше PostService
+// и планировщик сообщают об изменённых постах через PostChanged, и
+// обновляется только их запись.
+type Sitemap struct {
+	postRepo     repository.PostRepository
+	categoryRepo repository.CategoryRepository
+	tagRepo      repository.TagRepository
+
+	mu sync.Mutex
+	// posts - по ID поста; nil - ещё не загружены
+	posts map[int]sitemapPost
+	// urls - собранная карта; nil - собрать заново при следующем запросе
+	urls []SitemapURL
+}
+
+func NewSitemap(
+	postRepo repository.PostRepository,
+	categoryRepo repository.CategoryRepository,
+	tagRepo repository.TagRepository,
+) *Sitemap {
+	return &Sitemap{
+		postRepo:     postRepo,
+		categoryRepo: categoryRepo,
+		tagRepo:      tagRepo,
+	}
+}
+
+// URLs возвращает страницы карты сайта: главную, категории, теги и посты по
+// возрастанию ID, так что новые посты попадают в конец и не сдвигают остальные.
+// Время изменения главной, категории (с подкатегориями) и тега - самое позднее
+// у их постов.
+func (s *Sitemap) URLs() ([]SitemapURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.urls != nil {
+		return s.urls, nil
+	}
+	if s.posts == nil {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	categories, err := s.categoryRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.tagRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	tree := NewCategoryTree(categories)
+
+	var home time.Time
+	categoryMod := make(map[int]time.Time)
+	tagMod := make(map[int]time.Time)
+	ids := make([]int, 0, len(s.posts))
+	for id, post := range s.posts {
+		ids = append(ids, id)
+		lastMod := post.url.LastMod
+		home = later(home, lastMod)
+		categoryMod[post.categoryID] = later(categoryMod[post.categoryID], lastMod)
+		for _, ancestor := range tree.Ancestors(post.categoryID) {
+			categoryMod[ancestor.ID] = later(categoryMod[ancestor.ID], lastMod)
+		}
+		for _, tagID := range post.tagIDs {
+			tagMod[tagID] = later(tagMod[tagID], lastMod)
+		}
+	}
+	sort.Ints(ids)
+
+	urls := make([]SitemapURL, 0, 1+len(categories)+len(tags)+len(ids))
+	urls = append(urls, SitemapURL{Path: "/", LastMod: home})
+	for _, category := range tree.Flatten() {
+		urls = append(urls, SitemapURL{Path: "/category/" + category.Slug, LastMod: categoryMod[category.ID]})
+	}
+	for _, tag := range tags {
+		if lastMod, ok := tagMod[tag.ID]; ok {
+			urls = append(urls, SitemapURL{Path: "/tag/" + tag.Slug, LastMod: lastMod})
+		}
+	}
+	for _, id := range ids {
+		urls = append(urls, s.posts[id].url)
+	}
+
+	s.urls = urls
+	return urls, nil
+}
+
+// PostChanged обновляет в карте сайта пост id после создания, правки, смены
+// состояния или удаления: неопубликованный или удалённый пост из неё убирается
+func (s *Sitemap) PostChanged(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.urls = nil
+	if s.posts == nil {
+		return
+	}
+
+	post, err := s.postRepo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !post.Published()) {
+		delete(s.posts, id)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка обновления карты сайта, пост %d: %v", id, err)
+		s.posts = nil
+		return
+	}
+
+	tags, err := s.tagRepo.ListByPosts([]int{id})
+	if err != nil {
+		log.Printf("Ошибка обновления карты сайта, пост %d: %v", id, err)
+		s.posts = nil
+		return
+	}
+	s.posts[id] = newSitemapPost(post, tags[id])
+}
+
+// Invalidate пересобирает карту при следующем запросе, не перечитывая посты:
+// после создания, переименования или переноса категорий и переименования тегов
+func (s *Sitemap) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.urls = nil
+}
+
+// Reset сбрасывает карту сайта: при следующем запросе посты загрузятся заново.
+// Нужен, когда меняются категории или теги многих постов сразу.
+func (s *Sitemap) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.posts = nil
+	s.urls = nil
+}
+
+// load читает все опубликованные посты пачками по sitemapBatch
+func (s *Sitemap) load() error {
+	posts := make(map[int]sitemapPost)
+	filter := repository.PostFilter{Limit: sitemapBatch}
+	for {
+		batch, err := s.postRepo.List(filter)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]int, len(batch))
+		for i, post := range batch {
+			ids[i] = post.ID
+		}
+		tags, err := s.tagRepo.ListByPosts(ids)
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			posts[batch[i].ID] = newSitemapPost(&batch[i], tags[batch[i].ID])
+		}
+
+		if len(batch) < sitemapBatch {
+			break
+		}
+		last := batch[len(batch)-1]
+		filter.Before = repository.CursorOf(last.Date(), last.ID)
+	}
+
+	s.posts = posts
+	return nil
+}
+
+// newSitemapPost - запись поста: время изменения - последняя правка, но не раньше публикации
+func newSitemapPost(post *models.Post, tags []models.Tag) sitemapPost {
+	entry := sitemapPost{
+		url:        SitemapURL{Path: post.URL(), LastMod: later(post.UpdatedAt, post.Date())},
+		categoryID: post.CategoryID,
+	}
+	for _, tag := range tags {
+		entry.tagIDs = append(entry.tagIDs, tag.ID)
+	}
+	return entry
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
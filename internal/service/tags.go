@@ -0,0 +1,244 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+	"github.com/s.usynin/testing/go-server/internal/slug"
+)
+
+const (
+	// maxPostTags - тегов у одного поста
+	maxPostTags = 10
+	// maxTagName - длина названия тега в символах
+	maxTagName = 32
+	// TagCloudSize - тегов в облаке на главной
+	TagCloudSize = 30
+	// tagSuggestions - подсказок при вводе тега
+	tagSuggestions = 8
+)
+
+// TagService - облако тегов, подсказки при вводе и управление тегами
+// для администраторов. Теги постов сохраняет PostService.
+type TagService struct {
+	tagRepo repository.TagRepository
+	sitemap *Sitemap
+}
+
+func NewTagService(tagRepo repository.TagRepository, sitemap *Sitemap) *TagService {
+	return &TagService{tagRepo: tagRepo, sitemap: sitemap}
+}
+
+// SplitTags разбивает теги, введённые через запятую, отбрасывая пустые.
+// Результат не nil даже для пустой строки: для UpdatePost это "снять все теги".
+func SplitTags(input string) []string {
+	names := []string{}
+	for _, name := range strings.Split(input, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// normalizeTags проверяет названия тегов поста и строит их адреса.
+// Повторы (в том числе названия с одинаковым адресом) отбрасываются.
+func normalizeTags(names []string) ([]models.Tag, error) {
+	seen := make(map[string]bool)
+	tags := []models.Tag{}
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" {
+			continue
+		}
+		if utf8.RuneCountInString(name) > maxTagName {
+			return nil, invalid("tags", "Тег «"+name+"» слишком длинный")
+		}
+		tagSlug := slug.Make(name)
+		if tagSlug == "" {
+			return nil, invalid("tags", "В теге «"+name+"» нет ни букв, ни цифр")
+		}
+		if seen[tagSlug] {
+			continue
+		}
+		seen[tagSlug] = true
+		tags = append(tags, models.Tag{Name: name, Slug: tagSlug})
+	}
+	if len(tags) > maxPostTags {
+		return nil, invalid("tags", fmt.Sprintf("У поста может быть не больше %d тегов", maxPostTags))
+	}
+	return tags, nil
+}
+
+// Cloud возвращает до limit самых популярных тегов по алфавиту с весом
+// от 1 до models.TagWeights. Вес растёт с логарифмом числа постов, чтобы
+// один очень популярный тег не делал все остальные одинаково мелкими.
+func (s *TagService) Cloud(limit int) ([]models.Tag, error) {
+	tags, err := s.tagRepo.List("", limit)
+	if err != nil || len(tags) == 0 {
+		return tags, err
+	}
+
+	// Теги отсортированы по числу постов по убыванию
+	maxLog := math.Log(float64(tags[0].PostsCount))
+	minLog := math.Log(float64(tags[len(tags)-1].PostsCount))
+	for i := range tags {
+		if maxLog == minLog {
+			tags[i].Weight = (models.TagWeights + 1) / 2
+			continue
+		}
+		share := (math.Log(float64(tags[i].PostsCount)) - minLog) / (maxLog - minLog)
+		tags[i].Weight = 1 + int(math.Round(share*float64(models.TagWeights-1)))
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+	return tags, nil
+}
+
+// Suggest подсказывает теги для поля ввода через запятую: дополняется
+// последний тег, уже введённые теги не предлагаются
+func (s *TagService) Suggest(input string) ([]models.Tag, error) {
+	names := strings.Split(input, ",")
+	last := names[len(names)-1]
+	if slug.Make(last) == "" {
+		return nil, nil
+	}
+
+	entered := make(map[string]bool)
+	for _, name := range names[:len(names)-1] {
+		entered[slug.Make(name)] = true
+	}
+
+	tags, err := s.Popular(last, tagSuggestions+len(entered))
+	if err != nil {
+		return nil, err
+	}
+	suggestions := []models.Tag{}
+	for _, tag := range tags {
+		if !entered[tag.Slug] && len(suggestions) < tagSuggestions {
+			suggestions = append(suggestions, tag)
+		}
+	}
+	return suggestions, nil
+}
+
+// Popular возвращает до limit тегов с постами, начиная с самых популярных.
+// query - начало названия тега, пустой - любые теги.
+func (s *TagService) Popular(query string, limit int) ([]models.Tag, error) {
+	return s.tagRepo.List(slug.Make(query), limit)
+}
+
+// GetTagBySlug возвращает тег для страницы /tag/{slug}
+func (s *TagService) GetTagBySlug(tagSlug string) (*models.Tag, error) {
+	tag, err := s.tagRepo.GetBySlug(tagSlug)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return tag, nil
+}
+
+func (s *TagService) GetTagByID(id int) (*models.Tag, error) {
+	tag, err := s.tagRepo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return tag, nil
+}
+
+// ListTags возвращает все теги, включая оставшиеся без постов
+func (s *TagService) ListTags(actor *models.User) ([]models.Tag, error) {
+	if err := authorize(actor, PermManageTags); err != nil {
+		return nil, err
+	}
+	return s.tagRepo.GetAll()
+}
+
+// RenameTag меняет название и адрес тега; пустой адрес строится из названия.
+// Если адрес занят другим тегом, теги нужно объединить (MergeTags).
+func (s *TagService) RenameTag(actor *models.User, id int, name, tagSlug string) (*models.Tag, error) {
+	if err := authorize(actor, PermManageTags); err != nil {
+		return nil, err
+	}
+	if _, err := s.tagRepo.GetByID(id); err != nil {
+		return nil, notFound(err)
+	}
+
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return nil, invalid("name", "Название тега обязательно")
+	}
+	if utf8.RuneCountInString(name) > maxTagName {
+		return nil, invalid("name", "Название тега слишком длинное")
+	}
+	tagSlug = strings.TrimSpace(tagSlug)
+	if tagSlug == "" {
+		tagSlug = slug.Make(name)
+		if tagSlug == "" {
+			return nil, invalid("slug", "Не удалось построить адрес из названия, задайте его латиницей")
+		}
+	} else if !slug.Valid(tagSlug) {
+		return nil, invalid("slug", "Адрес: латиница в нижнем регистре, цифры и дефисы")
+	}
+
+	if other, err := s.tagRepo.GetBySlug(tagSlug); err == nil && other.ID != id {
+		return nil, invalid("slug", "Адрес занят тегом «"+other.Name+"», объедините теги")
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if err := s.tagRepo.Rename(id, name, tagSlug); err != nil {
+		return nil, err
+	}
+	s.sitemap.Invalidate()
+	return s.tagRepo.GetByID(id)
+}
+
+// MergeTags ставит тег into на все посты тега from и удаляет from
+func (s *TagService) MergeTags(actor *models.User, from, into int) (*models.Tag, error) {
+	if err := authorize(actor, PermManageTags); err != nil {
+		return nil, err
+	}
+	if _, err := s.tagRepo.GetByID(from); err != nil {
+		return nil, notFound(err)
+	}
+
+	if from == into {
+		return nil, invalid("into", "Тег нельзя объединить с самим собой")
+	}
+	if _, err := s.tagRepo.GetByID(into); errors.Is(err, sql.ErrNoRows) {
+		return nil, invalid("into", "Тег для объединения не найден")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := s.tagRepo.Merge(from, into); err != nil {
+		return nil, err
+	}
+	// Теги постов поменялись: записи постов в карте сайта устарели
+	s.sitemap.Reset()
+	return s.tagRepo.GetByID(into)
+}
+
+// DeleteTag удаляет тег и снимает его со всех постов
+func (s *TagService) DeleteTag(actor *models.User, id int) error {
+	if err := authorize(actor, PermManageTags); err != nil {
+		return err
+	}
+	if _, err := s.tagRepo.GetByID(id); err != nil {
+		return notFound(err)
+	}
+	if err := s.tagRepo.Delete(id); err != nil {
+		return err
+	}
+	s.sitemap.Reset()
+	return nil
+}
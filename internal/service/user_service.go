@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+var ErrInvalidRole = errors.New("неизвестная роль")
+
+// UserService - управление пользователями, доступно администраторам
+type UserService struct {
+	userRepo repository.UserRepository
+}
+
+func NewUserService(userRepo repository.UserRepository) *UserService {
+	return &UserService{userRepo: userRepo}
+}
+
+func (s *UserService) ListUsers(actor *models.User) ([]models.User, error) {
+	if err := authorize(actor, PermManageUsers); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetAll()
+}
+
+// SetRole меняет роль пользователя. Администратор не может понизить сам себя,
+// чтобы блог не остался без администратора.
+func (s *UserService) SetRole(actor *models.User, userID int, role models.Role) (*models.User, error) {
+	if err := authorize(actor, PermManageUsers); err != nil {
+		return nil, err
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if actor.ID == userID && role != models.RoleAdmin {
+		return nil, ErrForbidden
+	}
+
+	if err := s.userRepo.SetRole(userID, role); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetByID(userID)
+}
@@ -0,0 +1,80 @@
+// Package sitemap строит карту сайта по протоколу sitemaps.org: список адресов
+// (urlset) или, если адресов больше MaxURLs, индекс из нескольких карт
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+)
+
+// MaxURLs - адресов в одной карте сайта по протоколу
+const MaxURLs = 50000
+
+// ContentType - тип содержимого карты сайта и индекса
+const ContentType = "application/xml; charset=utf-8"
+
+const namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL - адрес страницы (или карты в индексе) и время её изменения;
+// нулевое время в карту не пишется
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	URLs    []xmlEntry `xml:"url"`
+}
+
+type index struct {
+	XMLName  xml.Name   `xml:"sitemapindex"`
+	Xmlns    string     `xml:"xmlns,attr"`
+	Sitemaps []xmlEntry `xml:"sitemap"`
+}
+
+type xmlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// URLSet - карта сайта из адресов urls (не больше MaxURLs)
+func URLSet(urls []URL) ([]byte, error) {
+	return marshal(urlSet{Xmlns: namespace, URLs: entries(urls)})
+}
+
+// Index - индекс карт сайта: sitemaps - адреса карт и время изменения самой
+// свежей страницы в каждой
+func Index(sitemaps []URL) ([]byte, error) {
+	return marshal(index{Xmlns: namespace, Sitemaps: entries(sitemaps)})
+}
+
+// Chunks делит n адресов на карты по MaxURLs: возвращает число карт
+func Chunks(n int) int {
+	return (n + MaxURLs - 1) / MaxURLs
+}
+
+func entries(urls []URL) []xmlEntry {
+	result := make([]xmlEntry, len(urls))
+	for i, u := range urls {
+		result[i].Loc = u.Loc
+		if !u.LastMod.IsZero() {
+			result[i].LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+	}
+	return result
+}
+
+func marshal(doc any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,71 @@
+package sitemap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChunks(t *testing.T) {
+	tests := []struct{ urls, want int }{
+		{0, 0},
+		{1, 1},
+		{MaxURLs, 1},
+		{MaxURLs + 1, 2},
+		{2 * MaxURLs, 2},
+		{2*MaxURLs + 1, 3},
+	}
+	for _, tt := range tests {
+		if got := Chunks(tt.urls); got != tt.want {
+			t.Errorf("Chunks(%d) = %d, want %d", tt.urls, got, tt.want)
+		}
+	}
+}
+
+func TestURLSet(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	got, err := URLSet([]URL{
+		{Loc: "https://blog.example.com/", LastMod: time.Date(2026, 10, 18, 15, 4, 5, 0, msk)},
+		{Loc: "https://blog.example.com/posts/1-a&b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://blog.example.com/</loc>
+    <lastmod>2026-10-18T12:04:05Z</lastmod>
+  </url>
+  <url>
+    <loc>https://blog.example.com/posts/1-a&amp;b</loc>
+  </url>
+</urlset>
+`
+	if string(got) != want {
+		t.Errorf("URLSet:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	got, err := Index([]URL{
+		{Loc: "https://blog.example.com/sitemap-1.xml", LastMod: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{Loc: "https://blog.example.com/sitemap-2.xml"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>https://blog.example.com/sitemap-1.xml</loc>
+    <lastmod>2026-10-18T00:00:00Z</lastmod>
+  </sitemap>
+  <sitemap>
+    <loc>https://blog.example.com/sitemap-2.xml</loc>
+  </sitemap>
+</sitemapindex>
+`
+	if string(got) != want {
+		t.Errorf("Index:\n%s\nwant:\n%s", got, want)
+	}
+}
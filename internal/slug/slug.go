@@ -0,0 +1,69 @@
+// Package slug строит из названий адреса для ссылок: латиница в нижнем
+// регистре, цифры и дефисы; кириллица транслитерируется
+package slug
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxLength - длина адреса, после которой название обрезается по границе слова
+const MaxLength = 80
+
+var validRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// translit - транслитерация русских букв, как в адресах Яндекса
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Make делает адрес из названия: "Привет, мир!" -> "privet-mir".
+// Прочие символы, включая буквы других алфавитов, разделяют слова;
+// если в названии нет ни латиницы, ни кириллицы, ни цифр, адрес пустой.
+func Make(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		var part string
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			part = string(r)
+		case translit[r] != "":
+			part = translit[r]
+		case r == 'ъ' || r == 'ь' || r == '\'' || r == '’':
+			// Знаки внутри слова не разрывают его
+			continue
+		default:
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(part)
+	}
+	return truncate(b.String())
+}
+
+// truncate обрезает адрес до MaxLength по последнему целому слову
+func truncate(s string) string {
+	if len(s) <= MaxLength {
+		return s
+	}
+	s = s[:MaxLength]
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+	return strings.Trim(s, "-")
+}
+
+// Valid проверяет адрес, заданный вручную: латиница в нижнем регистре, цифры
+// и одиночные дефисы между ними, не длиннее MaxLength
+func Valid(s string) bool {
+	return len(s) <= MaxLength && validRe.MatchString(s)
+}
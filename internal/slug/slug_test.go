@@ -0,0 +1,69 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Привет, мир!", "privet-mir"},
+		{"Щука, ёж и Юла", "schuka-ezh-i-yula"},
+		{"Объявление: подъезд", "obyavlenie-podezd"},
+		{"Go 1.22 — что нового?", "go-1-22-chto-novogo"},
+		{"Don't panic", "dont-panic"},
+		{"  --Пробелы  и__знаки--  ", "probely-i-znaki"},
+		{"Ελληνικά и 日本語", "i"},
+		{"!!!", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Make(tt.name); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Длинное название обрезается по последнему целому слову
+func TestMakeLong(t *testing.T) {
+	got := Make(strings.Repeat("слово ", 30))
+	if want := strings.TrimSuffix(strings.Repeat("slovo-", 13), "-"); got != want {
+		t.Errorf("Make = %q, want %q", got, want)
+	}
+	if !Valid(got) {
+		t.Errorf("обрезанный адрес %q не проходит Valid", got)
+	}
+
+	// Одно слово длиннее MaxLength режется посередине
+	if got := Make(strings.Repeat("a", MaxLength+10)); got != strings.Repeat("a", MaxLength) {
+		t.Errorf("Make длинного слова = %q", got)
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		slug string
+		want bool
+	}{
+		{"privet-mir", true},
+		{"go-1-22", true},
+		{"a", true},
+		{strings.Repeat("a", MaxLength), true},
+		{strings.Repeat("a", MaxLength+1), false},
+		{"", false},
+		{"Privet", false},
+		{"привет", false},
+		{"privet--mir", false},
+		{"-privet", false},
+		{"privet-", false},
+		{"privet mir", false},
+		{"privet_mir", false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.slug); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.slug, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package spam
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// Параметры классификатора
+const (
+	// minDocuments - сколько спамных и сколько нормальных комментариев нужно
+	// в обучении, прежде чем классификатору можно доверять
+	minDocuments = 5
+	// maxTokens - сколько разных слов комментария учитывается при обучении
+	maxTokens = 200
+	// interestingTokens - по скольким самым показательным словам выносится оценка
+	interestingTokens = 15
+	// strength и neutral сглаживают вероятность редких слов (метод Робинсона):
+	// слово, встреченное раз-другой, почти не сдвигает оценку от neutral
+	strength = 1.0
+	neutral  = 0.5
+)
+
+// Classifier - наивный байесовский классификатор, который учится на решениях
+// модераторов: комментарии, отмеченные спамом, и одобренные комментарии.
+// Оценка начисляется, только если вероятность спама выше половины.
+// Обучение хранится в SpamRepository: модерация сохраняет его вместе
+// с решением (CommentRepository.Moderate) по словам из Tokenize.
+type Classifier struct {
+	spamRepo repository.SpamRepository
+}
+
+func NewClassifier(spamRepo repository.SpamRepository) *Classifier {
+	return &Classifier{spamRepo: spamRepo}
+}
+
+func (c *Classifier) Check(sub *Submission) (Result, error) {
+	spamDocs, hamDocs, err := c.spamRepo.Documents()
+	if err != nil {
+		return Result{}, err
+	}
+	if spamDocs < minDocuments || hamDocs < minDocuments {
+		return Result{}, nil
+	}
+
+	counts, err := c.spamRepo.TokenCounts(Tokenize(sub))
+	if err != nil {
+		return Result{}, err
+	}
+
+	probabilities := make([]float64, 0, len(counts))
+	for _, count := range counts {
+		spamFreq := float64(count.Spam) / float64(spamDocs)
+		hamFreq := float64(count.Ham) / float64(hamDocs)
+		if spamFreq+hamFreq == 0 {
+			continue
+		}
+		p := spamFreq / (spamFreq + hamFreq)
+		n := float64(count.Spam + count.Ham)
+		probabilities = append(probabilities, (strength*neutral+n*p)/(strength+n))
+	}
+	if len(probabilities) == 0 {
+		return Result{}, nil
+	}
+
+	// Самые показательные слова - те, чья вероятность дальше всего от нейтральной
+	sort.Slice(probabilities, func(i, j int) bool {
+		return math.Abs(probabilities[i]-neutral) > math.Abs(probabilities[j]-neutral)
+	})
+	probabilities = probabilities[:min(len(probabilities), interestingTokens)]
+
+	// Складываем логарифмы шансов, чтобы произведение не ушло в ноль
+	var logOdds float64
+	for _, p := range probabilities {
+		p = math.Min(math.Max(p, 0.01), 0.99)
+		logOdds += math.Log(p / (1 - p))
+	}
+	probability := 1 / (1 + math.Exp(-logOdds))
+	if probability <= neutral {
+		return Result{}, nil
+	}
+
+	return Result{
+		Score:  (probability - neutral) * 2 * SpamScore,
+		Reason: fmt.Sprintf("байесовский фильтр: спам с вероятностью %.0f%%", probability*100),
+	}, nil
+}
+
+// Tokenize разбивает имя автора и текст комментария на разные слова в нижнем
+// регистре. Слова короче двух и длиннее 40 символов (хеши, base64) не несут
+// смысла и отбрасываются.
+func Tokenize(sub *Submission) []string {
+	text := sub.Author + " " + sub.Content
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '$'
+	}) {
+		if n := len([]rune(word)); n < 2 || n > 40 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+		if len(tokens) == maxTokens {
+			break
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,110 @@
+package spam
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/s.usynin/testing/go-server/internal/models"
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// memSpam - счётчики классификатора в памяти; Learn обучает их,
+// как CommentRepository.Moderate
+type memSpam struct {
+	tokens    map[string]repository.TokenCount
+	spam, ham int
+}
+
+func newMemSpam() *memSpam {
+	return &memSpam{tokens: map[string]repository.TokenCount{}}
+}
+
+func (m *memSpam) TokenCounts(tokens []string) (map[string]repository.TokenCount, error) {
+	counts := make(map[string]repository.TokenCount)
+	for _, token := range tokens {
+		if count, ok := m.tokens[token]; ok {
+			counts[token] = count
+		}
+	}
+	return counts, nil
+}
+
+func (m *memSpam) Documents() (int, int, error) {
+	return m.spam, m.ham, nil
+}
+
+func (m *memSpam) Learn(tokens []string, label models.SpamLabel, delta int) error {
+	for _, token := range tokens {
+		count := m.tokens[token]
+		if label == models.SpamLabelSpam {
+			count.Spam += delta
+		} else {
+			count.Ham += delta
+		}
+		m.tokens[token] = count
+	}
+	if label == models.SpamLabelSpam {
+		m.spam += delta
+	} else {
+		m.ham += delta
+	}
+	return nil
+}
+
+func TestTokenize(t *testing.T) {
+	// Повторы, слова из одной буквы и длиннее 40 символов не учитываются
+	sub := &Submission{Author: "Bot", Content: "FREE free, $100 don't a " + strings.Repeat("x", 41)}
+	got := Tokenize(sub)
+	want := []string{"bot", "free", "$100", "don't"}
+	if len(got) != len(want) {
+		t.Fatalf("Tokenize = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Tokenize = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	repo := newMemSpam()
+	classifier := NewClassifier(repo)
+
+	train := func(label models.SpamLabel, content string, times int) {
+		t.Helper()
+		for i := 0; i < times; i++ {
+			if err := repo.Learn(Tokenize(&Submission{Content: content}), label, 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	spamText := &Submission{Content: "free casino bonus"}
+
+	// Пока примеров меньше minDocuments, классификатор молчит
+	train(models.SpamLabelSpam, "free casino bonus money", minDocuments)
+	train(models.SpamLabelHam, "great article about go", minDocuments-1)
+	got, err := classifier.Check(spamText)
+	checkResult(t, "мало примеров", got, err, 0, "")
+
+	train(models.SpamLabelHam, "great article about go", 1)
+	// Три слова, каждое в 5 спамных и 0 нормальных: p = (0.5 + 5) / 6,
+	// итоговая вероятность 1 / (1 + 11^-3), оценка (вероятность - 0.5) * 2
+	got, err = classifier.Check(spamText)
+	checkResult(t, "спам", got, err, 0.9985, "байесовский фильтр: спам с вероятностью 100%")
+
+	got, err = classifier.Check(&Submission{Content: "great article"})
+	checkResult(t, "нормальный", got, err, 0, "")
+	got, err = classifier.Check(&Submission{Content: "незнакомые слова"})
+	checkResult(t, "незнакомые слова", got, err, 0, "")
+
+	// Одно спамное и одно нормальное слово уравновешивают друг друга
+	got, err = classifier.Check(&Submission{Content: "casino article"})
+	checkResult(t, "поровну", got, err, 0, "")
+
+	// Отмена обучения возвращает классификатор к порогу minDocuments
+	if err := repo.Learn(Tokenize(&Submission{Content: "free casino bonus money"}), models.SpamLabelSpam, -1); err != nil {
+		t.Fatal(err)
+	}
+	got, err = classifier.Check(spamText)
+	checkResult(t, "после отмены", got, err, 0, "")
+}
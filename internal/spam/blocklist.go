@@ -0,0 +1,94 @@
+package spam
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/s.usynin/testing/go-server/internal/repository"
+)
+
+// Ключи списков блокировки в settings: по одному слову или адресу на строку
+const (
+	BlockedWordsSetting = "spam_blocked_words"
+	BlockedIPsSetting   = "spam_blocked_ips"
+)
+
+// Blocklist отправляет в спам комментарии с запрещёнными словами (в имени
+// или тексте, без учёта регистра) и с запрещённых адресов. Адрес задаётся
+// целиком или подсетью CIDR, например 203.0.113.0/24.
+type Blocklist struct {
+	settingsRepo repository.SettingsRepository
+}
+
+func NewBlocklist(settingsRepo repository.SettingsRepository) *Blocklist {
+	return &Blocklist{settingsRepo: settingsRepo}
+}
+
+func (b *Blocklist) Check(sub *Submission) (Result, error) {
+	ips, err := b.list(BlockedIPsSetting)
+	if err != nil {
+		return Result{}, err
+	}
+	if ip := net.ParseIP(sub.IP); ip != nil {
+		for _, blocked := range ips {
+			if matchIP(ip, blocked) {
+				return Result{Score: SpamScore, Reason: fmt.Sprintf("адрес %s в списке блокировки", sub.IP)}, nil
+			}
+		}
+	}
+
+	words, err := b.list(BlockedWordsSetting)
+	if err != nil {
+		return Result{}, err
+	}
+	text := strings.ToLower(sub.Author + "\n" + sub.Content)
+	for _, word := range words {
+		if strings.Contains(text, strings.ToLower(word)) {
+			return Result{Score: SpamScore, Reason: fmt.Sprintf("запрещённое слово «%s»", word)}, nil
+		}
+	}
+
+	return Result{}, nil
+}
+
+func (b *Blocklist) list(key string) ([]string, error) {
+	value, err := b.settingsRepo.Get(key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseList(value), nil
+}
+
+func matchIP(ip net.IP, blocked string) bool {
+	if _, network, err := net.ParseCIDR(blocked); err == nil {
+		return network.Contains(ip)
+	}
+	other := net.ParseIP(blocked)
+	return other != nil && other.Equal(ip)
+}
+
+// ParseList разбирает список из настроек: по элементу на строку,
+// пустые строки и пробелы по краям отбрасываются
+func ParseList(value string) []string {
+	var items []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+	return items
+}
+
+// ValidIP проверяет элемент списка адресов: адрес или подсеть CIDR
+func ValidIP(value string) bool {
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
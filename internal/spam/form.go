@@ -0,0 +1,153 @@
+package spam
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HoneypotField - имя поля-ловушки в форме комментария. Поле скрыто от людей,
+// а боты, заполняющие все поля подряд, вписывают в него что-нибудь.
+const HoneypotField = "website"
+
+// FormTokenField - имя скрытого поля с токеном времени показа формы
+const FormTokenField = "form_token"
+
+// FormTokenHeader - заголовок ответа с новым токеном для формы, из которой
+// только что отправили комментарий: старый токен уже использован
+const FormTokenHeader = "X-Form-Token"
+
+// Honeypot срабатывает, если в поле-ловушке что-то есть
+type Honeypot struct{}
+
+func (Honeypot) Check(sub *Submission) (Result, error) {
+	if !sub.Form || sub.Honeypot == "" {
+		return Result{}, nil
+	}
+	return Result{Score: SpamScore, Reason: "заполнено скрытое поле формы"}, nil
+}
+
+// FormTimer выдаёт форме подписанный токен со временем показа и проверяет,
+// сколько форма заполнялась: люди не отправляют комментарий за пару секунд
+// после загрузки страницы, а боты часто отправляют форму без токена вовсе.
+// Токен привязан к посту и годится для одного комментария: повторная
+// отправка с тем же токеном - спам. Использованные токены хранятся в памяти
+// до истечения MaxAge, поэтому после перезапуска сервера токен, выданный
+// до него, можно использовать ещё раз.
+type FormTimer struct {
+	secret  []byte
+	MinFill time.Duration // быстрее - почти наверняка бот
+	MaxAge  time.Duration // старше - токен просрочен
+	now     func() time.Time
+
+	mu sync.Mutex
+	// used - использованные токены (время и nonce) и когда их можно забыть
+	used map[string]time.Time
+}
+
+// NewFormTimer создаёт FormTimer с ключом подписи secret. С пустым secret
+// ключ генерируется при запуске, и токены уже открытых форм перестают
+// приниматься после перезапуска сервера.
+func NewFormTimer(secret string) (*FormTimer, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &FormTimer{
+		secret:  key,
+		MinFill: 3 * time.Second,
+		MaxAge:  24 * time.Hour,
+		now:     time.Now,
+		used:    make(map[string]time.Time),
+	}, nil
+}
+
+// Token - токен для формы комментария к посту postID, показанной сейчас:
+// "<unix время>.<nonce>.<подпись>". Подпись покрывает и postID, так что
+// с токеном одного поста нельзя прокомментировать другой.
+func (t *FormTimer) Token(postID int) (string, error) {
+	raw := make([]byte, 9)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	payload := strconv.FormatInt(t.now().Unix(), 10) + "." + base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + t.sign(payload, postID), nil
+}
+
+func (t *FormTimer) sign(payload string, postID int) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(payload + "." + strconv.Itoa(postID)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parse проверяет подпись токена для поста postID и возвращает время показа
+// формы и ключ токена для учёта использованных
+func (t *FormTimer) parse(token string, postID int) (time.Time, string, bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return time.Time{}, "", false
+	}
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(t.sign(payload, postID))) {
+		return time.Time{}, "", false
+	}
+	issued, _, ok := strings.Cut(payload, ".")
+	if !ok {
+		return time.Time{}, "", false
+	}
+	unix, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return time.Unix(unix, 0), payload, true
+}
+
+// use отмечает токен использованным и возвращает false, если он уже был
+// использован. Заодно забываются токены, которые всё равно просрочены.
+func (t *FormTimer) use(key string, issued time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for k, expires := range t.used {
+		if now.After(expires) {
+			delete(t.used, k)
+		}
+	}
+	if _, ok := t.used[key]; ok {
+		return false
+	}
+	t.used[key] = issued.Add(t.MaxAge)
+	return true
+}
+
+func (t *FormTimer) Check(sub *Submission) (Result, error) {
+	if !sub.Form {
+		return Result{}, nil
+	}
+
+	issued, key, ok := t.parse(sub.FormToken, sub.PostID)
+	if !ok {
+		return Result{Score: SuspectScore, Reason: "нет токена формы, он подделан или выдан для другого поста"}, nil
+	}
+
+	elapsed := t.now().Sub(issued)
+	switch {
+	case elapsed > t.MaxAge:
+		return Result{Score: 0.3, Reason: "форма открыта слишком давно"}, nil
+	case !t.use(key, issued):
+		return Result{Score: SpamScore, Reason: "токен формы уже использован"}, nil
+	case elapsed < t.MinFill:
+		return Result{Score: SpamScore, Reason: fmt.Sprintf("форма заполнена за %s", elapsed.Round(time.Second))}, nil
+	}
+	return Result{}, nil
+}
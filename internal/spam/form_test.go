@@ -0,0 +1,99 @@
+package spam
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormTimer(t *testing.T) {
+	timer, err := NewFormTimer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	timer.now = func() time.Time { return now }
+
+	// issue выдаёт токен для поста postID и переводит часы на after вперёд
+	issue := func(postID int, after time.Duration) string {
+		t.Helper()
+		token, err := timer.Token(postID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(after)
+		return token
+	}
+	check := func(name, token string, postID int, score float64, reason string) {
+		t.Helper()
+		sub := &Submission{Source: Source{Form: true, FormToken: token}, PostID: postID}
+		got, err := timer.Check(sub)
+		checkResult(t, name, got, err, score, reason)
+	}
+
+	const forged = "нет токена формы, он подделан или выдан для другого поста"
+	got, err := timer.Check(&Submission{PostID: 1})
+	checkResult(t, "API без формы", got, err, 0, "")
+	check("без токена", "", 1, SuspectScore, forged)
+	check("мусор", "abc", 1, SuspectScore, forged)
+
+	token := issue(1, 10*time.Second)
+	check("токен другого поста", token, 2, SuspectScore, forged)
+	issued, rest, _ := strings.Cut(token, ".")
+	check("подделанное время", issued+"0."+rest, 1, SuspectScore, forged)
+	other, err := NewFormTimer("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := other.Token(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("токен с чужим ключом", otherToken, 1, SuspectScore, forged)
+
+	check("заполнена за 10 секунд", token, 1, 0, "")
+	check("повторная отправка", token, 1, SpamScore, "токен формы уже использован")
+
+	fast := issue(1, time.Second)
+	check("заполнена за секунду", fast, 1, SpamScore, "форма заполнена за 1s")
+	check("быстрый токен тоже одноразовый", fast, 1, SpamScore, "токен формы уже использован")
+
+	stale := issue(1, 25*time.Hour)
+	check("форма открыта сутки", stale, 1, 0.3, "форма открыта слишком давно")
+
+	// Просроченные использованные токены забываются: память не растёт
+	check("новый токен", issue(1, time.Minute), 1, 0, "")
+	if len(timer.used) != 1 {
+		t.Errorf("использованных токенов в памяти: %d, want 1", len(timer.used))
+	}
+}
+
+// Токены с пустым ключом подписи действуют до перезапуска: ключ случайный
+func TestFormTimerRandomSecret(t *testing.T) {
+	first, err := NewFormTimer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewFormTimer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	first.now = func() time.Time { return past }
+	token, err := first.Token(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := &Submission{Source: Source{Form: true, FormToken: token}, PostID: 1}
+	if got, _ := second.Check(sub); got.Score != SuspectScore {
+		t.Errorf("токен другого запуска: %+v", got)
+	}
+	first.now = time.Now
+	if got, _ := first.Check(sub); got.Score != 0 {
+		t.Errorf("токен того же запуска: %+v", got)
+	}
+	if token := NewFilter(first).FormToken(1); token == "" {
+		t.Error("FormToken с FormTimer пустой")
+	}
+}
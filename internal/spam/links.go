@@ -0,0 +1,30 @@
+package spam
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// linkPattern находит ссылки в тексте: адреса со схемой, www. и почту
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://|\bwww\.|\b[\w.+-]+@[\w-]+\.[\w.]+`)
+
+// LinkChecker оценивает число ссылок: до Allowed ссылок - норма, за каждую
+// следующую начисляется PerLink, но не больше SpamScore
+type LinkChecker struct {
+	Allowed int
+	PerLink float64
+}
+
+func NewLinkChecker() *LinkChecker {
+	return &LinkChecker{Allowed: 1, PerLink: 0.3}
+}
+
+func (c *LinkChecker) Check(sub *Submission) (Result, error) {
+	links := len(linkPattern.FindAllStringIndex(sub.Content, -1))
+	if links <= c.Allowed {
+		return Result{}, nil
+	}
+
+	score := min(float64(links-c.Allowed)*c.PerLink, SpamScore)
+	return Result{Score: score, Reason: fmt.Sprintf("ссылок: %d", links)}, nil
+}
@@ -0,0 +1,111 @@
+package spam
+
+import (
+	"log"
+	"strings"
+)
+
+// Пороги суммарной оценки: комментарий с оценкой от SpamScore сразу попадает
+// в спам, от SuspectScore - ждёт модератора, даже если премодерация выключена
+const (
+	SpamScore    = 1.0
+	SuspectScore = 0.5
+)
+
+// Source - откуда пришёл комментарий: адрес клиента и, для HTML формы,
+// значения её скрытых полей
+type Source struct {
+	IP string
+	// Form - комментарий отправлен HTML формой; у запросов API нет ни ловушки,
+	// ни токена формы, и эти проверки для них пропускаются
+	Form      bool
+	Honeypot  string
+	FormToken string
+}
+
+// Submission - новый комментарий на проверку
+type Submission struct {
+	Source
+	PostID  int
+	UserID  *int
+	Author  string
+	Content string
+}
+
+// Result - вывод одной проверки. Score 0 - проверка ничего не нашла;
+// Reason объясняет модератору, за что начислена оценка.
+type Result struct {
+	Score  float64
+	Reason string
+}
+
+// Checker - одна проверка комментария на спам
+type Checker interface {
+	Check(sub *Submission) (Result, error)
+}
+
+// Verdict - итог всех проверок: сумма оценок и причины тех, что сработали
+type Verdict struct {
+	Score   float64
+	Reasons []string
+}
+
+// Reason - причины одной строкой для хранения рядом с комментарием
+func (v Verdict) Reason() string {
+	return strings.Join(v.Reasons, "; ")
+}
+
+// Spam - оценка достаточна, чтобы сразу отправить комментарий в спам
+func (v Verdict) Spam() bool {
+	return v.Score >= SpamScore
+}
+
+// Suspicious - комментарий стоит показать модератору перед публикацией
+func (v Verdict) Suspicious() bool {
+	return v.Score >= SuspectScore
+}
+
+// Filter прогоняет комментарий через набор проверок и складывает их оценки
+type Filter struct {
+	checkers []Checker
+}
+
+func NewFilter(checkers ...Checker) *Filter {
+	return &Filter{checkers: checkers}
+}
+
+// Check проверяет комментарий всеми проверками. Сломанная проверка (например,
+// недоступна статистика классификатора) не должна мешать комментировать,
+// поэтому её ошибка только логируется.
+func (f *Filter) Check(sub *Submission) Verdict {
+	var verdict Verdict
+	for _, checker := range f.checkers {
+		result, err := checker.Check(sub)
+		if err != nil {
+			log.Printf("Ошибка спам проверки %T: %v", checker, err)
+			continue
+		}
+		if result.Score <= 0 {
+			continue
+		}
+		verdict.Score += result.Score
+		verdict.Reasons = append(verdict.Reasons, result.Reason)
+	}
+	return verdict
+}
+
+// FormToken выдаёт токен для новой формы комментария к посту postID, если
+// среди проверок есть FormTimer; иначе пустую строку. Без токена форма всё
+// равно работает, только её комментарий будет ждать модератора.
+func (f *Filter) FormToken(postID int) string {
+	for _, checker := range f.checkers {
+		if timer, ok := checker.(*FormTimer); ok {
+			token, err := timer.Token(postID)
+			if err != nil {
+				log.Printf("Ошибка выдачи токена формы: %v", err)
+			}
+			return token
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package spam
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+)
+
+// checkResult сравнивает оценку с точностью до тысячных и причину целиком
+func checkResult(t *testing.T, name string, got Result, err error, score float64, reason string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if math.Abs(got.Score-score) > 1e-3 || got.Reason != reason {
+		t.Errorf("%s: %.4f %q, want %.4f %q", name, got.Score, got.Reason, score, reason)
+	}
+}
+
+func TestLinkChecker(t *testing.T) {
+	tests := []struct {
+		content string
+		score   float64
+		reason  string
+	}{
+		{"без ссылок", 0, ""},
+		{"одна ссылка https://example.com допустима", 0, ""},
+		{"http://a.example www.b.example c@example.com", 0.6, "ссылок: 3"},
+		{"HTTPS://A.EXAMPLE https://b.example", 0.3, "ссылок: 2"},
+		{"https://1 https://2 https://3 https://4 https://5 https://6 https://7", SpamScore, "ссылок: 7"},
+	}
+	checker := NewLinkChecker()
+	for _, tt := range tests {
+		got, err := checker.Check(&Submission{Content: tt.content})
+		checkResult(t, tt.content, got, err, tt.score, tt.reason)
+	}
+}
+
+func TestHoneypot(t *testing.T) {
+	tests := []struct {
+		name   string
+		source Source
+		score  float64
+		reason string
+	}{
+		{"API без формы", Source{Honeypot: "http://spam.example"}, 0, ""},
+		{"пустая ловушка", Source{Form: true}, 0, ""},
+		{"заполненная ловушка", Source{Form: true, Honeypot: "http://spam.example"}, SpamScore, "заполнено скрытое поле формы"},
+	}
+	for _, tt := range tests {
+		got, err := Honeypot{}.Check(&Submission{Source: tt.source})
+		checkResult(t, tt.name, got, err, tt.score, tt.reason)
+	}
+}
+
+// memSettings - настройки в памяти; err возвращается из Get вместо значения
+type memSettings struct {
+	values map[string]string
+	err    error
+}
+
+func (s *memSettings) Get(key string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	value, ok := s.values[key]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return value, nil
+}
+
+func (s *memSettings) Set(key, value string) error {
+	s.values[key] = value
+	return nil
+}
+
+func (s *memSettings) SetDefault(key, value string) (string, error) {
+	if current, ok := s.values[key]; ok {
+		return current, nil
+	}
+	s.values[key] = value
+	return value, nil
+}
+
+func TestBlocklist(t *testing.T) {
+	settings := &memSettings{values: map[string]string{}}
+	blocklist := NewBlocklist(settings)
+
+	// Списки не заданы - ничего не блокируется
+	got, err := blocklist.Check(&Submission{Source: Source{IP: "203.0.113.5"}, Content: "Казино"})
+	checkResult(t, "без списков", got, err, 0, "")
+
+	settings.values[BlockedWordsSetting] = "  казино \n\nxxx\n"
+	settings.values[BlockedIPsSetting] = "203.0.113.0/24\n198.51.100.7"
+	tests := []struct {
+		name   string
+		sub    Submission
+		score  float64
+		reason string
+	}{
+		{"обычный комментарий", Submission{Source: Source{IP: "192.0.2.1"}, Author: "Анна", Content: "Спасибо"}, 0, ""},
+		{"подсеть", Submission{Source: Source{IP: "203.0.113.200"}, Content: "Спасибо"},
+			SpamScore, "адрес 203.0.113.200 в списке блокировки"},
+		{"адрес целиком", Submission{Source: Source{IP: "198.51.100.7"}, Content: "Спасибо"},
+			SpamScore, "адрес 198.51.100.7 в списке блокировки"},
+		{"соседний адрес", Submission{Source: Source{IP: "198.51.100.8"}, Content: "Спасибо"}, 0, ""},
+		{"слово без учёта регистра", Submission{Content: "Лучшее КАЗИНО онлайн"}, SpamScore, "запрещённое слово «казино»"},
+		{"слово в имени", Submission{Author: "xxx-bot", Content: "Привет"}, SpamScore, "запрещённое слово «xxx»"},
+		{"адрес без IP", Submission{Source: Source{IP: "не адрес"}, Content: "Привет"}, 0, ""},
+	}
+	for _, tt := range tests {
+		got, err := blocklist.Check(&tt.sub)
+		checkResult(t, tt.name, got, err, tt.score, tt.reason)
+	}
+
+	settings.err = errors.New("база недоступна")
+	if _, err := blocklist.Check(&Submission{Content: "Привет"}); err == nil {
+		t.Error("ошибка настроек не вернулась")
+	}
+}
+
+// fixed - проверка с заранее заданным итогом
+type fixed struct {
+	result Result
+	err    error
+}
+
+func (f *fixed) Check(*Submission) (Result, error) { return f.result, f.err }
+
+func TestFilter(t *testing.T) {
+	links := &fixed{result: Result{Score: 0.3, Reason: "ссылок: 2"}}
+	timer := &fixed{result: Result{Score: 0.3, Reason: "форма открыта слишком давно"}}
+	broken := &fixed{err: errors.New("сломалась")}
+	clean := &fixed{}
+	filter := NewFilter(links, broken, clean, timer)
+
+	// Сломанная проверка пропускается, оценки складываются
+	verdict := filter.Check(&Submission{Content: "текст"})
+	if math.Abs(verdict.Score-0.6) > 1e-9 || verdict.Reason() != "ссылок: 2; форма открыта слишком давно" {
+		t.Errorf("Check = %.2f %q", verdict.Score, verdict.Reason())
+	}
+	if !verdict.Suspicious() || verdict.Spam() {
+		t.Errorf("оценка 0.6: Suspicious %v, Spam %v", verdict.Suspicious(), verdict.Spam())
+	}
+	if v := (Verdict{Score: SpamScore}); !v.Spam() || !v.Suspicious() {
+		t.Error("оценка SpamScore - спам")
+	}
+	if v := (Verdict{Score: 0.49}); v.Suspicious() {
+		t.Error("оценка ниже SuspectScore не подозрительна")
+	}
+
+	// Без FormTimer токена нет
+	if token := filter.FormToken(1); token != "" {
+		t.Errorf("FormToken без FormTimer = %q", token)
+	}
+}
@@ -0,0 +1,39 @@
+// Package storage хранит файлы вложений постов. Хранилище выбирается при
+// запуске: каталог на диске (Local) или S3-совместимый бакет (S3).
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound - файла с таким ключом нет
+var ErrNotFound = errors.New("файл не найден")
+
+// Blob - хранилище файлов по ключу. Ключ - путь из сегментов через "/"
+// (например posts/12/3f9c.png); содержимое по ключу не меняется, поэтому
+// один и тот же ключ можно отдавать с долгим кэшированием.
+type Blob interface {
+	// Put сохраняет size байт из r под ключом key
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Get открывает файл; если файла нет - ErrNotFound. Если результат
+	// реализует io.Seeker, его можно отдавать с поддержкой Range.
+	Get(key string) (io.ReadCloser, error)
+	// Delete удаляет файл; удаление отсутствующего файла - не ошибка
+	Delete(key string) error
+}
+
+// checkKey отклоняет ключи, которые могут выйти за пределы хранилища
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("недопустимый ключ файла %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("недопустимый ключ файла %q", key)
+		}
+	}
+	return nil
+}
@@ -2,19 +2,50 @@ package templates
 
 import (
 	"html/template"
+	"path/filepath"
 )
 
 var Tpl *template.Template
 
-// InitTemplates загружает все шаблоны из директории templates
-func InitTemplates() error {
-	var err error
+// files - шаблоны относительно каталога шаблонов
+var files = []string{
+	"home.html",
+	"post_item.html",
+	"post_page.html",
+	"drafts.html",
+	"like_button.html",
+	"reactions.html",
+	"post_list.html",
+	"comment_item.html",
+	"comment_page.html",
+	"comment_replies.html",
+	"post_edit.html",
+	"tag_suggest.html",
+	"post_revisions.html",
+	"post_diff.html",
+	"post_preview.html",
+	"login.html",
+	"signup.html",
+	"error_fragment.html",
+	"admin_users.html",
+	"admin_categories.html",
+	"category_table.html",
+	"admin_tags.html",
+	"tag_table.html",
+	"admin_comments.html",
+	"moderation_queue.html",
+	"comment_pending.html",
+	"notifications.html",
+}
 
-	Tpl, err = template.ParseFiles(
-		"templates/home.html",
-		"templates/post_item.html",
-		"templates/comment_item.html",
-	)
+// InitTemplates загружает все шаблоны из каталога dir
+func InitTemplates(dir string) error {
+	paths := make([]string, len(files))
+	for i, name := range files {
+		paths[i] = filepath.Join(dir, name)
+	}
 
+	var err error
+	Tpl, err = template.ParseFiles(paths...)
 	return err
 }
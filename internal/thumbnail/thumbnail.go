@@ -0,0 +1,113 @@
+// Package thumbnail уменьшает изображения для превью вложений. Поддерживаются
+// форматы стандартной библиотеки: JPEG, PNG и GIF (первый кадр).
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	_ "image/gif"
+)
+
+// MaxPixels - изображения больше этого числа пикселей не декодируются:
+// распакованное, оно заняло бы сотни мегабайт памяти
+const MaxPixels = 40_000_000
+
+// maxSamples - сколько пикселей исходника по каждой оси усредняется
+// в один пиксель превью
+const maxSamples = 4
+
+// ErrTooLarge - изображение больше MaxPixels
+var ErrTooLarge = errors.New("изображение слишком большое для превью")
+
+// Thumbnail - превью и размеры исходного изображения
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Make вписывает изображение из r в квадрат size×size, сохраняя пропорции;
+// изображения меньше квадрата не увеличиваются. Фотографии (JPEG) сохраняются
+// в JPEG, остальное - в PNG, чтобы не потерять прозрачность.
+// Неизвестный формат - image.ErrFormat.
+func Make(r io.ReadSeeker, size int) (*Thumbnail, error) {
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := fit(config.Width, config.Height, size)
+	dst := scale(src, width, height)
+
+	thumb := &Thumbnail{Width: config.Width, Height: config.Height}
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		thumb.ContentType = "image/jpeg"
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	} else {
+		thumb.ContentType = "image/png"
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	thumb.Data = buf.Bytes()
+	return thumb, nil
+}
+
+// fit - размеры, в которые width×height вписывается в квадрат size×size
+func fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// scale уменьшает src до width×height: каждый пиксель - среднее до
+// maxSamples×maxSamples равномерно расставленных пикселей его области
+func scale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	samplesX := min(maxSamples, (bounds.Dx()+width-1)/width)
+	samplesY := min(maxSamples, (bounds.Dy()+height-1)/height)
+	n := uint32(samplesX * samplesY)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var r, g, b, a uint32
+			for sy := 0; sy < samplesY; sy++ {
+				srcY := bounds.Min.Y + ((y*samplesY+sy)*bounds.Dy()+bounds.Dy()/2)/(height*samplesY)
+				for sx := 0; sx < samplesX; sx++ {
+					srcX := bounds.Min.X + ((x*samplesX+sx)*bounds.Dx()+bounds.Dx()/2)/(width*samplesX)
+					pr, pg, pb, pa := src.At(srcX, srcY).RGBA()
+					r, g, b, a = r+pr, g+pg, b+pb, a+pa
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}